@@ -0,0 +1,296 @@
+// Package browse implements a minimal gitweb-style HTML browser for a git
+// repository: directory listings, file views with breadcrumbs, raw links and
+// a switcher between branches and tags.
+//
+//	http.Handle("/code/", http.StripPrefix("/code", browse.New(gitDir)))
+//
+// Paths are given as URL paths and revisions as the rev query parameter,
+// which defaults to HEAD. Adding raw=1 serves the content of a file as is.
+package browse
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// Handler serves the browser for the repository at GitDir.
+type Handler struct {
+	GitDir  string
+	Options []git.Option
+
+	// Prefix is prepended to the paths of links, which should be set when
+	// the handler is mounted under http.StripPrefix.
+	Prefix string
+
+	mu   sync.Mutex
+	repo *git.Repository // made on the first request
+}
+
+// New returns a Handler for the repository at gitDir. opts are applied to
+// the Repository serving requests, which is made once and shared by them.
+func New(gitDir string, opts ...git.Option) *Handler {
+	return &Handler{GitDir: gitDir, Options: opts}
+}
+
+type page struct {
+	Rev      string // as requested
+	Action   string // of the revision switcher
+	Commit   string
+	Path     string
+	Crumbs   []crumb
+	Refs     []git.Ref
+	Entries  []entry
+	IsDir    bool
+	Content  string
+	Binary   bool
+	Target   string // of a symlink
+	Gitlink  bool
+	RawURL   string
+	NotFound bool
+}
+
+type crumb struct {
+	Name string
+	URL  string
+}
+
+type entry struct {
+	Name string
+	URL  string
+	Type string
+	Size int64
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rev := r.URL.Query().Get("rev")
+	if rev == "" {
+		rev = "HEAD"
+	}
+	name := strings.Trim(path.Clean("/"+r.URL.Path), "/")
+
+	base, err := h.base()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	base = base.WithContext(r.Context())
+
+	p := &page{Rev: rev, Path: name, Action: (&url.URL{Path: h.Prefix + "/" + name}).String()}
+
+	p.Refs, err = base.Refs()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// pin the revision so that the page is consistent
+	repo, err := base.At(rev)
+	if errors.Is(err, git.ErrUnknownRevision) {
+		p.NotFound = true
+		h.render(w, http.StatusNotFound, p)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p.Commit = repo.Revision
+
+	if ok, err := repo.Exists(name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !ok {
+		p.NotFound = true
+		h.render(w, http.StatusNotFound, p)
+		return
+	}
+
+	fi, err := repo.Lstat(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("raw") != "" {
+		h.serveRaw(w, repo, name, fi)
+		return
+	}
+
+	p.Crumbs = h.crumbs(name, rev)
+	p.RawURL = h.url(name, rev) + "&raw=1"
+
+	switch {
+	case fi.IsDir():
+		err = h.fillDir(p, repo, name)
+	case fi.Mode()&os.ModeSymlink != 0:
+		p.Target, err = repo.Readlink(name)
+	case fi.Mode().IsRegular():
+		err = h.fillFile(p, repo, name)
+	default:
+		p.Gitlink = true
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, http.StatusOK, p)
+}
+
+// base returns the Repository shared by requests, making it if not yet.
+func (h *Handler) base() (*git.Repository, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.repo == nil {
+		repo, err := git.NewRepository("HEAD", h.GitDir, h.Options...)
+		if err != nil {
+			return nil, err
+		}
+		h.repo = repo
+	}
+
+	return h.repo, nil
+}
+
+func (h *Handler) fillDir(p *page, repo *git.Repository, name string) error {
+	fis, err := repo.ReadDir(name)
+	if err != nil {
+		return err
+	}
+
+	p.IsDir = true
+
+	// directories first, as gitweb does
+	for _, dirs := range []bool{true, false} {
+		for _, fi := range fis {
+			if fi.IsDir() != dirs {
+				continue
+			}
+
+			e := entry{
+				Name: fi.Name(),
+				URL:  h.url(path.Join(name, fi.Name()), p.Rev),
+				Type: entryType(fi),
+				Size: fi.Size(),
+			}
+			if fi.IsDir() {
+				e.Name += "/"
+			}
+			p.Entries = append(p.Entries, e)
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) fillFile(p *page, repo *git.Repository, name string) error {
+	binary, err := repo.IsBinary(name)
+	if err != nil {
+		return err
+	}
+	if binary {
+		p.Binary = true
+		return nil
+	}
+
+	f, err := repo.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	p.Content = string(content)
+
+	return nil
+}
+
+func (h *Handler) serveRaw(w http.ResponseWriter, repo *git.Repository, name string, fi os.FileInfo) {
+	if !fi.Mode().IsRegular() {
+		http.Error(w, "not a file: "+name, http.StatusBadRequest)
+		return
+	}
+
+	binary, err := repo.IsBinary(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	f, err := repo.Open(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	// never let browsers render content of the repository as HTML
+	if binary {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	io.Copy(w, f)
+}
+
+func (h *Handler) render(w http.ResponseWriter, status int, p *page) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := pageTemplate.Execute(w, p); err != nil {
+		fmt.Fprintf(w, "<p>%s</p>", template.HTMLEscapeString(err.Error()))
+	}
+}
+
+func entryType(fi os.FileInfo) string {
+	switch mode := fi.Mode(); {
+	case mode.IsDir():
+		return "dir"
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode&os.ModeIrregular != 0:
+		return "submodule"
+	}
+
+	return "file"
+}
+
+func (h *Handler) url(name, rev string) string {
+	u := url.URL{Path: h.Prefix + "/" + name, RawQuery: url.Values{"rev": {rev}}.Encode()}
+	return u.String()
+}
+
+func (h *Handler) crumbs(name, rev string) []crumb {
+	cs := []crumb{{Name: "/", URL: h.url("", rev)}}
+	if name == "" {
+		return cs
+	}
+
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		cs = append(cs, crumb{Name: part, URL: h.url(path.Join(parts[:i+1]...), rev)})
+	}
+
+	return cs
+}
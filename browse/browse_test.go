@@ -0,0 +1,94 @@
+package browse
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestHandler(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README.md", "# project\n").
+		AddFile("git/git.go", "package git\n\nfunc (repo *Repository) Open(name string) {}\n").
+		Commit("initial")
+
+	h := New(r.GitDir, git.WithModTimeMode(git.ModTimeCommitterDate))
+	h.Prefix = "/code"
+
+	s := httptest.NewServer(http.StripPrefix("/code", h))
+	defer s.Close()
+
+	get := func(path string) (*http.Response, string) {
+		resp, err := http.Get(s.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp, string(body)
+	}
+
+	t.Run("directory", func(t *testing.T) {
+		resp, body := get("/code/git")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, `<a href="/code/git/git.go?rev=HEAD">git.go</a>`)
+		assert.Contains(t, body, `<a href="/code/?rev=HEAD">/</a>`)
+		assert.Contains(t, body, `<form method="get" action="/code/git">`)
+		assert.Contains(t, body, `<option selected>HEAD</option>`)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		resp, body := get("/code/git/git.go?rev=HEAD")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, "package git")
+		assert.Contains(t, body, "func (repo *Repository) Open")
+		assert.Contains(t, body, `href="/code/git/git.go?rev=HEAD&amp;raw=1"`)
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		resp, body := get("/code/git/git.go?rev=HEAD&raw=1")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.Contains(t, body, "func (repo *Repository) Open")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		resp, _ := get("/code/no-such-file")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp, _ = get("/code/?rev=no-such-revision")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestHandler_movingRevision(t *testing.T) {
+	r := gittest.New(t).AddFile("README", "first\n").Commit("first")
+
+	h := New(r.GitDir)
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	raw := func() string {
+		resp, err := http.Get(s.URL + "/README?raw=1")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	assert.Equal(t, "first\n", raw())
+	repo := h.repo
+
+	r.AddFile("README", "second\n").Commit("second")
+	assert.Equal(t, "second\n", raw())
+	assert.Same(t, repo, h.repo, "one Repository serves every request")
+}
@@ -0,0 +1,56 @@
+package browse
+
+import "html/template"
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>/{{.Path}} at {{.Rev}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+pre, td.size, td.type { font-family: monospace; }
+table { border-collapse: collapse; }
+td { padding: 0.1em 1em 0.1em 0; }
+td.size { text-align: right; }
+nav { display: flex; justify-content: space-between; align-items: baseline; border-bottom: 1px solid #ccc; margin-bottom: 1em; }
+pre { background: #f6f6f6; padding: 1em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<nav>
+<h1>{{range $i, $c := .Crumbs}}{{if $i}} / {{end}}<a href="{{$c.URL}}">{{$c.Name}}</a>{{end}}</h1>
+<form method="get" action="{{.Action}}">
+<select name="rev">
+{{- $rev := .Rev}}{{$found := false}}
+{{- range .Refs}}{{if or (eq .Name $rev) (eq .ShortName $rev)}}{{$found = true}}{{end}}{{end}}
+{{- if not $found}}<option selected>{{$rev}}</option>{{end}}
+{{- range .Refs}}
+<option value="{{.ShortName}}"{{if or (eq .Name $rev) (eq .ShortName $rev)}} selected{{end}}>{{.ShortName}}</option>
+{{- end}}
+</select>
+<button>Go</button>
+{{if .Commit}}<small>{{.Commit}}</small>{{end}}
+</form>
+</nav>
+{{if .NotFound -}}
+<p>Not found: /{{.Path}} at {{.Rev}}</p>
+{{- else if .IsDir -}}
+<table>
+{{- range .Entries}}
+<tr><td class="type">{{.Type}}</td><td><a href="{{.URL}}">{{.Name}}</a></td><td class="size">{{if eq .Type "file"}}{{.Size}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- else if .Target -}}
+<p>Symbolic link to <code>{{.Target}}</code></p>
+{{- else if .Gitlink -}}
+<p>Submodule</p>
+{{- else if .Binary -}}
+<p>Binary file. <a href="{{.RawURL}}">Download</a></p>
+{{- else -}}
+<p><a href="{{.RawURL}}">Raw</a></p>
+<pre>{{.Content}}</pre>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,149 @@
+// Command vcsfs-embed generates Go source embedding files of a git
+// revision, so that templates and static assets pinned to a tag can be
+// shipped in a binary without access to the repository at run time.
+//
+//	vcsfs-embed [-r rev] [-git-dir dir] [-pkg name] [-var name] [-o file] [-include glob]... [-exclude glob]... [path]
+//
+// Files under path (the repository root by default) are embedded with
+// their names relative to it, into a variable of *embedfs.FS, which
+// implements fs.FS, fs.ReadDirFS and fs.ReadFileFS like embed.FS does.
+// Symbolic links and submodules are skipped. The globs of -include and
+// -exclude are those of git.WithInclude and git.WithExclude: path.Match
+// patterns, without "**" or negation.
+//
+// It is meant to be run by go:generate:
+//
+//	//go:generate vcsfs-embed -r v1.2.0 -pkg assets -var Templates -o templates.go templates
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+type patterns []string
+
+func (p *patterns) String() string     { return strings.Join(*p, ",") }
+func (p *patterns) Set(v string) error { *p = append(*p, v); return nil }
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "vcsfs-embed: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	var (
+		fs       = flag.NewFlagSet("vcsfs-embed", flag.ContinueOnError)
+		revision = fs.String("r", "HEAD", "revision to embed")
+		gitDir   = fs.String("git-dir", "", "path to the git directory (default: the one of the current directory)")
+		pkg      = fs.String("pkg", "main", "package name of the generated file")
+		varName  = fs.String("var", "FS", "variable name of the generated file system")
+		output   = fs.String("o", "", "file to write to (default: standard output)")
+		includes patterns
+		excludes patterns
+	)
+	fs.Var(&includes, "include", "embed only files matching the path.Match `glob`, against the base name if it has no slash (repeatable)")
+	fs.Var(&excludes, "exclude", "skip files matching the path.Match `glob`, as -include does; a trailing slash matches directories (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	root := ""
+	if fs.NArg() > 0 {
+		root = strings.Trim(path.Clean(fs.Arg(0)), "/")
+		if root == "." {
+			root = ""
+		}
+	}
+
+	opts := []git.Option{git.WithModTimeMode(git.ModTimeCommitterDate)}
+	if len(includes) > 0 {
+		opts = append(opts, git.WithInclude(includes...))
+	}
+	if len(excludes) > 0 {
+		opts = append(opts, git.WithExclude(excludes...))
+	}
+
+	repo, err := git.NewRepository(*revision, *gitDir, opts...)
+	if err != nil {
+		return err
+	}
+
+	// pin the revision so that the snapshot is consistent
+	if err := repo.SetRevision(*revision); err != nil {
+		return err
+	}
+
+	src, err := generate(repo, root, *pkg, *varName, *revision)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = stdout.Write(src)
+		return err
+	}
+
+	return os.WriteFile(*output, src, 0644)
+}
+
+// generate returns formatted Go source embedding files under root of repo,
+// which must be pinned to a commit.
+func generate(repo *git.Repository, root, pkg, varName, revision string) ([]byte, error) {
+	fi, err := repo.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", root)
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "// Code generated by vcsfs-embed; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintf(&buf, "import (\n\t\"time\"\n\n\t\"github.com/motemen/go-vcs-fs/embedfs\"\n)\n\n")
+	fmt.Fprintf(&buf, "// %s holds /%s at %s (%s).\n", varName, root, revision, repo.Revision)
+	fmt.Fprintf(&buf, "var %s = embedfs.New(\n", varName)
+
+	err = repo.Walk(root, git.WalkOptions{}, func(name string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+
+		f, err := repo.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+		fmt.Fprintf(&buf, "\tembedfs.File{Name: %q, Mode: %#o, ModTime: time.Unix(%d, 0), Data: %s},\n",
+			rel, fi.Mode().Perm(), fi.ModTime().Unix(), strconv.Quote(string(data)))
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&buf, ")\n")
+
+	return format.Source(buf.Bytes())
+}
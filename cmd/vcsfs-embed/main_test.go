@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+// newFixture returns the git directory of a repository to embed from.
+func newFixture(t *testing.T) string {
+	return gittest.New(t).
+		AddFile("LICENSE", "MIT License\n").
+		AddFile("git/git.go", "package git\n").
+		AddFile("git/git_test.go", "package git\n").
+		AddFile("git/README.md", "# git\n").
+		Commit("initial").
+		GitDir
+}
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"-git-dir", newFixture(t), "-pkg", "assets", "-var", "Source", "-include", "*.go", "-exclude", "*_test.go", "git"}, &out))
+
+	src := out.String()
+	assert.Contains(t, src, "// Code generated by vcsfs-embed; DO NOT EDIT.\n")
+	assert.Contains(t, src, "package assets\n")
+	assert.Contains(t, src, "var Source = embedfs.New(\n")
+	assert.Contains(t, src, `embedfs.File{Name: "git.go", Mode: 0644,`)
+	assert.NotContains(t, src, `"git_test.go"`)
+	assert.NotContains(t, src, `"README.md"`)
+
+	_, err := parser.ParseFile(token.NewFileSet(), "assets.go", src, 0)
+	assert.NoError(t, err)
+}
+
+func TestRun_notDirectory(t *testing.T) {
+	var out bytes.Buffer
+	assert.Error(t, run([]string{"-git-dir", newFixture(t), "LICENSE"}, &out))
+}
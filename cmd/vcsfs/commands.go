@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+func (rf *repoFlags) open() (*git.Repository, error) {
+	return git.NewRepository(rf.revision, rf.gitDir)
+}
+
+func runLs(args []string, stdout io.Writer) error {
+	fs, rf := newFlagSet("ls")
+	long := fs.Bool("l", false, "use a long listing format")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	repo, err := rf.open()
+	if err != nil {
+		return err
+	}
+
+	fi, err := repo.Stat(dir)
+	if err != nil {
+		return err
+	}
+
+	entries := []os.FileInfo{fi}
+	if fi.IsDir() {
+		entries, err = repo.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if !fi.IsDir() {
+			name = dir
+		}
+		if e.IsDir() {
+			name += "/"
+		}
+
+		if *long {
+			fmt.Fprintf(stdout, "%s %10d %s %s\n", e.Mode(), e.Size(), e.ModTime().Format(time.RFC3339), name)
+		} else {
+			fmt.Fprintln(stdout, name)
+		}
+	}
+
+	return nil
+}
+
+func runCat(args []string, stdout io.Writer) error {
+	fs, rf := newFlagSet("cat")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	repo, err := rf.open()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range fs.Args() {
+		f, err := repo.Open(p)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(stdout, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func runStat(args []string, stdout io.Writer) error {
+	fs, rf := newFlagSet("stat")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	repo, err := rf.open()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range fs.Args() {
+		fi, err := repo.Stat(p)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(stdout, "  File: %s\n", path.Clean(p))
+		fmt.Fprintf(stdout, "  Size: %d\n", fi.Size())
+		fmt.Fprintf(stdout, "  Mode: %s\n", fi.Mode())
+		fmt.Fprintf(stdout, "Modify: %s\n", fi.ModTime().Format(time.RFC3339))
+	}
+
+	return nil
+}
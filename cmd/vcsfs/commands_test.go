@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+// newFixture returns the git directory of a repository to run commands on.
+func newFixture(t *testing.T) string {
+	return gittest.New(t).
+		AddFile("README.md", "# project\n").
+		AddFile("git/git.go", "package git\n").
+		Commit("initial").
+		GitDir
+}
+
+func TestRunLs(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, runLs([]string{"-git-dir", newFixture(t), "-r", "HEAD", "."}, &out))
+
+	assert.Contains(t, out.String(), "git/\n")
+	assert.Contains(t, out.String(), "README.md\n")
+}
+
+func TestRunCat(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, runCat([]string{"-git-dir", newFixture(t), "git/git.go"}, &out))
+
+	assert.Contains(t, out.String(), "package git\n")
+}
+
+func TestRunStat(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, runStat([]string{"-git-dir", newFixture(t), "git"}, &out))
+
+	assert.Contains(t, out.String(), "File: git\n")
+	assert.Contains(t, out.String(), "Size: 0\n")
+}
@@ -0,0 +1,79 @@
+// Command vcsfs reads files of a git repository at any revision
+// through the go-vcs-fs library.
+//
+//	vcsfs ls [-r rev] [-git-dir dir] [-l] [path]
+//	vcsfs cat [-r rev] [-git-dir dir] path...
+//	vcsfs stat [-r rev] [-git-dir dir] path...
+//	vcsfs serve [-rev rev] [-git-dir dir] [-addr addr]
+//	vcsfs mount [-rev rev | -refs] [gitdir] mountpoint
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type command struct {
+	usage string
+	run   func(args []string, stdout io.Writer) error
+}
+
+var commands = map[string]command{
+	"ls":    {"ls [-r rev] [-git-dir dir] [-l] [path]", runLs},
+	"cat":   {"cat [-r rev] [-git-dir dir] path...", runCat},
+	"stat":  {"stat [-r rev] [-git-dir dir] path...", runStat},
+	"serve": {"serve [-rev rev] [-git-dir dir] [-addr addr]", runServe},
+	"mount": {"mount [-rev rev | -refs] [gitdir] mountpoint", runMount},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "vcsfs %s: %s\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{"usage:"}
+	for _, name := range names {
+		lines = append(lines, "  vcsfs "+commands[name].usage)
+	}
+
+	fmt.Fprintln(os.Stderr, strings.Join(lines, "\n"))
+}
+
+// repoFlags are flags common to the commands.
+type repoFlags struct {
+	revision string
+	gitDir   string
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *repoFlags) {
+	fs := flag.NewFlagSet("vcsfs "+name, flag.ContinueOnError)
+	rf := &repoFlags{}
+	fs.StringVar(&rf.revision, "r", "HEAD", "revision to read")
+	fs.StringVar(&rf.revision, "rev", "HEAD", "same as -r")
+	fs.StringVar(&rf.gitDir, "git-dir", "", "path to the git directory (default: the one of the current directory)")
+	return fs, rf
+}
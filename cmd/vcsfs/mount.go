@@ -0,0 +1,54 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"bazil.org/fuse"
+
+	"github.com/motemen/go-vcs-fs/fusefs"
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+func runMount(args []string, stdout io.Writer) error {
+	flags, rf := newFlagSet("mount")
+	allRefs := flags.Bool("refs", false, "mount HEAD, branches and tags, following moving refs")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var mountpoint string
+	switch flags.NArg() {
+	case 1:
+		mountpoint = flags.Arg(0)
+	case 2:
+		rf.gitDir, mountpoint = flags.Arg(0), flags.Arg(1)
+	default:
+		return fmt.Errorf("usage: vcsfs mount [-rev rev | -refs] [gitdir] mountpoint")
+	}
+
+	repo, err := git.NewRepository(rf.revision, rf.gitDir, git.WithModTimeMode(git.ModTimeCommitterDate))
+	if err != nil {
+		return err
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		if err := fuse.Unmount(mountpoint); err != nil {
+			log.Printf("unmount: %s", err)
+		}
+	}()
+
+	if *allRefs {
+		return fusefs.MountRefs(repo, mountpoint)
+	}
+	return fusefs.Mount(repo, mountpoint)
+}
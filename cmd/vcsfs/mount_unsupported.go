@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd)
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+func runMount(args []string, stdout io.Writer) error {
+	return fmt.Errorf("mount is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"path"
+
+	"golang.org/x/tools/godoc/vfs/httpfs"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+func runServe(args []string, stdout io.Writer) error {
+	fs, rf := newFlagSet("serve")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	repo, err := git.NewRepository(rf.revision, rf.gitDir, git.WithModTimeMode(git.ModTimeCommitterDate))
+	if err != nil {
+		return err
+	}
+
+	// the commit the revision points to
+	if err := repo.SetRevision(rf.revision); err != nil {
+		return err
+	}
+
+	log.Printf("serving %s (%s) on %s", rf.revision, repo.Revision, *addr)
+
+	return http.ListenAndServe(*addr, newServeHandler(repo))
+}
+
+// newServeHandler returns a handler serving files of repo. As the content
+// of a blob never changes, its object ID is the ETag of the file.
+func newServeHandler(repo *git.Repository) http.Handler {
+	fileServer := http.FileServer(httpfs.New(repo))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fi, err := repo.Stat(path.Clean(r.URL.Path)); err == nil && fi.Mode().IsRegular() {
+			if oid, ok := git.ObjectID(fi); ok {
+				w.Header().Set("ETag", `"`+oid+`"`)
+			}
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+}
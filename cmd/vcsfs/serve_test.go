@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestServeHandler(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("LICENSE", "MIT License\n").
+		AddFile("git/git.go", "package git\n").
+		Commit("initial")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	s := httptest.NewServer(newServeHandler(repo))
+	defer s.Close()
+
+	res, err := http.Get(s.URL + "/LICENSE")
+	require.NoError(t, err)
+	res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, `"`+r.Git("rev-parse", "HEAD:LICENSE")+`"`, res.Header.Get("ETag"))
+	assert.Equal(t, "text/plain; charset=utf-8", res.Header.Get("Content-Type"))
+
+	req, err := http.NewRequest("GET", s.URL+"/git/git.go", nil)
+	require.NoError(t, err)
+	req.Header.Set("If-None-Match", `"`+r.Git("rev-parse", "HEAD:git/git.go")+`"`)
+
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotModified, res.StatusCode)
+
+	res, err = http.Get(s.URL + "/git")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode, "redirected to /git/")
+	assert.Equal(t, "/git/", res.Request.URL.Path)
+	assert.Empty(t, res.Header.Get("ETag"), "directories have none")
+
+	res, err = http.Get(s.URL + "/nonexistent")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.NotEqual(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get("ETag"))
+}
@@ -0,0 +1,80 @@
+// Package docserver serves API documentation of Go packages in
+// a git.Repository with the presentation layer of golang.org/x/tools/godoc,
+// so that docs of any tag can be browsed without checking it out.
+//
+//	repo, _ := git.NewRepository("v1.2.0", gitDir, git.WithModTimeMode(git.ModTimeCommitterDate))
+//	pres, _ := docserver.New(repo, "example.com/mod")
+//	http.ListenAndServe(":6060", pres)
+//
+// Documentation is then at /pkg/example.com/mod/.
+package docserver
+
+import (
+	"path"
+	"text/template"
+
+	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/static"
+	"golang.org/x/tools/godoc/vfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// New returns a godoc Presentation documenting the packages of repo as if
+// the repository root were importPath. repo should be pinned to a commit,
+// as the directory tree is scanned only once here.
+func New(repo *git.Repository, importPath string) (*godoc.Presentation, error) {
+	// the root must be a directory for the corpus to scan
+	lib := map[string]string{"favicon.ico": static.Files["favicon.ico"]}
+	for name, content := range static.Files {
+		lib["lib/godoc/"+name] = content
+	}
+
+	ns := vfs.NameSpace{}
+	ns.Bind("/", mapfs.New(lib), "/", vfs.BindReplace)
+	ns.Bind(path.Join("/src", importPath), gopathFS{repo}, "/", vfs.BindReplace)
+
+	corpus := godoc.NewCorpus(ns)
+	corpus.IndexEnabled = false
+	if err := corpus.Init(); err != nil {
+		return nil, err
+	}
+
+	pres := godoc.NewPresentation(corpus)
+
+	for _, t := range []struct {
+		dst  **template.Template
+		name string
+	}{
+		{&pres.DirlistHTML, "dirlist.html"},
+		{&pres.ErrorHTML, "error.html"},
+		{&pres.ExampleHTML, "example.html"},
+		{&pres.GodocHTML, "godoc.html"},
+		{&pres.ImplementsHTML, "implements.html"},
+		{&pres.MethodSetHTML, "methodset.html"},
+		{&pres.PackageHTML, "package.html"},
+		{&pres.PackageRootHTML, "packageroot.html"},
+	} {
+		data, err := vfs.ReadFile(ns, path.Join("/lib/godoc", t.name))
+		if err != nil {
+			return nil, err
+		}
+
+		*t.dst, err = template.New(t.name).Funcs(pres.FuncMap()).Parse(string(data))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pres, nil
+}
+
+// gopathFS reports a Repository as a GOPATH tree to godoc.
+type gopathFS struct {
+	*git.Repository
+}
+
+func (fs gopathFS) RootType(name string) vfs.RootType {
+	return vfs.RootTypeGoPath
+}
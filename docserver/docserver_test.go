@@ -0,0 +1,44 @@
+package docserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestNew(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("go.mod", "module github.com/motemen/go-vcs-fs\n").
+		AddFile("git/git.go", "package git\n\n// NewRepository returns a Repository.\nfunc NewRepository() {}\n").
+		Commit("initial")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir, git.WithModTimeMode(git.ModTimeCommitterDate))
+	require.NoError(t, err)
+
+	pres, err := New(repo, "github.com/motemen/go-vcs-fs")
+	require.NoError(t, err)
+
+	s := httptest.NewServer(pres)
+	defer s.Close()
+
+	resp, err := http.Get(s.URL + "/pkg/github.com/motemen/go-vcs-fs/git/")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "func NewRepository")
+
+	resp, err = http.Get(s.URL + "/src/github.com/motemen/go-vcs-fs/git/git.go")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
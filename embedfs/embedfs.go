@@ -0,0 +1,187 @@
+// Package embedfs provides the file system type of the code generated by
+// vcsfs-embed. Like embed.FS, an FS is a read-only collection of files
+// which implements fs.FS, fs.ReadDirFS and fs.ReadFileFS.
+package embedfs
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File is a regular file or a directory of an FS. Directories have
+// fs.ModeDir in Mode and no Data.
+type File struct {
+	Name    string // slash-separated path, without leading slash
+	Mode    fs.FileMode
+	ModTime time.Time
+	Data    string
+}
+
+// FS is a read-only file system of Files.
+type FS struct {
+	files map[string]*File
+	dirs  map[string][]*File // sorted by name
+}
+
+var (
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+)
+
+// New returns an FS of files. Parent directories missing from files are
+// added implicitly.
+func New(files ...File) *FS {
+	fsys := &FS{
+		files: map[string]*File{".": {Name: ".", Mode: fs.ModeDir | 0555}},
+		dirs:  map[string][]*File{},
+	}
+
+	for i := range files {
+		f := files[i]
+		for name := f.Name; name != "."; {
+			if _, ok := fsys.files[name]; ok {
+				break
+			}
+
+			entry := f
+			fsys.files[name] = &entry
+			dir := path.Dir(name)
+			fsys.dirs[dir] = append(fsys.dirs[dir], &entry)
+
+			name = dir
+			f = File{Name: dir, Mode: fs.ModeDir | 0555, ModTime: f.ModTime}
+		}
+	}
+
+	for _, entries := range fsys.dirs {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	}
+
+	return fsys
+}
+
+func (fsys *FS) lookup(op, name string) (*File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	f, ok := fsys.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return f, nil
+}
+
+func (fsys *FS) Open(name string) (fs.File, error) {
+	f, err := fsys.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Mode.IsDir() {
+		return &openDir{fileInfo: fileInfo{f}, entries: fsys.dirs[name]}, nil
+	}
+
+	return &openFile{fileInfo: fileInfo{f}, Reader: strings.NewReader(f.Data)}, nil
+}
+
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	f, err := fsys.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileInfo{f}, nil
+}
+
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	f, err := fsys.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if f.Mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return []byte(f.Data), nil
+}
+
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := fsys.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !f.Mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return dirEntries(fsys.dirs[name]), nil
+}
+
+func dirEntries(files []*File) []fs.DirEntry {
+	entries := make([]fs.DirEntry, len(files))
+	for i, f := range files {
+		entries[i] = fileInfo{f}
+	}
+	return entries
+}
+
+// fileInfo implements fs.FileInfo and fs.DirEntry.
+type fileInfo struct {
+	f *File
+}
+
+func (fi fileInfo) Name() string               { return path.Base(fi.f.Name) }
+func (fi fileInfo) Size() int64                { return int64(len(fi.f.Data)) }
+func (fi fileInfo) Mode() fs.FileMode          { return fi.f.Mode }
+func (fi fileInfo) Type() fs.FileMode          { return fi.f.Mode.Type() }
+func (fi fileInfo) ModTime() time.Time         { return fi.f.ModTime }
+func (fi fileInfo) IsDir() bool                { return fi.f.Mode.IsDir() }
+func (fi fileInfo) Sys() interface{}           { return nil }
+func (fi fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+func (fi fileInfo) String() string             { return fs.FormatFileInfo(fi) }
+
+type openFile struct {
+	fileInfo
+	*strings.Reader
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return f.fileInfo, nil }
+func (f *openFile) Close() error               { return nil }
+
+type openDir struct {
+	fileInfo
+	entries []*File
+	offset  int
+}
+
+func (d *openDir) Stat() (fs.FileInfo, error) { return d.fileInfo, nil }
+func (d *openDir) Close() error               { return nil }
+
+func (d *openDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.f.Name, Err: fs.ErrInvalid}
+}
+
+func (d *openDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return dirEntries(rest), nil
+	}
+
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(rest) {
+		count = len(rest)
+	}
+	d.offset += count
+
+	return dirEntries(rest[:count]), nil
+}
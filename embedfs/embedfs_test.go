@@ -0,0 +1,42 @@
+package embedfs
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFS(t *testing.T) {
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	fsys := New(
+		File{Name: "templates/index.html", Mode: 0444, ModTime: mtime, Data: "<h1>index</h1>"},
+		File{Name: "templates/partials/footer.html", Mode: 0444, ModTime: mtime, Data: "footer"},
+		File{Name: "README", Mode: 0444, ModTime: mtime, Data: "readme"},
+		File{Name: "empty", Mode: fs.ModeDir | 0555, ModTime: mtime},
+	)
+
+	require.NoError(t, fstest.TestFS(fsys, "templates/index.html", "templates/partials/footer.html", "README", "empty"))
+
+	content, err := fs.ReadFile(fsys, "templates/index.html")
+	require.NoError(t, err)
+	assert.Equal(t, "<h1>index</h1>", string(content))
+
+	entries, err := fs.ReadDir(fsys, ".")
+	require.NoError(t, err)
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"README", "empty", "templates"}, names)
+
+	_, err = fsys.Open("nonexistent")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fsys.Open("/README")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
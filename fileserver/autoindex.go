@@ -0,0 +1,117 @@
+package fileserver
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// WithAutoindex makes the Handler list directories without index.html, as
+// autoindex of nginx does, instead of forbidding them. Entries are shown
+// with their sizes and modification times. If commitURL is not nil, each
+// entry also links to commitURL of the last commit touching it, which
+// costs a git process per entry.
+func WithAutoindex(commitURL func(commit string) string) Option {
+	return func(h *Handler) {
+		h.autoindex = true
+		h.commitURL = commitURL
+	}
+}
+
+type indexPage struct {
+	Path    string
+	Parent  bool
+	Commits bool
+	Entries []indexEntry
+}
+
+type indexEntry struct {
+	Name      string
+	URL       string
+	Size      string
+	ModTime   string
+	Commit    string
+	CommitURL string
+}
+
+// serveIndex lists the directory at name.
+func (h *Handler) serveIndex(w http.ResponseWriter, repo *git.Repository, name string) {
+	fis, err := repo.ReadDir(name)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	p := &indexPage{Path: "/" + name, Parent: name != "", Commits: h.commitURL != nil}
+	if name != "" {
+		p.Path += "/"
+	}
+
+	for _, fi := range fis {
+		e := indexEntry{
+			Name:    fi.Name(),
+			Size:    "-",
+			ModTime: fi.ModTime().UTC().Format(time.DateTime),
+		}
+		if fi.IsDir() {
+			e.Name += "/"
+		} else if fi.Mode().IsRegular() {
+			e.Size = strconv.FormatInt(fi.Size(), 10)
+		}
+
+		// as a relative reference, which a name such as "a:b" would not be
+		// taken for, as dirList of net/http does
+		e.URL = (&url.URL{Path: e.Name}).String()
+
+		if h.commitURL != nil {
+			commits, err := repo.RevisionsTouching(path.Join(name, fi.Name()), 1, 0)
+			if err != nil {
+				serveError(w, err)
+				return
+			}
+			if len(commits) > 0 {
+				e.Commit = commits[0]
+				e.CommitURL = h.commitURL(commits[0])
+			}
+		}
+
+		p.Entries = append(p.Entries, e)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, p); err != nil {
+		serveError(w, err)
+	}
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Index of {{.Path}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+table { border-collapse: collapse; font-family: monospace; }
+td { padding: 0.1em 1em 0.1em 0; }
+td.size { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+{{- if .Parent}}
+<tr><td><a href="../">../</a></td><td></td><td class="size"></td>{{if .Commits}}<td></td>{{end}}</tr>
+{{- end}}
+{{- range .Entries}}
+<tr><td><a href="{{.URL}}">{{.Name}}</a></td><td>{{.ModTime}}</td><td class="size">{{.Size}}</td>
+{{- if $.Commits}}<td>{{if .Commit}}<a href="{{.CommitURL}}">{{slice .Commit 0 7}}</a>{{end}}</td>{{end}}</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
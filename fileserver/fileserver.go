@@ -0,0 +1,223 @@
+// Package fileserver serves files of a git.Repository over HTTP.
+//
+// Unlike http.FileServer over an http.FileSystem, it keys ETags on the git
+// object IDs of files, so caches stay valid across commits which do not
+// change a file, and maps errors of the repository to 404 and 403 by their
+// types instead of reporting every failure as a missing file. Byte ranges,
+// conditional requests and HEAD are handled by http.ServeContent.
+//
+// For single-page apps built and committed to the repository, the
+// fallback to an index.html can be set up by WithSPAFallback:
+//
+//	h := fileserver.New(repo, fileserver.WithSPAFallback("dist"), fileserver.WithRewrite(func(name string) string {
+//		return path.Join("dist", name)
+//	}))
+package fileserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// Handler serves files of a Repository. Directories are served by their
+// index.html, and are forbidden without one unless WithMarkdown or
+// WithAutoindex is given.
+type Handler struct {
+	repo      *git.Repository
+	rewrite   func(name string) string
+	spaDir    string
+	spaIndex  bool // spaDir is set
+	autoindex bool
+	commitURL func(commit string) string
+	markdown  func(w io.Writer, source []byte) error
+}
+
+// Option configures a Handler created by New.
+type Option func(*Handler)
+
+// WithRewrite makes the Handler serve the path fn returns for the path of
+// a request, both relative to the root without a leading slash, so that
+// URLs can be mapped to where the files are in the repository.
+func WithRewrite(fn func(name string) string) Option {
+	return func(h *Handler) {
+		h.rewrite = fn
+	}
+}
+
+// WithSPAFallback makes the Handler serve dir/index.html for paths under
+// dir which do not exist, with 200 OK, so that the routes of a single-page
+// app are served its entry point. The paths are those in the repository,
+// after WithRewrite; "" means the whole tree.
+func WithSPAFallback(dir string) Option {
+	return func(h *Handler) {
+		h.spaDir = strings.Trim(path.Clean("/"+dir), "/")
+		h.spaIndex = true
+	}
+}
+
+// New returns a Handler serving repo, which should be pinned to a commit
+// and report ModTime by ModTimeCommitterDate for Last-Modified to be cheap
+// and meaningful.
+func New(repo *git.Repository, opts ...Option) *Handler {
+	h := &Handler{repo: repo}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	urlPath := r.URL.Path
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+	name := strings.TrimPrefix(path.Clean(urlPath), "/")
+	if h.rewrite != nil {
+		name = strings.Trim(path.Clean("/"+h.rewrite(name)), "/")
+	}
+
+	repo := h.repo.WithContext(r.Context())
+	fi, f, redir, err := open(repo, name, strings.HasSuffix(urlPath, "/"))
+	if redir == removeSlash && urlPath == "/" {
+		// a file the root is rewritten to has no URL without the slash
+		fi, f, redir, err = open(repo, name, false)
+	}
+	if errors.Is(err, os.ErrNotExist) && h.fallsBack(name) {
+		fi, f, redir, err = open(repo, path.Join(h.spaDir, "index.html"), false)
+	}
+	if errors.Is(err, errNoIndex) && h.markdown != nil {
+		if readme, rf, _, rerr := open(repo, path.Join(name, "README.md"), false); rerr == nil {
+			fi, f, err = readme, rf, nil
+		}
+	}
+	if errors.Is(err, errNoIndex) && h.autoindex {
+		h.serveIndex(w, repo, name)
+		return
+	}
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	if redir != noRedirect {
+		// relative to the URL requested, which WithRewrite may have
+		// named differently from the file
+		base := path.Base(path.Clean(urlPath))
+		if redir == addSlash {
+			redirect(w, r, base+"/")
+		} else {
+			redirect(w, r, "../"+base)
+		}
+		return
+	}
+	defer f.Close()
+
+	if h.markdown != nil && isMarkdown(fi.Name()) {
+		h.serveMarkdown(w, r, fi, f)
+		return
+	}
+
+	if oid, ok := git.ObjectID(fi); ok {
+		w.Header().Set("ETag", `"`+oid+`"`)
+	}
+
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), f)
+}
+
+// fallsBack reports whether the index.html of the single-page app is
+// served for name if it does not exist.
+func (h *Handler) fallsBack(name string) bool {
+	if !h.spaIndex {
+		return false
+	}
+
+	return h.spaDir == "" || name == h.spaDir || strings.HasPrefix(name, h.spaDir+"/")
+}
+
+// errNoIndex is the error of opening a directory without index.html.
+var errNoIndex = fmt.Errorf("no index.html: %w", os.ErrPermission)
+
+// slashRedirect tells whether a request is to be redirected to add or
+// remove the trailing slash of its URL.
+type slashRedirect int
+
+const (
+	noRedirect slashRedirect = iota
+	addSlash
+	removeSlash
+)
+
+// open opens the file to serve for name, which is index.html for
+// a directory. If the request should be redirected to add or remove the
+// trailing slash, it tells so instead.
+func open(repo *git.Repository, name string, trailingSlash bool) (os.FileInfo, vfs.ReadSeekCloser, slashRedirect, error) {
+	fi, err := repo.Stat(name)
+	if err != nil {
+		return nil, nil, noRedirect, err
+	}
+
+	if fi.IsDir() {
+		if !trailingSlash && name != "" {
+			return nil, nil, addSlash, nil
+		}
+
+		index := path.Join(name, "index.html")
+		fi, err = repo.Stat(index)
+		if errors.Is(err, os.ErrNotExist) {
+			err = &os.PathError{Op: "open", Path: name, Err: errNoIndex}
+		}
+		if err != nil {
+			return nil, nil, noRedirect, err
+		}
+		name = index
+	} else if trailingSlash {
+		return nil, nil, removeSlash, nil
+	}
+
+	if !fi.Mode().IsRegular() {
+		return nil, nil, noRedirect, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+	}
+
+	f, err := repo.Open(name)
+	if err != nil {
+		return nil, nil, noRedirect, err
+	}
+
+	return fi, f, noRedirect, nil
+}
+
+func redirect(w http.ResponseWriter, r *http.Request, location string) {
+	if q := r.URL.RawQuery; q != "" {
+		location += "?" + q
+	}
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+func serveError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		http.Error(w, "404 page not found", http.StatusNotFound)
+	case errors.Is(err, os.ErrPermission):
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+	default:
+		log.Printf("fileserver: %v", err)
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+}
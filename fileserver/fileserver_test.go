@@ -0,0 +1,287 @@
+package fileserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestHandler(t *testing.T) {
+	r := gittest.New(t).AddFile("git/git.go", "package git\n").Commit("initial")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir, git.WithModTimeMode(git.ModTimeCommitterDate), git.WithMaxFileSize(100<<10))
+	require.NoError(t, err)
+
+	s := httptest.NewServer(New(repo))
+	defer s.Close()
+
+	do := func(method, path string, header ...string) (*http.Response, string) {
+		req, err := http.NewRequest(method, s.URL+path, nil)
+		require.NoError(t, err)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp, string(body)
+	}
+
+	resp, body := do("GET", "/git/git.go")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "package git")
+
+	etag := resp.Header.Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{40}"$`, etag)
+	assert.NotEmpty(t, resp.Header.Get("Last-Modified"))
+
+	t.Run("range", func(t *testing.T) {
+		resp, body := do("GET", "/git/git.go", "Range", "bytes=0-6")
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		assert.Equal(t, "package", body)
+	})
+
+	t.Run("conditional", func(t *testing.T) {
+		resp, _ := do("GET", "/git/git.go", "If-None-Match", etag)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+		resp, _ = do("GET", "/git/git.go", "If-None-Match", `"other"`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp, _ = do("GET", "/git/git.go", "If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	})
+
+	t.Run("head", func(t *testing.T) {
+		resp, body := do("HEAD", "/git/git.go")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotEmpty(t, resp.Header.Get("Content-Length"))
+		assert.Empty(t, body)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		resp, _ := do("GET", "/nonexistent")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp, _ = do("GET", "/git/")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, "directory without index.html")
+
+		resp, _ = do("POST", "/git/git.go")
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		resp, _ := do("GET", "/git")
+		assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+		assert.Equal(t, "git/", resp.Header.Get("Location"))
+
+		resp, _ = do("GET", "/git/git.go/")
+		assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+		assert.Equal(t, "../git.go", resp.Header.Get("Location"))
+	})
+}
+
+func TestHandler_index(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("docs/index.html", "<h1>docs</h1>").
+		AddFile("big", strings.Repeat("\x00", 2048)).
+		Commit("init")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir, git.WithMaxFileSize(1024))
+	require.NoError(t, err)
+
+	s := httptest.NewServer(New(repo))
+	defer s.Close()
+
+	resp, err := http.Get(s.URL + "/docs/")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "<h1>docs</h1>", string(body))
+
+	resp, err = http.Get(s.URL + "/big")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "larger than WithMaxFileSize")
+}
+
+func TestHandler_spa(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README.md", "readme").
+		AddFile("dist/index.html", "<div id=app></div>").
+		AddFile("dist/assets/app.js", "app()").
+		Commit("init")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	s := httptest.NewServer(New(repo, WithSPAFallback("dist"), WithRewrite(func(name string) string {
+		return path.Join("dist", name)
+	})))
+	defer s.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(s.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("/")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "<div id=app></div>", body)
+
+	code, body = get("/assets/app.js")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "app()", body)
+
+	code, body = get("/users/42/")
+	assert.Equal(t, http.StatusOK, code, "route of the app")
+	assert.Equal(t, "<div id=app></div>", body)
+
+	code, body = get("/../README.md")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "<div id=app></div>", body, "nothing outside dist is served")
+
+	h := New(repo, WithSPAFallback("dist"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/other/route", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "outside the fallback directory")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/dist/route", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHandler_rewriteRedirect(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("site/documentation/index.html", "<h1>docs</h1>").
+		AddFile("site/documentation/guide.html", "<h1>guide</h1>").
+		Commit("init")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	h := New(repo, WithRewrite(func(name string) string {
+		return strings.Replace(name, "docs", "site/documentation", 1)
+	}))
+
+	for _, tc := range []struct{ path, location string }{
+		{"/docs", "docs/"},
+		{"/docs/guide.html/", "../guide.html"},
+		{"/docs?x=1", "docs/?x=1"},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tc.path, nil))
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code, tc.path)
+		assert.Equal(t, tc.location, rec.Header().Get("Location"), tc.path)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/docs/", nil))
+	assert.Equal(t, "<h1>docs</h1>", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	New(repo, WithRewrite(func(string) string { return "site/documentation/guide.html" })).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "<h1>guide</h1>", rec.Body.String(), "the root rewritten to a file")
+}
+
+func TestHandler_autoindex(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("pkg/a.go", "package pkg\n").
+		AddFile("pkg/sub/b.go", "package sub\n").
+		AddFile("pkg/a:b", "colon\n").
+		AddFile("pkg/c d.txt", "space\n").
+		AddFile("site/index.html", "<h1>site</h1>").
+		Commit("init")
+	commit := r.Git("rev-parse", "HEAD")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir, git.WithModTimeMode(git.ModTimeCommitterDate))
+	require.NoError(t, err)
+
+	h := New(repo, WithAutoindex(func(commit string) string {
+		return "https://example.com/commit/" + commit
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/pkg/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "<title>Index of /pkg/</title>")
+	assert.Contains(t, body, `<a href="../">`)
+	assert.Contains(t, body, `<a href="a.go">a.go</a>`)
+	assert.Contains(t, body, `<a href="sub/">sub/</a>`)
+	assert.Contains(t, body, `<a href="./a:b">a:b</a>`)
+	assert.Contains(t, body, `<a href="c%20d.txt">c d.txt</a>`)
+	assert.Contains(t, body, `<td class="size">12</td>`)
+	assert.Contains(t, body, `<a href="https://example.com/commit/`+commit+`">`+commit[:7]+`</a>`)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/site/", nil))
+	assert.Equal(t, "<h1>site</h1>", rec.Body.String(), "index.html wins")
+
+	rec = httptest.NewRecorder()
+	New(repo).ServeHTTP(rec, httptest.NewRequest("GET", "/pkg/", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code, "without WithAutoindex")
+}
+
+func TestHandler_markdown(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README.md", "# Top\n").
+		AddFile("docs/guide.md", "# Guide\n").
+		AddFile("docs/logo.txt", "# not markdown\n").
+		AddFile("site/index.html", "<h1>site</h1>").
+		AddFile("site/README.md", "# Site\n").
+		Commit("init")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	// renders headings only, enough to tell rendered from raw
+	h := New(repo, WithMarkdown(func(w io.Writer, source []byte) error {
+		_, err := io.WriteString(w, "<h1>"+strings.TrimSpace(strings.TrimPrefix(string(source), "# "))+"</h1>")
+		return err
+	}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := get("/docs/guide.md")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "<title>guide.md</title>")
+	assert.Contains(t, rec.Body.String(), "<h1>Guide</h1>")
+	assert.Regexp(t, `^"[0-9a-f]{40}\.html"$`, rec.Header().Get("ETag"))
+
+	rec = get("/")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<h1>Top</h1>", "README.md of a directory without index.html")
+
+	assert.Equal(t, "<h1>site</h1>", get("/site/").Body.String(), "index.html wins")
+	assert.Equal(t, "# not markdown\n", get("/docs/logo.txt").Body.String())
+	assert.Equal(t, http.StatusForbidden, get("/docs/").Code, "no README.md")
+}
@@ -0,0 +1,91 @@
+package fileserver
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// WithMarkdown makes the Handler serve markdown files, those named *.md or
+// *.markdown, as HTML pages with the body render writes for their source,
+// and directories without index.html by their README.md. Other files are
+// served as they are. The renderer is pluggable so that this package does
+// not depend on a markdown library; with goldmark:
+//
+//	fileserver.WithMarkdown(func(w io.Writer, source []byte) error {
+//		return goldmark.Convert(source, w)
+//	})
+//
+// The output of render is trusted, so it should not pass raw HTML of the
+// source through unless the repository is.
+func WithMarkdown(render func(w io.Writer, source []byte) error) Option {
+	return func(h *Handler) {
+		h.markdown = render
+	}
+}
+
+func isMarkdown(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+type markdownPage struct {
+	Title string
+	Body  template.HTML
+}
+
+// serveMarkdown serves the markdown file f as an HTML page.
+func (h *Handler) serveMarkdown(w http.ResponseWriter, r *http.Request, fi os.FileInfo, f vfs.ReadSeekCloser) {
+	source, err := io.ReadAll(f)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := h.markdown(&body, source); err != nil {
+		serveError(w, err)
+		return
+	}
+
+	var page bytes.Buffer
+	if err := markdownTemplate.Execute(&page, &markdownPage{Title: fi.Name(), Body: template.HTML(body.String())}); err != nil {
+		serveError(w, err)
+		return
+	}
+
+	// the object ID tags the source, not the page made of it
+	if oid, ok := git.ObjectID(fi); ok {
+		w.Header().Set("ETag", `"`+oid+`.html"`)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), bytes.NewReader(page.Bytes()))
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 1em auto; padding: 0 1em; line-height: 1.5; }
+pre { background: #f6f6f6; padding: 1em; overflow-x: auto; }
+</style>
+</head>
+<body>
+{{.Body}}
+</body>
+</html>
+`))
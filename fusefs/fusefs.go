@@ -0,0 +1,161 @@
+//go:build linux || darwin || freebsd
+
+// Package fusefs adapts a git.Repository to bazil.org/fuse so that
+// a revision can be mounted as a read-only filesystem.
+//
+// The content of a pinned revision never changes, so attributes and
+// directory entries are cached by the kernel for a long time, and file
+// contents are kept in the page cache across opens.
+package fusefs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"time"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// DefaultCacheValidity is how long the kernel may cache attributes
+// and directory entries by default.
+const DefaultCacheValidity = time.Hour
+
+// FS implements fs.FS for a Repository.
+type FS struct {
+	repo *git.Repository
+
+	// CacheValidity is how long the kernel may cache attributes and
+	// directory entries. It should be short for repositories that are
+	// not pinned to a commit, e.g. created with git.WithAutoFetch.
+	CacheValidity time.Duration
+}
+
+// New returns a filesystem serving repo.
+func New(repo *git.Repository) *FS {
+	return &FS{repo: repo, CacheValidity: DefaultCacheValidity}
+}
+
+// Mount mounts repo at mountpoint and serves it until unmounted.
+func Mount(repo *git.Repository, mountpoint string) error {
+	c, err := fuse.Mount(
+		mountpoint,
+		fuse.ReadOnly(),
+		fuse.FSName("vcsfs"),
+		fuse.Subtype("vcsfs"),
+	)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := fs.Serve(c, New(repo)); err != nil {
+		return err
+	}
+
+	<-c.Ready
+	return c.MountError
+}
+
+func (f *FS) Root() (fs.Node, error) {
+	return f.node("")
+}
+
+func (f *FS) node(name string) (*Node, error) {
+	fi, err := f.repo.Lstat(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fuse.ENOENT
+	} else if err != nil {
+		return nil, fuse.Errno(syscall.EIO)
+	}
+
+	return &Node{fs: f, path: name, fi: fi}, nil
+}
+
+// Node is a file, directory or symbolic link in FS.
+type Node struct {
+	fs   *FS
+	path string
+	fi   os.FileInfo
+}
+
+var (
+	_ fs.Node                = (*Node)(nil)
+	_ fs.NodeRequestLookuper = (*Node)(nil)
+	_ fs.NodeOpener          = (*Node)(nil)
+	_ fs.NodeReadlinker      = (*Node)(nil)
+	_ fs.HandleReadDirAller  = (*Node)(nil)
+	_ fs.HandleReadAller     = (*Node)(nil)
+)
+
+func (n *Node) Attr(ctx context.Context, a *fuse.Attr) error {
+	a.Valid = n.fs.CacheValidity
+	a.Mode = n.fi.Mode()
+	a.Size = uint64(n.fi.Size())
+	a.Mtime = n.fi.ModTime()
+	a.Ctime = a.Mtime
+	a.Atime = a.Mtime
+	return nil
+}
+
+func (n *Node) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.LookupResponse) (fs.Node, error) {
+	node, err := n.fs.node(path.Join(n.path, req.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	resp.EntryValid = n.fs.CacheValidity
+
+	return node, nil
+}
+
+func (n *Node) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fs.Handle, error) {
+	if !req.Flags.IsReadOnly() {
+		return nil, fuse.Errno(syscall.EROFS)
+	}
+
+	resp.Flags |= fuse.OpenKeepCache
+
+	return n, nil
+}
+
+func (n *Node) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	entries, err := n.fs.repo.ReadDir(n.path)
+	if err != nil {
+		return nil, err
+	}
+
+	dirents := make([]fuse.Dirent, 0, len(entries))
+	for _, e := range entries {
+		d := fuse.Dirent{Name: e.Name(), Type: fuse.DT_File}
+		switch {
+		case e.IsDir():
+			d.Type = fuse.DT_Dir
+		case e.Mode()&os.ModeSymlink != 0:
+			d.Type = fuse.DT_Link
+		}
+		dirents = append(dirents, d)
+	}
+
+	return dirents, nil
+}
+
+func (n *Node) ReadAll(ctx context.Context) ([]byte, error) {
+	f, err := n.fs.repo.Open(n.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+func (n *Node) Readlink(ctx context.Context, req *fuse.ReadlinkRequest) (string, error) {
+	return n.fs.repo.Readlink(n.path)
+}
@@ -0,0 +1,66 @@
+//go:build linux || darwin || freebsd
+
+package fusefs
+
+import (
+	"context"
+	"syscall"
+	"testing"
+
+	"bazil.org/fuse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestFS(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README.md", "# project\n").
+		AddFile("git/git.go", "package git\n").
+		Commit("initial")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	fsys := New(repo)
+
+	root, err := fsys.Root()
+	require.NoError(t, err)
+
+	var a fuse.Attr
+	require.NoError(t, root.Attr(ctx, &a))
+	assert.True(t, a.Mode.IsDir())
+	assert.Equal(t, DefaultCacheValidity, a.Valid)
+
+	dirents, err := root.(*Node).ReadDirAll(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, dirents, fuse.Dirent{Name: "git", Type: fuse.DT_Dir})
+	assert.Contains(t, dirents, fuse.Dirent{Name: "README.md", Type: fuse.DT_File})
+
+	resp := &fuse.LookupResponse{}
+	dir, err := root.(*Node).Lookup(ctx, &fuse.LookupRequest{Name: "git"}, resp)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCacheValidity, resp.EntryValid)
+
+	file, err := dir.(*Node).Lookup(ctx, &fuse.LookupRequest{Name: "git.go"}, resp)
+	require.NoError(t, err)
+
+	openResp := &fuse.OpenResponse{}
+	h, err := file.(*Node).Open(ctx, &fuse.OpenRequest{Flags: fuse.OpenReadOnly}, openResp)
+	require.NoError(t, err)
+	assert.NotZero(t, openResp.Flags&fuse.OpenKeepCache)
+
+	content, err := h.(*Node).ReadAll(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "package git")
+
+	_, err = root.(*Node).Lookup(ctx, &fuse.LookupRequest{Name: "nonexistent"}, resp)
+	assert.Equal(t, fuse.ENOENT, err)
+
+	// failures other than of a missing path are not ENOENT
+	_, err = New(&git.Repository{GitDir: r.GitDir, Revision: "nonexistent"}).Root()
+	assert.Equal(t, fuse.Errno(syscall.EIO), err)
+}
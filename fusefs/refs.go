@@ -0,0 +1,322 @@
+//go:build linux || darwin || freebsd
+
+package fusefs
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// DefaultFollowInterval is how often RefsFS reads the refs by default.
+const DefaultFollowInterval = 5 * time.Second
+
+// RefsFS implements fs.FS for all the refs of a repository at once:
+//
+//	/HEAD/...
+//	/branches/<branch>/...
+//	/tags/<tag>/...
+//
+// Names with slashes, such as "feature/x", are nested directories. Each
+// ref directory is the tree of the commit the ref pointed to when it was
+// looked up. Refs are read again every FollowInterval; while Follow runs,
+// the kernel is told to forget the entries of refs which moved, so that
+// branch directories track their tips while the trees of commits already
+// entered stay as they were.
+type RefsFS struct {
+	repo *git.Repository
+
+	// CacheValidity is how long the kernel may cache attributes and
+	// directory entries inside the trees of commits, which never change.
+	CacheValidity time.Duration
+	// FollowInterval is how often the refs are read, and how long the
+	// kernel may cache the ref directories.
+	FollowInterval time.Duration
+
+	mu        sync.Mutex
+	refs      map[string]string // commit by path, e.g. "branches/main"
+	readAt    time.Time
+	following bool                // refs are read by Follow only
+	roots     map[string]*Node    // by commit
+	dirs      map[string]*refsDir // by path
+}
+
+// NewRefs returns a filesystem serving the refs of repo. repo should be
+// for the RefsFS alone, as it is re-pinned to HEAD whenever the refs are
+// read; the trees of commits share its caches.
+func NewRefs(repo *git.Repository) *RefsFS {
+	return &RefsFS{
+		repo:           repo,
+		CacheValidity:  DefaultCacheValidity,
+		FollowInterval: DefaultFollowInterval,
+		roots:          map[string]*Node{},
+		dirs:           map[string]*refsDir{},
+	}
+}
+
+// MountRefs mounts all the refs of repo at mountpoint and serves them,
+// following moving refs, until unmounted.
+func MountRefs(repo *git.Repository, mountpoint string) error {
+	c, err := fuse.Mount(
+		mountpoint,
+		fuse.ReadOnly(),
+		fuse.FSName("vcsfs"),
+		fuse.Subtype("vcsfs"),
+	)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	fsys := NewRefs(repo)
+	srv := fs.New(c, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go fsys.Follow(ctx, srv)
+
+	if err := srv.Serve(fsys); err != nil {
+		return err
+	}
+
+	<-c.Ready
+	return c.MountError
+}
+
+func (f *RefsFS) Root() (fs.Node, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.dir(""), nil
+}
+
+// Follow reads the refs every FollowInterval until ctx is done, and
+// invalidates the kernel caches of the entries of refs which moved or were
+// deleted. Invalidations are sent from here rather than from the handlers
+// of requests, which the kernel may be waiting on.
+func (f *RefsFS) Follow(ctx context.Context, srv *fs.Server) error {
+	f.mu.Lock()
+	f.following = true
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		f.following = false
+		f.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(f.FollowInterval)
+	defer ticker.Stop()
+
+	type invalidation struct {
+		parent *refsDir
+		name   string
+		root   *Node // of the commit the ref pointed to, if looked up
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		f.mu.Lock()
+		old, roots := f.refs, maps.Clone(f.roots)
+		f.update()
+		var invalidations []invalidation
+		for p, commit := range old {
+			if f.refs[p] != commit {
+				dir, name := path.Split(p)
+				invalidations = append(invalidations, invalidation{f.dir(strings.TrimSuffix(dir, "/")), name, roots[commit]})
+			}
+		}
+		f.mu.Unlock()
+
+		// ErrNotCached is expected for entries the kernel has not seen
+		for _, inv := range invalidations {
+			srv.InvalidateEntry(inv.parent, inv.name)
+			if inv.root != nil {
+				srv.InvalidateNodeAttr(inv.root)
+			}
+		}
+	}
+}
+
+// refsLocked returns the refs by path, reading them if they are older than
+// FollowInterval.
+func (f *RefsFS) refsLocked() map[string]string {
+	if f.refs == nil || !f.following && time.Since(f.readAt) >= f.FollowInterval {
+		f.update()
+	}
+
+	return f.refs
+}
+
+// update reads the refs. If it fails, the refs read last are kept.
+func (f *RefsFS) update() {
+	f.readAt = time.Now()
+
+	refs, err := f.repo.Refs()
+	if err != nil {
+		if f.refs == nil {
+			f.refs = map[string]string{}
+		}
+		return
+	}
+
+	m := map[string]string{}
+	for _, ref := range refs {
+		switch {
+		case strings.HasPrefix(ref.Name, "refs/heads/"):
+			m["branches/"+ref.ShortName()] = ref.Commit
+		case strings.HasPrefix(ref.Name, "refs/tags/"):
+			m["tags/"+ref.ShortName()] = ref.Commit
+		}
+	}
+
+	// HEAD is missing in a repository without commits
+	if err := f.repo.SetRevision("HEAD"); err == nil {
+		if head, err := f.repo.At("HEAD"); err == nil {
+			m["HEAD"] = head.Revision
+		}
+	}
+
+	f.refs = m
+
+	// forget the trees of commits no longer referred to
+	live := map[string]bool{}
+	for _, commit := range m {
+		live[commit] = true
+	}
+	for commit := range f.roots {
+		if !live[commit] {
+			delete(f.roots, commit)
+		}
+	}
+}
+
+// dir returns the node of the directory at p, the same one for each p so
+// that its entries can be invalidated.
+func (f *RefsFS) dir(p string) *refsDir {
+	d, ok := f.dirs[p]
+	if !ok {
+		d = &refsDir{fs: f, path: p}
+		f.dirs[p] = d
+	}
+
+	return d
+}
+
+// root returns the root of the tree of commit.
+func (f *RefsFS) root(commit string) (*Node, error) {
+	if n, ok := f.roots[commit]; ok {
+		return n, nil
+	}
+
+	view, err := f.repo.At(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	rev := New(view)
+	rev.CacheValidity = f.CacheValidity
+
+	n, err := rev.node("")
+	if err != nil {
+		return nil, err
+	}
+	f.roots[commit] = n
+
+	return n, nil
+}
+
+// refsDir is the root, or a directory of refs such as "branches" or
+// "branches/feature".
+type refsDir struct {
+	fs   *RefsFS
+	path string
+}
+
+var (
+	_ fs.Node                = (*refsDir)(nil)
+	_ fs.NodeRequestLookuper = (*refsDir)(nil)
+	_ fs.HandleReadDirAller  = (*refsDir)(nil)
+)
+
+func (d *refsDir) Attr(ctx context.Context, a *fuse.Attr) error {
+	a.Valid = d.fs.FollowInterval
+	a.Mode = os.ModeDir | 0555
+	return nil
+}
+
+func (d *refsDir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.LookupResponse) (fs.Node, error) {
+	f := d.fs
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := path.Join(d.path, req.Name)
+	refs := f.refsLocked()
+
+	resp.EntryValid = f.FollowInterval
+
+	if commit, ok := refs[p]; ok {
+		n, err := f.root(commit)
+		if err != nil {
+			return nil, fuse.Errno(syscall.EIO)
+		}
+		return n, nil
+	}
+
+	if p == "branches" || p == "tags" {
+		return f.dir(p), nil
+	}
+	for ref := range refs {
+		if strings.HasPrefix(ref, p+"/") {
+			return f.dir(p), nil
+		}
+	}
+
+	return nil, fuse.ENOENT
+}
+
+func (d *refsDir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	f := d.fs
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := map[string]bool{}
+	if d.path == "" {
+		names["branches"], names["tags"] = true, true
+	}
+
+	prefix := d.path + "/"
+	if d.path == "" {
+		prefix = ""
+	}
+	for ref := range f.refsLocked() {
+		if rest, ok := strings.CutPrefix(ref, prefix); ok {
+			name, _, _ := strings.Cut(rest, "/")
+			names[name] = true
+		}
+	}
+
+	dirents := make([]fuse.Dirent, 0, len(names))
+	for name := range names {
+		dirents = append(dirents, fuse.Dirent{Name: name, Type: fuse.DT_Dir})
+	}
+	sort.Slice(dirents, func(i, j int) bool { return dirents[i].Name < dirents[j].Name })
+
+	return dirents, nil
+}
@@ -0,0 +1,83 @@
+//go:build linux || darwin || freebsd
+
+package fusefs
+
+import (
+	"context"
+	"testing"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestRefsFS(t *testing.T) {
+	r := gittest.New(t).AddFile("file", "v1\n").Commit("first").Tag("v1")
+	r.Git("branch", "feature/x")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	fsys := NewRefs(repo)
+
+	root, err := fsys.Root()
+	require.NoError(t, err)
+
+	dirents, err := root.(*refsDir).ReadDirAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []fuse.Dirent{
+		{Name: "HEAD", Type: fuse.DT_Dir},
+		{Name: "branches", Type: fuse.DT_Dir},
+		{Name: "tags", Type: fuse.DT_Dir},
+	}, dirents)
+
+	lookup := func(n fs.Node, names ...string) fs.Node {
+		t.Helper()
+		for _, name := range names {
+			l, ok := n.(fs.NodeRequestLookuper)
+			require.True(t, ok, name)
+			resp := &fuse.LookupResponse{}
+			var err error
+			n, err = l.Lookup(ctx, &fuse.LookupRequest{Name: name}, resp)
+			require.NoError(t, err, name)
+		}
+		return n
+	}
+
+	read := func(n fs.Node) string {
+		t.Helper()
+		content, err := n.(*Node).ReadAll(ctx)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	branches, err := lookup(root, "branches").(*refsDir).ReadDirAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []fuse.Dirent{{Name: "feature", Type: fuse.DT_Dir}, {Name: "main", Type: fuse.DT_Dir}}, branches)
+
+	assert.Equal(t, "v1\n", read(lookup(root, "branches", "feature", "x", "file")))
+	assert.Equal(t, "v1\n", read(lookup(root, "tags", "v1", "file")))
+
+	mainV1 := lookup(root, "branches", "main")
+	assert.Equal(t, "v1\n", read(lookup(mainV1, "file")))
+	assert.Same(t, mainV1, lookup(root, "HEAD"), "one node for a commit")
+
+	r.AddFile("file", "v2\n").Commit("second")
+
+	fsys.mu.Lock()
+	fsys.update()
+	fsys.mu.Unlock()
+
+	assert.Equal(t, "v2\n", read(lookup(root, "branches", "main", "file")), "follows the branch")
+	assert.Equal(t, "v2\n", read(lookup(root, "HEAD", "file")))
+	assert.Equal(t, "v1\n", read(lookup(root, "tags", "v1", "file")))
+	assert.Equal(t, "v1\n", read(lookup(mainV1, "file")), "entered trees stay")
+
+	_, err = lookup(root, "branches").(*refsDir).Lookup(ctx, &fuse.LookupRequest{Name: "nonexistent"}, &fuse.LookupResponse{})
+	assert.Equal(t, fuse.ENOENT, err)
+}
@@ -0,0 +1,65 @@
+package git
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// ApplyPatch returns a filesystem of the revision of repo with patch, a
+// unified diff such as one by DiffPatch, applied. Nothing is checked out
+// or committed: the patch is applied to a temporary index and the
+// resulting tree is served, with every entry dated as the revision of
+// repo. Blobs and trees created are left in the object database
+// unreachable, for git gc to prune eventually.
+func ApplyPatch(repo *Repository, patch io.Reader) (vfs.FileSystem, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	dir, err := os.MkdirTemp("", "vcsfs-apply-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	indexed := repo.withEnv("GIT_INDEX_FILE=" + filepath.Join(dir, "index"))
+
+	if _, err := indexed.git("read-tree", "--end-of-options", repo.revision()); err != nil {
+		return nil, err
+	}
+
+	if _, err := indexed.gitInput(patch, "apply", "--cached", "--whitespace=nowarn"); err != nil {
+		return nil, err
+	}
+
+	out, err := indexed.git("write-tree")
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := out.first()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.treeView(tree, repo.revision()), nil
+}
+
+// treeView returns a Repository serving tree, an object made from the
+// commit rev but not committed. As a tree has no history, every entry is
+// dated as rev whatever the ModTimeMode, which the view sets to
+// ModTimeCommitterDate.
+func (repo *Repository) treeView(tree, rev string) *Repository {
+	view := repo.clone()
+	view.Revision = tree
+	view.ModTimeMode = ModTimeCommitterDate
+	view.autoFetchInterval = 0
+
+	if t, err := repo.logTime(rev); err == nil {
+		view.commitTime = &t
+	}
+
+	return view
+}
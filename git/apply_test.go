@@ -0,0 +1,64 @@
+package git
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestApplyPatch(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "one\ntwo\nthree\n").
+		AddFile("b.txt", "b\n").
+		Commit("v1").
+		Tag("v1").
+		Remove("a.txt").
+		AddFile("moved.txt", "one\ntwo\nthree\n").
+		AddFile("b.txt", "B\n").
+		AddExecutable("new.sh", "#!/bin/sh\n").
+		Commit("v2")
+
+	repo, err := NewRepository("v1", r.GitDir)
+	require.NoError(t, err)
+
+	var patch bytes.Buffer
+	require.NoError(t, repo.DiffPatch(&patch, "HEAD"))
+
+	head := r.Head()
+
+	fs, err := ApplyPatch(repo, &patch)
+	require.NoError(t, err)
+
+	b, err := vfs.ReadFile(fs, "b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "B\n", string(b))
+
+	_, err = fs.Stat("a.txt")
+	assert.Error(t, err)
+
+	fi, err := fs.Stat("new.sh")
+	require.NoError(t, err)
+	assert.Equal(t, "-rwxr-xr-x", fi.Mode().String())
+
+	assert.Equal(t, r.Git("rev-parse", "HEAD^{tree}"), fs.(*Repository).Revision)
+	assert.Equal(t, head, r.Head(), "nothing committed")
+
+	// dated as the revision patched, as a tree has no history
+	assert.True(t, gittest.CommitTime(1).Equal(fi.ModTime()), "%v", fi.ModTime())
+	root, err := fs.Stat("")
+	require.NoError(t, err)
+	assert.True(t, gittest.CommitTime(1).Equal(root.ModTime()), "%v", root.ModTime())
+
+	b, err = vfs.ReadFile(repo, "b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "b\n", string(b), "original untouched")
+
+	_, err = ApplyPatch(repo, strings.NewReader("--- a/nonexistent\n+++ b/nonexistent\n@@ -1 +1 @@\n-x\n+y\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,213 @@
+package git
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"time"
+)
+
+// ArchiveOptions controls Archive.
+type ArchiveOptions struct {
+	Prefix string // prepended to the paths of entries, such as "project-1.0/"
+
+	// Reproducible makes the archive depend only on the tree and the
+	// revision, so that archiving the same tag anywhere gives identical
+	// bytes: every entry is dated SOURCE_DATE_EPOCH if set in the
+	// environment, or the committer date of the revision otherwise.
+	Reproducible bool
+}
+
+// Archive writes the tree of the revision to w as a tar archive. Entries
+// are in the order of the tree, owned by uid and gid 0 without user and
+// group names, and dated by ModTime unless opts.Reproducible. Entries
+// hidden by filters are omitted, and submodules are empty directories
+// unless WithRecurseSubmodules is given.
+//
+// When no filters are configured, no gitattributes apply and every entry
+// is dated the committer date of the revision, the archive is made by git
+// archive, which is much faster. Its output differs in details: entries
+// are owned by "root", and the prefix directory and a PAX header with the
+// commit ID are included. With attributes, git archive would convert
+// contents and omit files marked export-ignore, so it is not used.
+func (repo *Repository) Archive(w io.Writer, opts ArchiveOptions) (err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	defer repo.startSpan("Archive", "")(&err)
+
+	native, err := repo.archivesNatively(opts)
+	if err != nil {
+		return err
+	}
+
+	if native {
+		// the global and system attributes are left out, and those of the
+		// tree and info/attributes are known to be absent
+		args := []string{"-c", "tar.umask=0022", "-c", "core.attributesFile=" + os.DevNull, "archive", "--format=tar"}
+		if opts.Prefix != "" {
+			args = append(args, "--prefix="+opts.Prefix)
+		}
+		// entries are not known
+		progress := repo.startProgress("Archive", 0)
+		return repo.withEnv("GIT_ATTR_NOSYSTEM=1").gitTo(progress.writer(w), append(args, "--end-of-options", repo.revision())...)
+	}
+
+	var fixedTime time.Time
+	if opts.Reproducible {
+		fixedTime, err = repo.sourceDate()
+		if err != nil {
+			return err
+		}
+	}
+
+	entries, err := repo.checkoutEntries(true)
+	if err != nil {
+		return err
+	}
+
+	batches := repo.newCatFiles()
+	defer batches.Close()
+
+	exposed := entries[:0]
+	for _, e := range entries {
+		if repo.exposes(e) {
+			exposed = append(exposed, e)
+		}
+	}
+
+	progress := repo.startProgress("Archive", len(exposed))
+	bw := bufio.NewWriter(progress.writer(w))
+	tw := tar.NewWriter(bw)
+
+	for _, e := range exposed {
+		hdr := &tar.Header{
+			Name:    opts.Prefix + e.Path(),
+			Mode:    int64(e.Mode().Perm()),
+			ModTime: fixedTime,
+		}
+		if fixedTime.IsZero() {
+			hdr.ModTime = e.modTime()
+		}
+		// whole seconds need no PAX records
+		hdr.ModTime = hdr.ModTime.Truncate(time.Second)
+
+		batch, err := batches.of(e)
+		if err != nil {
+			return err
+		}
+
+		switch e.objType {
+		case objTypeDir, objTypeGitlink:
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			hdr.Mode = 0755
+
+		case objTypeSymlink:
+			target := new(bytes.Buffer)
+			if err := batch.read(e.sha1, func(string, int64) io.Writer { return target }); err != nil {
+				return err
+			}
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = target.String()
+
+		default:
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = e.size
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if hdr.Typeflag == tar.TypeReg {
+			if err := batch.read(e.sha1, func(string, int64) io.Writer { return tw }); err != nil {
+				return err
+			}
+		}
+
+		if err := progress.entry(0); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// archivesNatively reports whether git archive makes the archive Archive
+// would with opts.
+func (repo *Repository) archivesNatively(opts ArchiveOptions) (bool, error) {
+	if repo.recurseSubmodules || len(repo.sparseDirs) > 0 || len(repo.includes) > 0 || len(repo.excludes) > 0 {
+		return false, nil
+	}
+
+	if opts.Reproducible {
+		if os.Getenv("SOURCE_DATE_EPOCH") != "" {
+			return false, nil
+		}
+	} else if repo.ModTimeMode != ModTimeCommitterDate {
+		return false, nil
+	}
+
+	// git archive dates entries of a tree by the current time
+	if _, err := repo.resolveCommit(repo.revision()); err != nil {
+		return false, nil
+	}
+
+	attrs, err := repo.hasAttributes()
+	return !attrs && err == nil, err
+}
+
+// hasAttributes reports whether the tree of the revision has .gitattributes
+// files, or the repository has info/attributes, either of which git archive
+// would apply.
+func (repo *Repository) hasAttributes() (bool, error) {
+	infoAttrs, err := repo.revParse("--git-path", "info/attributes")
+	if err != nil {
+		return false, err
+	}
+	if fi, err := os.Stat(infoAttrs); err == nil && fi.Size() > 0 {
+		return true, nil
+	}
+
+	out, err := repo.git("ls-tree", "-r", "--name-only", "-z", "--end-of-options", repo.revision())
+	if err != nil {
+		return false, err
+	}
+
+	names, err := out.lines('\x00')
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range names {
+		if path.Base(name) == ".gitattributes" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// sourceDate returns the time given by SOURCE_DATE_EPOCH, or the committer
+// date of the revision if it is not set.
+func (repo *Repository) sourceDate() (time.Time, error) {
+	if s := os.Getenv("SOURCE_DATE_EPOCH"); s != "" {
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH: %q", s)
+		}
+		return time.Unix(sec, 0), nil
+	}
+
+	return repo.committerTime()
+}
@@ -0,0 +1,148 @@
+package git
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestArchive(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README", "readme\n").
+		Commit("first").
+		AddExecutable("bin/run", "#!/bin/sh\n").
+		AddSymlink("link", "README").
+		Commit("second")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, repo.Archive(&buf, ArchiveOptions{Prefix: "p/"}))
+
+	type entry struct {
+		name, link, content string
+		typ                 byte
+		mode                int64
+		mtime               int64
+	}
+
+	read := func(b []byte) []entry {
+		entries := []entry{}
+		tr := tar.NewReader(bytes.NewReader(b))
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 0, hdr.Uid)
+			assert.Equal(t, "", hdr.Uname)
+
+			content, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			entries = append(entries, entry{hdr.Name, hdr.Linkname, string(content), hdr.Typeflag, hdr.Mode, hdr.ModTime.Unix()})
+		}
+		return entries
+	}
+
+	c1, c2 := gittest.CommitTime(1).Unix(), gittest.CommitTime(2).Unix()
+	assert.Equal(t, []entry{
+		{"p/README", "", "readme\n", tar.TypeReg, 0644, c1},
+		{"p/bin/", "", "", tar.TypeDir, 0755, c2},
+		{"p/bin/run", "", "#!/bin/sh\n", tar.TypeReg, 0755, c2},
+		{"p/link", "README", "", tar.TypeSymlink, 0777, c2},
+	}, read(buf.Bytes()))
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1234567890")
+
+	var a, b bytes.Buffer
+	require.NoError(t, repo.Archive(&a, ArchiveOptions{Reproducible: true}))
+	repo.ModTimeMode = ModTimeCommitterDate
+	require.NoError(t, repo.Archive(&b, ArchiveOptions{Reproducible: true}))
+	assert.Equal(t, a.Bytes(), b.Bytes())
+	for _, e := range read(a.Bytes()) {
+		assert.Equal(t, int64(1234567890), e.mtime, e.name)
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	assert.Error(t, repo.Archive(io.Discard, ArchiveOptions{Reproducible: true}))
+}
+
+func TestArchive_native(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README", "readme\n").
+		AddExecutable("bin/run", "#!/bin/sh\n").
+		Commit("first")
+
+	o := &testObserver{}
+	repo, err := NewRepository("HEAD", r.GitDir, WithObserver(o))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, repo.Archive(&buf, ArchiveOptions{Prefix: "p/", Reproducible: true}))
+	assert.Contains(t, o.execs[len(o.execs)-1], "archive")
+
+	modes := map[string]int64{}
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		assert.Equal(t, gittest.CommitTime(1).Unix(), hdr.ModTime.Unix(), hdr.Name)
+		modes[hdr.Name] = hdr.Mode
+	}
+	assert.Equal(t, map[string]int64{"p/": 0755, "p/README": 0644, "p/bin/": 0755, "p/bin/run": 0755}, modes)
+
+	o.execs = nil
+	filtered, err := NewRepository("HEAD", r.GitDir, WithObserver(o), WithExclude("bin"))
+	require.NoError(t, err)
+	require.NoError(t, filtered.Archive(io.Discard, ArchiveOptions{Reproducible: true}))
+	for _, args := range o.execs {
+		assert.NotContains(t, args, "archive")
+	}
+}
+
+func TestArchive_attributes(t *testing.T) {
+	r := gittest.New(t).
+		AddFile(".gitattributes", "* text eol=crlf\nsecret export-ignore\n").
+		AddFile("a.txt", "a\nb\n").
+		AddFile("secret", "kept\n").
+		Commit("first")
+
+	o := &testObserver{}
+	repo, err := NewRepository("HEAD", r.GitDir, WithObserver(o), WithModTimeMode(ModTimeCommitterDate))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, repo.Archive(&buf, ArchiveOptions{}))
+	for _, args := range o.execs {
+		assert.NotContains(t, args, "archive")
+	}
+
+	files := map[string]string{}
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		b, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(b)
+	}
+	assert.Equal(t, "a\nb\n", files["a.txt"], "contents of the blob")
+	assert.Equal(t, "kept\n", files["secret"])
+}
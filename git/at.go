@@ -0,0 +1,60 @@
+package git
+
+import (
+	"context"
+	"maps"
+)
+
+// At returns a Repository pinned to the commit rev resolves to, sharing
+// the configuration and the caches of repo: listings of trees common to
+// both revisions and blob contents are fetched only once. Serving many
+// revisions of one repository this way costs little more than serving
+// one. The view does not auto-fetch, even if repo does.
+func (repo *Repository) At(rev string) (*Repository, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	commit, err := repo.resolveCommit(rev)
+	if err != nil {
+		return nil, err
+	}
+
+	if repo.treeCache == nil {
+		repo.treeCache = repo.newTreeCache()
+	}
+
+	view := repo.clone()
+	view.Revision = commit
+	view.autoFetchInterval = 0
+	view.tracking = ""
+	view.treeCache = repo.treeCache
+
+	return view, nil
+}
+
+// WithContext returns a Repository serving the same revision as repo whose
+// git commands run with ctx, sharing the configuration and the caches of
+// repo like At. When ctx is done, running git processes are killed and the
+// operation waiting for them returns an error wrapping ctx.Err(). It is
+// meant for binding a Repository shared by a server to each request. The
+// view does not auto-fetch, but repo does before it is made if due.
+func (repo *Repository) WithContext(ctx context.Context) *Repository {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.autoFetch()
+
+	if repo.treeCache == nil {
+		repo.treeCache = repo.newTreeCache()
+	}
+
+	view := repo.clone()
+	view.ctx = ctx
+	view.autoFetchInterval = 0
+	view.tracking = ""
+	view.treeCache = repo.treeCache
+	view.snapshot = repo.snapshot
+	view.resolved = maps.Clone(repo.resolved)
+
+	return view
+}
@@ -0,0 +1,136 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestAt(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("shared/a.txt", "a\n").
+		AddFile("file", "first\n").
+		Commit("first")
+	first := r.Head()
+	r.AddFile("file", "second\n").Commit("second")
+
+	o := &testObserver{}
+	repo, err := NewRepository("main", r.GitDir, WithObserver(o))
+	require.NoError(t, err)
+
+	_, err = repo.ReadDir("shared")
+	require.NoError(t, err)
+
+	view, err := repo.At(first)
+	require.NoError(t, err)
+	assert.Equal(t, first, view.Revision)
+
+	o.miss = 0
+	entries, err := view.ReadDir("shared")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 1, o.miss, "only the root tree differs")
+	assert.Equal(t, int64(2), entries[0].Size())
+
+	content, err := vfs.ReadFile(view, "file")
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", string(content))
+
+	content, err = vfs.ReadFile(repo, "file")
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(content))
+
+	_, err = repo.At("nonexistent")
+	assert.Error(t, err)
+}
+
+func TestAt_concurrent(t *testing.T) {
+	r := gittest.New(t).AddFile("dir/file", "first\n").Commit("first")
+	first := r.Head()
+	r.AddFile("dir/file", "second\n").Commit("second")
+
+	repo, err := NewRepository("main", r.GitDir)
+	require.NoError(t, err)
+
+	view, err := repo.At(first)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for fs, want := range map[*Repository]string{repo: "second\n", view: "first\n"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				entries, err := fs.ReadDir("dir")
+				if assert.NoError(t, err) && assert.Len(t, entries, 1) {
+					assert.Equal(t, int64(len(want)), entries[0].Size())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRepository_WithContext(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("dir/a.txt", "a\n").
+		AddFile("large", strings.Repeat("x", 1<<20)).
+		Commit("first")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	view := repo.WithContext(ctx)
+
+	_, err = view.ReadDir("dir")
+	require.NoError(t, err)
+
+	cancel()
+
+	_, err = view.Open("large")
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+
+	// listings cached are shared
+	entries, err := view.ReadDir("dir")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = repo.Open("large")
+	assert.NoError(t, err)
+
+	// stops walking
+	ctx, cancel = context.WithCancel(context.Background())
+	err = repo.WithContext(ctx).Walk("", WalkOptions{}, func(name string, fi os.FileInfo, err error) error {
+		cancel()
+		return err
+	})
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+}
+
+func TestRepository_WithContext_kill(t *testing.T) {
+	gitPath := filepath.Join(t.TempDir(), "git")
+	require.NoError(t, os.WriteFile(gitPath, []byte("#!/bin/sh\nsleep 10\n"), 0755))
+
+	repo := &Repository{}
+	WithGitPath(gitPath)(repo)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := repo.WithContext(ctx).Stat("git/git.go")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,81 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attributes returns the gitattributes of path, as reported by
+// git check-attr --cached. Values are either "set", "unset", "unspecified"
+// or the value assigned. If no attrs are given, all attributes specified
+// for the path are returned.
+//
+// Attributes are read from .gitattributes files in the index, or the one
+// given by WithIndexFile, so they reflect the staged state rather than the
+// repository's revision.
+func (repo *Repository) Attributes(path string, attrs ...string) (map[string]string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.attributes(path, attrs...)
+}
+
+func (repo *Repository) attributes(path string, attrs ...string) (map[string]string, error) {
+	path, err := repo.rootRelative(repo.encodePath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"check-attr", "--cached", "-z"}
+	if len(attrs) == 0 {
+		args = append(args, "-a")
+	} else {
+		for _, attr := range attrs {
+			if strings.HasPrefix(attr, "-") {
+				return nil, fmt.Errorf("invalid attribute name: %q", attr)
+			}
+		}
+		args = append(args, attrs...)
+	}
+	args = append(args, "--", path)
+
+	out, err := repo.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := out.lines('\x00')
+	if err != nil {
+		return nil, err
+	}
+
+	// example output (NUL shown as "|"):
+	//   git.go|diff|unspecified|git.go|eol|lf|
+	if len(fields)%3 != 1 {
+		return nil, fmt.Errorf("could not parse check-attr output: %q", out.String())
+	}
+
+	result := map[string]string{}
+	for i := 0; i+2 < len(fields); i += 3 {
+		result[fields[i+1]] = fields[i+2]
+	}
+
+	return result, nil
+}
+
+// rootRelative converts a path relative to the repository root into
+// one that git commands taking plain paths (not pathspecs) understand.
+// When GitDir is given git regards the current directory as the top of
+// the work tree, otherwise paths are relative to the current directory.
+func (repo *Repository) rootRelative(path string) (string, error) {
+	if repo.GitDir != "" {
+		return path, nil
+	}
+
+	cdup, err := repo.revParse("--show-cdup")
+	if err != nil {
+		return "", err
+	}
+
+	return cdup + path, nil
+}
@@ -0,0 +1,21 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributes(t *testing.T) {
+	repo := Repository{GitDir: newFixture(t).GitDir}
+
+	attrs, err := repo.Attributes("git/git.go", "diff", "eol")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"diff": "unspecified", "eol": "unspecified"}, attrs)
+
+	attrs, err = repo.Attributes("git/git.go")
+	require.NoError(t, err)
+	assert.Empty(t, attrs)
+}
@@ -0,0 +1,86 @@
+package git
+
+import "time"
+
+// WithAutoFetch makes the Repository track the revision given to the
+// constructor (typically a branch of a repository created by NewFromURL):
+// when the filesystem is accessed and more than interval has passed since
+// the last fetch, the remote is fetched and the Repository is re-pinned to
+// where the revision points to. A failed fetch keeps the current pin.
+func WithAutoFetch(interval time.Duration) Option {
+	return func(repo *Repository) {
+		repo.autoFetchInterval = interval
+	}
+}
+
+func (repo *Repository) autoFetch() {
+	if repo.autoFetchInterval <= 0 || time.Since(repo.lastFetch) < repo.autoFetchInterval {
+		return
+	}
+
+	repo.refresh()
+}
+
+// Refresh fetches from the remote "origin" and re-pins the Repository to
+// the commit the tracked revision now points to. Without WithAutoFetch,
+// the tracked revision is the current one.
+func (repo *Repository) Refresh() error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.refresh()
+}
+
+func (repo *Repository) refresh() error {
+	repo.lastFetch = time.Now()
+
+	if _, err := repo.git("fetch", "--quiet", "--prune", "origin"); err != nil {
+		return err
+	}
+
+	tracking := repo.tracking
+	if tracking == "" {
+		tracking = repo.revision()
+	}
+
+	return repo.pin(tracking)
+}
+
+// SetRevision makes the Repository serve rev, resolved to a commit now.
+// Unlike assigning Revision, it is safe while the Repository is in use:
+// operations in progress complete on the old revision and later ones see
+// the new one. If rev does not resolve, the current revision is kept.
+// With WithAutoFetch, rev becomes the tracked revision.
+func (repo *Repository) SetRevision(rev string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if err := repo.pin(rev); err != nil {
+		return err
+	}
+
+	if repo.autoFetchInterval > 0 {
+		repo.tracking = rev
+	}
+
+	return nil
+}
+
+// pin resolves rev and makes it the revision served. Cached listings stay
+// valid as they are keyed by object IDs, while memoized resolutions of
+// revisions are forgotten.
+func (repo *Repository) pin(rev string) error {
+	repo.resolved = nil
+
+	commit, err := repo.resolveCommit(rev)
+	if err != nil {
+		return err
+	}
+
+	if commit != repo.Revision {
+		repo.Revision = commit
+		repo.commitTime = nil
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package git
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestWithAutoFetch(t *testing.T) {
+	upstream := gittest.New(t).Commit("first")
+
+	repo, err := NewFromURL(upstream.Dir, "main", filepath.Join(t.TempDir(), "cache"), WithAutoFetch(time.Nanosecond))
+	require.NoError(t, err)
+	first := repo.Revision
+
+	files, err := repo.ReadDir(".")
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	upstream.Commit("second")
+
+	time.Sleep(time.Millisecond)
+
+	_, err = repo.ReadDir(".")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, repo.Revision)
+	assert.Regexp(t, `^[0-9a-f]{40}$`, repo.Revision)
+}
+
+func TestSetRevision(t *testing.T) {
+	r := gittest.New(t).AddFile("file", "first\n").Commit("first")
+	first := r.Head()
+	r.AddFile("file", "second\n").Commit("second")
+	second := r.Head()
+
+	repo, err := NewRepository(first, r.GitDir)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				content, err := vfs.ReadFile(repo, "file")
+				if assert.NoError(t, err) {
+					assert.Contains(t, []string{"first\n", "second\n"}, string(content))
+				}
+			}
+		}()
+	}
+
+	require.NoError(t, repo.SetRevision(second))
+	wg.Wait()
+
+	content, err := vfs.ReadFile(repo, "file")
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(content))
+
+	assert.Error(t, repo.SetRevision("nonexistent"))
+	assert.Equal(t, second, repo.Revision)
+}
@@ -0,0 +1,114 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// catFile is a running git cat-file --batch process, which reads
+// many objects without spawning a process for each.
+type catFile struct {
+	ctx    context.Context
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	broken bool // if an object was left partially read
+}
+
+func (repo *Repository) startCatFile(ctx context.Context) (*catFile, error) {
+	cmd := repo.commandContext(ctx, "cat-file", "--batch")
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &catFile{
+		ctx:    ctx,
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+	}, nil
+}
+
+// read copies the content of the object to the writer returned by w,
+// which is called with the type and the size of the object.
+// It returns ErrMissingObject if the object does not exist.
+//
+// example header:
+//   e69de29bb2d1d6434b8b29ae775ad8c2e48c5391 blob 0
+//   e69de29bb2d1d6434b8b29ae775ad8c2e48c5391 missing
+func (c *catFile) read(oid string, w func(objType string, size int64) io.Writer) error {
+	if c.broken {
+		return fmt.Errorf("cat-file: previous read failed")
+	}
+
+	err := c.readObject(oid, w)
+	if err != nil && err != ErrMissingObject {
+		c.broken = true
+		if ctxErr := c.ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("%w: %v", ctxErr, err)
+		}
+	}
+
+	return err
+}
+
+func (c *catFile) readObject(oid string, w func(objType string, size int64) io.Writer) error {
+	if _, err := io.WriteString(c.stdin, oid+"\n"); err != nil {
+		return err
+	}
+
+	header, err := c.stdout.ReadString('\n')
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(header)
+	if len(fields) == 2 && fields[1] == "missing" {
+		return ErrMissingObject
+	}
+	if len(fields) != 3 {
+		return fmt.Errorf("could not parse cat-file header: %q", header)
+	}
+
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.CopyN(w(fields[1], size), c.stdout, size); err != nil {
+		return err
+	}
+
+	// content is followed by a LF
+	_, err = c.stdout.Discard(1)
+	return err
+}
+
+// Close stops git. If a read failed, git may be blocked writing the rest
+// of an object nobody reads, so it is killed rather than waited for.
+func (c *catFile) Close() error {
+	c.stdin.Close()
+	if c.broken {
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+		return nil
+	}
+
+	return c.cmd.Wait()
+}
@@ -0,0 +1,54 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestCatFile_abandoned(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("large", strings.Repeat("x", 1<<20)).
+		Commit("first")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	oid, err := repo.objectID("large")
+	require.NoError(t, err)
+
+	batch, err := repo.startCatFile(context.Background())
+	require.NoError(t, err)
+
+	// git is left blocked writing the rest of the blob
+	boom := errors.New("boom")
+	err = batch.read(oid, func(string, int64) io.Writer { return failingWriter{boom} })
+	assert.True(t, errors.Is(err, boom), "%v", err)
+
+	err = batch.read(oid, func(string, int64) io.Writer { return io.Discard })
+	assert.Error(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		batch.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked")
+	}
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write([]byte) (int, error) { return 0, w.err }
@@ -0,0 +1,137 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// binaryCheckSize is the number of leading bytes git itself inspects
+// to decide whether a blob is binary.
+const binaryCheckSize = 8000
+
+// IsBinary reports whether the file at path is binary by git's heuristic.
+// The diff attribute decides first, as read by Attributes: unset, as by
+// the binary macro, makes it binary and set makes it text, and a diff
+// driver decides by its binary setting if it has one. Otherwise it is
+// binary if a NUL byte appears in its first 8000 bytes, of which only the
+// leading part of the blob is read.
+func (repo *Repository) IsBinary(path string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	fi, err := repo.stat(path)
+	if err != nil {
+		return false, err
+	}
+	if fi.objType != objTypeRegular {
+		return false, fmt.Errorf("not a regular blob")
+	}
+
+	if binary, ok, err := repo.binaryByAttributes(fi.Path()); err != nil || ok {
+		return binary, err
+	}
+
+	head, err := repo.blobHead(fi.sha1, binaryCheckSize)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.IndexByte(head, 0) != -1, nil
+}
+
+// binaryByAttributes reports whether path is binary by its diff attribute,
+// and whether the attribute decides it.
+func (repo *Repository) binaryByAttributes(path string) (binary, ok bool, err error) {
+	attrs, err := repo.attributes(path, "diff")
+	if err != nil {
+		return false, false, err
+	}
+
+	switch driver := attrs["diff"]; driver {
+	case "unset":
+		return true, true, nil
+	case "set":
+		return false, true, nil
+	case "unspecified", "":
+		return false, false, nil
+	default:
+		out, err := repo.git("config", "--type=bool", "--get", "diff."+driver+".binary")
+		if isExitCode(err, 1) {
+			return false, false, nil
+		} else if err != nil {
+			return false, false, err
+		}
+
+		value, err := out.first()
+		return value == "true", err == nil, err
+	}
+}
+
+// blobHead reads at most n bytes from the beginning of the blob
+// and stops git without consuming the rest.
+func (repo *Repository) blobHead(sha1 string, n int64) ([]byte, error) {
+	if content, ok := repo.blobCache.get(sha1); ok {
+		repo.observeCache("blob", true)
+		if int64(len(content)) > n {
+			content = content[:n]
+		}
+		return content, nil
+	}
+
+	ctx, cancel := repo.commandCtx()
+	defer cancel()
+
+	stderr := new(bytes.Buffer)
+	cmd := repo.commandContext(ctx, "cat-file", "blob", sha1)
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	endSpan := repo.startExecSpan(cmd)
+	if err := cmd.Start(); err != nil {
+		endSpan(err)
+		return nil, err
+	}
+
+	head, readErr := io.ReadAll(io.LimitReader(stdout, n))
+
+	// the rest of the blob is not needed; git is killed in that case,
+	// so its exit status is only meaningful when we reached EOF
+	truncated := int64(len(head)) == n
+	if truncated || readErr != nil {
+		cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+	if truncated {
+		endSpan(nil)
+		repo.observeExec(cmd, start, head, nil)
+	} else {
+		endSpan(waitErr)
+		repo.observeExec(cmd, start, head, waitErr)
+	}
+
+	if ctx.Err() != nil && (readErr != nil || waitErr != nil && !truncated) {
+		return nil, fmt.Errorf("%w: %v", ctx.Err(), errors.Join(readErr, waitErr))
+	}
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	if waitErr != nil && !truncated {
+		if exitErr, ok := waitErr.(*exec.ExitError); ok {
+			return nil, newGitError(cmd, exitErr, stderr.String())
+		}
+		return nil, waitErr
+	}
+
+	return head, nil
+}
@@ -0,0 +1,52 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestIsBinary(t *testing.T) {
+	repo := Repository{GitDir: newFixture(t).GitDir}
+
+	binary, err := repo.IsBinary("git/git.go")
+	require.NoError(t, err)
+	assert.False(t, binary)
+
+	_, err = repo.IsBinary("git")
+	assert.Error(t, err)
+}
+
+func TestIsBinary_attributes(t *testing.T) {
+	r := gittest.New(t).
+		AddFile(".gitattributes", "*.dat binary\n*.txt diff\n*.bin -diff\n*.drv diff=plain\n*.hex diff=hex\n").
+		AddFile("a.dat", "text\n").
+		AddFile("nul.txt", "text\x00\n").
+		AddFile("a.bin", "text\n").
+		AddFile("a.drv", "text\n").
+		AddFile("nul.drv", "text\x00\n").
+		AddFile("a.hex", "text\n").
+		AddFile("nul.go", "text\x00\n").
+		Commit("init")
+	r.Git("config", "diff.hex.binary", "true")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	for name, want := range map[string]bool{
+		"a.dat":   true,  // the binary macro
+		"nul.txt": false, // diff set
+		"a.bin":   true,  // diff unset
+		"a.drv":   false, // a driver without binary, by the content
+		"nul.drv": true,
+		"a.hex":   true, // a driver with binary
+		"nul.go":  true, // unspecified, by the content
+	} {
+		binary, err := repo.IsBinary(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, binary, name)
+	}
+}
@@ -0,0 +1,123 @@
+package git
+
+import (
+	"container/list"
+	"sync"
+)
+
+// BlobCache caches contents of blobs keyed by object ID. Since a blob is
+// immutable and identified by its content, one cache can be shared by
+// Repositories of any revisions and of any repositories, e.g. forks or
+// mirrors of the same project, which then read each blob from git once.
+// It is safe for concurrent use.
+type BlobCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	lru      *list.List
+	items    map[string]*list.Element
+	budget   *MemoryBudget
+}
+
+type blobCacheItem struct {
+	oid     string
+	content []byte
+}
+
+// SharedBlobCache is a process-wide BlobCache of 64 MiB, to be given to
+// WithBlobCache.
+var SharedBlobCache = NewBlobCache(64 << 20)
+
+// NewBlobCache returns a BlobCache holding at most maxBytes of contents,
+// evicting the least recently used blobs. Blobs larger than maxBytes are
+// not cached.
+func NewBlobCache(maxBytes int64) *BlobCache {
+	return &BlobCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// WithBlobCache makes the Repository consult c before reading blobs from
+// git, and fill it after.
+func WithBlobCache(c *BlobCache) Option {
+	return func(repo *Repository) {
+		repo.blobCache = c
+	}
+}
+
+// Len returns the number of blobs in the cache.
+func (c *BlobCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *BlobCache) get(oid string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[oid]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+
+	return el.Value.(*blobCacheItem).content, true
+}
+
+// add caches content, which must not be modified afterwards.
+func (c *BlobCache) add(oid string, content []byte) {
+	if c == nil || int64(len(content)) > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+
+	if _, ok := c.items[oid]; ok {
+		c.mu.Unlock()
+		return
+	}
+
+	before := c.bytes
+	c.items[oid] = c.lru.PushFront(&blobCacheItem{oid: oid, content: content})
+	c.bytes += int64(len(content))
+
+	for c.bytes > c.maxBytes {
+		c.removeOldest()
+	}
+
+	delta := c.bytes - before
+	c.mu.Unlock()
+
+	c.budget.charge(c, blobCacheWeight, delta)
+}
+
+// removeOldest removes the least recently used blob and returns its size.
+func (c *BlobCache) removeOldest() int64 {
+	el := c.lru.Back()
+	if el == nil {
+		return 0
+	}
+
+	item := el.Value.(*blobCacheItem)
+	c.lru.Remove(el)
+	delete(c.items, item.oid)
+	c.bytes -= int64(len(item.content))
+
+	return int64(len(item.content))
+}
+
+func (c *BlobCache) evictOldest() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.removeOldest()
+}
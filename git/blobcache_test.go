@@ -0,0 +1,83 @@
+package git
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestBlobCache(t *testing.T) {
+	c := NewBlobCache(10)
+
+	c.add("a", []byte("aaaa"))
+	c.add("b", []byte("bbbb"))
+
+	_, ok := c.get("a")
+	assert.True(t, ok)
+
+	c.add("c", []byte("cccc"))
+
+	_, ok = c.get("b")
+	assert.False(t, ok, "least recently used one is evicted")
+	_, ok = c.get("a")
+	assert.True(t, ok)
+
+	c.add("large", make([]byte, 11))
+	_, ok = c.get("large")
+	assert.False(t, ok, "larger than the cache")
+	assert.Equal(t, 2, c.Len())
+
+	var nilCache *BlobCache
+	_, ok = nilCache.get("a")
+	assert.False(t, ok)
+}
+
+func TestWithBlobCache(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "shared\n").
+		Commit("v1").
+		Tag("v1").
+		AddFile("b.txt", "b\n").
+		Commit("v2")
+
+	c := NewBlobCache(1 << 20)
+
+	catFiles := func(repo *Repository) int {
+		n := 0
+		for _, e := range repo.DebugLog() {
+			if len(e.Args) > 0 && e.Args[0] == "cat-file" {
+				n++
+			}
+		}
+		return n
+	}
+
+	read := func(repo *Repository) {
+		f, err := repo.Open("a.txt")
+		require.NoError(t, err)
+		defer f.Close()
+
+		b, err := io.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, "shared\n", string(b))
+	}
+
+	v1, err := NewRepository("v1", r.GitDir, WithBlobCache(c), WithDebugLog(10))
+	require.NoError(t, err)
+	read(v1)
+	assert.Equal(t, 1, catFiles(v1))
+
+	v2, err := NewRepository("HEAD", r.GitDir, WithBlobCache(c), WithDebugLog(10))
+	require.NoError(t, err)
+	read(v2)
+	assert.Equal(t, 0, catFiles(v2), "read from the shared cache")
+
+	binary, err := v2.IsBinary("a.txt")
+	require.NoError(t, err)
+	assert.False(t, binary)
+	assert.Equal(t, 0, catFiles(v2))
+}
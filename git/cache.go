@@ -0,0 +1,110 @@
+package git
+
+import (
+	"container/list"
+	"sync"
+)
+
+// treeCache holds parsed directory listings, keyed by object IDs of the
+// trees, so that a tree appearing in several revisions or at several paths
+// is listed once. When size is positive, least recently used listings are evicted to
+// keep at most size of them. With a budget, listings are also evicted to
+// keep the budget.
+type treeCache struct {
+	mu     sync.Mutex
+	size   int
+	lru    *list.List
+	items  map[string]*list.Element
+	budget *MemoryBudget
+}
+
+type treeCacheItem struct {
+	oid     string
+	entries map[string]*treeEntry // name -> entry
+	bytes   int64                 // estimated
+}
+
+func newTreeCache(size int) *treeCache {
+	return &treeCache{
+		size:  size,
+		lru:   list.New(),
+		items: map[string]*list.Element{},
+	}
+}
+
+// newTreeCache returns a tree cache bounded by the options of repo.
+func (repo *Repository) newTreeCache() *treeCache {
+	c := newTreeCache(repo.treeCacheSize)
+	c.budget = repo.memoryBudget
+	return c
+}
+
+func (c *treeCache) get(oid string) (map[string]*treeEntry, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[oid]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+
+	return el.Value.(*treeCacheItem).entries, true
+}
+
+func (c *treeCache) add(oid string, entries map[string]*treeEntry) {
+	var bytes int64
+	if c.budget != nil {
+		for name := range entries {
+			bytes += treeEntryBytes + int64(len(name))
+		}
+	}
+
+	c.mu.Lock()
+
+	var delta int64
+	if el, ok := c.items[oid]; ok {
+		item := el.Value.(*treeCacheItem)
+		delta = bytes - item.bytes
+		item.entries, item.bytes = entries, bytes
+		c.lru.MoveToFront(el)
+	} else {
+		c.items[oid] = c.lru.PushFront(&treeCacheItem{oid: oid, entries: entries, bytes: bytes})
+		delta = bytes
+
+		for c.size > 0 && c.lru.Len() > c.size {
+			delta -= c.removeOldest()
+		}
+	}
+
+	c.mu.Unlock()
+
+	c.budget.charge(c, treeCacheWeight, delta)
+}
+
+// removeOldest removes the least recently used listing and returns its
+// estimated size.
+func (c *treeCache) removeOldest() int64 {
+	el := c.lru.Back()
+	if el == nil {
+		return 0
+	}
+
+	item := el.Value.(*treeCacheItem)
+	c.lru.Remove(el)
+	delete(c.items, item.oid)
+
+	return item.bytes
+}
+
+func (c *treeCache) evictOldest() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.removeOldest()
+}
@@ -0,0 +1,65 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestTreeCache(t *testing.T) {
+	c := newTreeCache(2)
+
+	c.add("a", map[string]*treeEntry{})
+	c.add("b", map[string]*treeEntry{})
+
+	_, ok := c.get("a")
+	assert.True(t, ok)
+
+	c.add("c", map[string]*treeEntry{})
+
+	_, ok = c.get("b")
+	assert.False(t, ok, "least recently used one is evicted")
+
+	_, ok = c.get("a")
+	assert.True(t, ok)
+	_, ok = c.get("c")
+	assert.True(t, ok)
+
+	var nilCache *treeCache
+	_, ok = nilCache.get("a")
+	assert.False(t, ok)
+}
+
+func TestTreeCache_sharedSubtrees(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("dir/a.txt", "a\n").
+		AddFile("README", "v1\n").
+		Commit("v1").
+		Tag("v1").
+		AddFile("README", "v2\n").
+		Commit("v2").
+		Tag("v2")
+
+	repo, err := NewRepository("v1", r.GitDir, WithDebugLog(20))
+	require.NoError(t, err)
+
+	_, err = repo.ReadDir("dir")
+	require.NoError(t, err)
+	assert.Len(t, repo.DebugLog(), 3, "rev-parse, and ls-tree of the root and dir/")
+
+	repo.Revision = "v2"
+
+	entries, err := repo.ReadDir("dir")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "dir/a.txt", entries[0].(*treeEntry).Path())
+	assert.Len(t, repo.DebugLog(), 3+2, "dir/ is the same tree in v2")
+
+	b, err := vfs.ReadFile(repo, "README")
+	require.NoError(t, err)
+	assert.Equal(t, "v2\n", string(b), "cache is not stale after changing Revision")
+}
@@ -0,0 +1,156 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cacheUsedFile is the file in a clone under a cache directory whose
+// modification time is when the clone was last used.
+const cacheUsedFile = "vcsfs-used"
+
+// errCacheBusy is returned by lockCache when the clone is locked by
+// someone else and waiting is not wanted.
+var errCacheBusy = errors.New("cache entry is in use")
+
+// WithCacheQuota bounds the size of the cache directory of NewFromURL to
+// maxBytes: after cloning or fetching, the least recently used clones are
+// evicted, as PruneCache does.
+func WithCacheQuota(maxBytes int64) Option {
+	return func(repo *Repository) {
+		repo.cacheQuota = maxBytes
+	}
+}
+
+// cacheLockPath is the file locked for the clone at dir. Clones and
+// fetches hold a shared lock on it, and eviction an exclusive one.
+func cacheLockPath(dir string) string {
+	return dir + ".lock"
+}
+
+// touchCache records that the clone at dir is used now.
+func touchCache(dir string) error {
+	name := filepath.Join(dir, cacheUsedFile)
+	now := time.Now()
+	err := os.Chtimes(name, now, now)
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.WriteFile(name, nil, 0666)
+	}
+
+	return err
+}
+
+type cacheEntry struct {
+	dir      string
+	size     int64
+	lastUsed time.Time
+}
+
+// PruneCache evicts clones under cacheDir, made by NewFromURL or Mirrors,
+// least recently used first until their total size is at most maxBytes.
+// Clones being cloned or fetched into, by this process or another, are
+// skipped. A Repository still open on an evicted clone fails, so the
+// quota should leave room for the clones in use.
+func PruneCache(cacheDir string, maxBytes int64) error {
+	entries, err := cacheEntries(cacheDir)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].lastUsed.Before(entries[j].lastUsed) })
+
+	for _, e := range entries {
+		if total <= maxBytes {
+			break
+		}
+
+		err := evictCache(cacheDir, e.dir)
+		if errors.Is(err, errCacheBusy) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		total -= e.size
+	}
+
+	return nil
+}
+
+func cacheEntries(cacheDir string) ([]cacheEntry, error) {
+	dirEntries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []cacheEntry
+	for _, de := range dirEntries {
+		if !de.IsDir() || !strings.HasSuffix(de.Name(), ".git") || strings.HasPrefix(de.Name(), ".") {
+			continue
+		}
+
+		dir := filepath.Join(cacheDir, de.Name())
+		e := cacheEntry{dir: dir}
+
+		if fi, err := os.Stat(filepath.Join(dir, cacheUsedFile)); err == nil {
+			e.lastUsed = fi.ModTime()
+		} else if fi, err := de.Info(); err == nil {
+			e.lastUsed = fi.ModTime()
+		}
+
+		err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				fi, err := d.Info()
+				if err != nil {
+					return err
+				}
+				e.size += fi.Size()
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// evictCache removes the clone at dir unless it is locked. The clone is
+// moved aside under the lock, so that it disappears at once for others.
+func evictCache(cacheDir, dir string) error {
+	unlock, err := lockCache(dir, true, false)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp(cacheDir, ".evict-")
+	if err != nil {
+		unlock()
+		return err
+	}
+
+	err = os.Rename(dir, filepath.Join(tmp, filepath.Base(dir)))
+	unlock()
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.RemoveAll(tmp)
+}
@@ -0,0 +1,76 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestPruneCache(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	var dirs []string
+	for i := range 3 {
+		r := gittest.New(t).AddFile("a", "a").Commit("initial")
+		repo, err := NewFromURL(r.Dir, "main", cacheDir)
+		require.NoError(t, err)
+
+		used := time.Now().Add(time.Duration(i-3) * time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(repo.GitDir, cacheUsedFile), used, used))
+		dirs = append(dirs, repo.GitDir)
+	}
+
+	entries, err := cacheEntries(cacheDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	size := entries[0].size
+
+	// the least recently used one is locked by a fetch in progress
+	unlock, err := lockCache(dirs[0], false, true)
+	require.NoError(t, err)
+
+	require.NoError(t, PruneCache(cacheDir, size*2))
+	assert.DirExists(t, dirs[0])
+	assert.NoDirExists(t, dirs[1])
+	assert.DirExists(t, dirs[2])
+
+	unlock()
+
+	require.NoError(t, PruneCache(cacheDir, size))
+	assert.NoDirExists(t, dirs[0])
+	assert.DirExists(t, dirs[2])
+
+	entries, err = cacheEntries(cacheDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = lockCache(dirs[2], true, false)
+	assert.NoError(t, err)
+	_, err = lockCache(dirs[2], false, false)
+	assert.ErrorIs(t, err, errCacheBusy)
+}
+
+func TestWithCacheQuota(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	a := gittest.New(t).AddFile("a", "a").Commit("initial")
+	repoA, err := NewFromURL(a.Dir, "main", cacheDir, WithCacheQuota(1))
+	require.NoError(t, err)
+	// the clone just made is in use
+	assert.DirExists(t, repoA.GitDir)
+
+	b := gittest.New(t).AddFile("b", "b").Commit("initial")
+	repoB, err := NewFromURL(b.Dir, "main", cacheDir, WithCacheQuota(1))
+	require.NoError(t, err)
+	assert.NoDirExists(t, repoA.GitDir)
+	assert.DirExists(t, repoB.GitDir)
+
+	_, err = repoB.Stat("b")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,46 @@
+//go:build unix
+
+package git
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockCache locks the clone at dir with flock(2), exclusively or shared,
+// waiting for others to unlock it if wait is true and returning
+// errCacheBusy otherwise.
+func lockCache(dir string, exclusive, wait bool) (unlock func(), err error) {
+	f, err := os.OpenFile(cacheLockPath(dir), os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+
+	for {
+		err = syscall.Flock(int(f.Fd()), how)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errCacheBusy
+		}
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
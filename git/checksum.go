@@ -0,0 +1,72 @@
+package git
+
+import (
+	"bufio"
+	"crypto"
+	_ "crypto/md5" // registers the algorithms for ChecksumManifest
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ChecksumManifest writes checksums of the regular files in the tree of
+// the revision to w in the format of sha256sum and its kin (md5sum,
+// sha1sum, sha512sum), so that an extracted tree can be checked with
+// "sha256sum -c". algo is the hash function, such as crypto.SHA256. The
+// contents are hashed as they are read from git. Entries hidden by filters,
+// symbolic links and submodules are omitted.
+func (repo *Repository) ChecksumManifest(w io.Writer, algo crypto.Hash) (err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	defer repo.startSpan("ChecksumManifest", "")(&err)
+
+	if !algo.Available() {
+		return fmt.Errorf("unavailable hash function: %v", algo)
+	}
+
+	entries, err := repo.lsTreeRecursive(false)
+	if err != nil {
+		return err
+	}
+
+	batch, err := repo.startCatFile(repo.context())
+	if err != nil {
+		return err
+	}
+	defer batch.Close()
+
+	bw := bufio.NewWriter(w)
+	h := algo.New()
+
+	for _, e := range entries {
+		if e.objType != objTypeRegular || !repo.exposes(e) {
+			continue
+		}
+
+		h.Reset()
+		if err := batch.read(e.sha1, func(string, int64) io.Writer { return h }); err != nil {
+			return err
+		}
+
+		writeChecksumLine(bw, hex.EncodeToString(h.Sum(nil)), e.Path())
+	}
+
+	return bw.Flush()
+}
+
+// writeChecksumLine writes a line of sha256sum output. As GNU coreutils
+// does, a name with a backslash or a newline is escaped and the line
+// prefixed with a backslash.
+func writeChecksumLine(w io.Writer, sum, name string) {
+	if strings.ContainsAny(name, "\\\n\r") {
+		name = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(name)
+		sum = "\\" + sum
+	}
+
+	fmt.Fprintf(w, "%s  %s\n", sum, name)
+}
@@ -0,0 +1,45 @@
+package git
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestChecksumManifest(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README", "readme\n").
+		AddExecutable("bin/run", "#!/bin/sh\n").
+		AddSymlink("link", "README").
+		AddFile(`back\slash`, "").
+		Commit("first")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	sum := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, repo.ChecksumManifest(&buf, crypto.SHA256))
+	assert.Equal(t, sum("readme\n")+"  README\n"+
+		`\`+sum("")+`  back\\slash`+"\n"+
+		sum("#!/bin/sh\n")+"  bin/run\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, repo.ChecksumManifest(&buf, crypto.MD5))
+	md5sum := md5.Sum([]byte("readme\n"))
+	assert.Contains(t, buf.String(), hex.EncodeToString(md5sum[:])+"  README\n")
+
+	assert.Error(t, repo.ChecksumManifest(&buf, crypto.BLAKE2b_256))
+}
@@ -0,0 +1,44 @@
+package git
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+	"github.com/motemen/go-vcs-fs/vcsfstest"
+)
+
+func TestConformance(t *testing.T) {
+	vcsfstest.Run(t, func(t *testing.T, files []vcsfstest.File) vfs.FileSystem {
+		return newFixtureRepo(t, files)
+	})
+}
+
+func TestConformance_zipSnapshot(t *testing.T) {
+	vcsfstest.Run(t, func(t *testing.T, files []vcsfstest.File) vfs.FileSystem {
+		return newFixtureRepo(t, files, WithZipSnapshot())
+	})
+}
+
+func newFixtureRepo(t *testing.T, files []vcsfstest.File, opts ...Option) *Repository {
+	r := gittest.New(t)
+	for _, f := range files {
+		switch {
+		case f.Mode&os.ModeSymlink != 0:
+			r.AddSymlink(f.Path, f.Content)
+		case f.Mode&0100 != 0:
+			r.AddExecutable(f.Path, f.Content)
+		default:
+			r.AddFile(f.Path, f.Content)
+		}
+	}
+	r.Commit("fixture")
+
+	repo, err := NewRepository("HEAD", r.GitDir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
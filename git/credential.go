@@ -0,0 +1,55 @@
+package git
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// WithSSHCommand sets the command git uses to connect to SSH remotes,
+// e.g. "ssh -i /path/to/key -o IdentitiesOnly=yes".
+func WithSSHCommand(command string) Option {
+	return WithEnv("GIT_SSH_COMMAND=" + command)
+}
+
+// WithAskPass sets the program git runs to ask for credentials,
+// and disables prompting on the terminal.
+func WithAskPass(program string) Option {
+	return WithEnv(
+		"GIT_ASKPASS="+program,
+		"SSH_ASKPASS="+program,
+		"GIT_TERMINAL_PROMPT=0",
+	)
+}
+
+// WithHTTPCredentials makes git authenticate to HTTP(S) remotes under
+// remoteURL with the username and password (or token), e.g.
+// "https://github.com/owner/", "x-access-token" and a token for GitHub.
+// They are sent to URLs matching remoteURL as http.<url>.* settings do
+// only, not to other hosts such as those of submodules or LFS. The
+// credentials are passed through the environment rather than the command
+// line, and prompting on the terminal is disabled.
+func WithHTTPCredentials(remoteURL, username, password string) Option {
+	return func(repo *Repository) {
+		auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		repo.configs = append(repo.configs, [2]string{"http." + remoteURL + ".extraHeader", "Authorization: Basic " + auth})
+		repo.env = append(repo.env, "GIT_TERMINAL_PROMPT=0")
+	}
+}
+
+// configEnv converts configs into environment variables
+// understood by git as if given by "git -c".
+func configEnv(configs [][2]string) []string {
+	if len(configs) == 0 {
+		return nil
+	}
+
+	env := []string{fmt.Sprintf("GIT_CONFIG_COUNT=%d", len(configs))}
+	for i, c := range configs {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, c[0]),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, c[1]),
+		)
+	}
+
+	return env
+}
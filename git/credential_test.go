@@ -0,0 +1,37 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHTTPCredentials(t *testing.T) {
+	repo, err := NewRepository("", newFixture(t).GitDir, WithHTTPCredentials("https://example.com/owner/", "user", "token"))
+	require.NoError(t, err)
+
+	out, err := repo.git("config", "--get-urlmatch", "http.extraHeader", "https://example.com/owner/repo.git")
+	require.NoError(t, err)
+
+	header, err := out.first()
+	require.NoError(t, err)
+	assert.Equal(t, "Authorization: Basic dXNlcjp0b2tlbg==", header)
+
+	for _, url := range []string{"https://example.org/owner/repo.git", "https://example.com/other/repo.git", "http://example.com/owner/repo.git"} {
+		_, err = repo.git("config", "--get-urlmatch", "http.extraHeader", url)
+		assert.True(t, isExitCode(err, 1), "not sent to %s", url)
+	}
+
+	assert.NotContains(t, repo.commandContext(context.Background(), "ls-remote").Args, header)
+}
+
+func TestWithSSHCommand(t *testing.T) {
+	repo, err := NewRepository("", newFixture(t).GitDir, WithSSHCommand("ssh -i key"), WithAskPass("/bin/false"))
+	require.NoError(t, err)
+
+	env := repo.commandContext(context.Background(), "fetch").Env
+	assert.Contains(t, env, "GIT_SSH_COMMAND=ssh -i key")
+	assert.Contains(t, env, "GIT_ASKPASS=/bin/false")
+}
@@ -0,0 +1,79 @@
+package git
+
+import (
+	"sync"
+	"time"
+)
+
+// debugOutputSize is the number of leading bytes of output kept
+// in a DebugEntry.
+const debugOutputSize = 512
+
+// DebugEntry is a git command recorded by WithDebugLog.
+type DebugEntry struct {
+	Args     []string // without the git executable and --git-dir
+	Start    time.Time
+	Duration time.Duration
+	Output   string // trimmed to the first 512 bytes
+	Err      error
+}
+
+// WithDebugLog makes the Repository record the last n git commands it runs,
+// which are retrieved by DebugLog to attach to bug reports.
+func WithDebugLog(n int) Option {
+	return func(repo *Repository) {
+		if n > 0 {
+			repo.debugLog = &debugLog{entries: make([]DebugEntry, 0, n)}
+		}
+	}
+}
+
+// DebugLog returns the commands recorded by WithDebugLog, oldest first.
+// It returns nil if WithDebugLog is not given.
+func (repo *Repository) DebugLog() []DebugEntry {
+	l := repo.debugLog
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append(append([]DebugEntry{}, l.entries[l.next:]...), l.entries[:l.next]...)
+}
+
+// debugLog is a ring buffer of DebugEntry.
+type debugLog struct {
+	mu      sync.Mutex
+	entries []DebugEntry
+	next    int // index to overwrite once entries is full
+}
+
+func (l *debugLog) add(args []string, duration time.Duration, out []byte, err error) {
+	if l == nil {
+		return
+	}
+
+	if len(out) > debugOutputSize {
+		out = out[:debugOutputSize]
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := DebugEntry{
+		Args:     append([]string{}, args...),
+		Start:    time.Now().Add(-duration),
+		Duration: duration,
+		Output:   string(out),
+		Err:      err,
+	}
+
+	if len(l.entries) < cap(l.entries) {
+		l.entries = append(l.entries, e)
+		return
+	}
+
+	l.entries[l.next] = e
+	l.next = (l.next + 1) % len(l.entries)
+}
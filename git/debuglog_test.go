@@ -0,0 +1,45 @@
+package git
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDebugLog(t *testing.T) {
+	gitDir := newFixture(t).GitDir
+
+	repo, err := NewRepository("HEAD", gitDir, WithDebugLog(3))
+	require.NoError(t, err)
+
+	_, err = repo.Stat("nonexistent/file")
+	require.Error(t, err)
+
+	for i := 1; i <= 3; i++ {
+		_, err = repo.RevisionsTouching("", i, 0)
+		require.NoError(t, err)
+	}
+
+	log := repo.DebugLog()
+	require.Len(t, log, 3)
+	for i, e := range log {
+		assert.Equal(t, []string{"rev-list", fmt.Sprintf("--max-count=%d", i+1), "--end-of-options", "HEAD", "--"}, e.Args)
+		assert.NoError(t, e.Err)
+		assert.True(t, len(e.Output) <= debugOutputSize)
+	}
+
+	repo, err = NewRepository("nonexistent-revision", gitDir, WithDebugLog(10))
+	require.NoError(t, err)
+
+	_, err = repo.ReadDir("git")
+	require.Error(t, err)
+
+	log = repo.DebugLog()
+	require.NotEmpty(t, log)
+	assert.Equal(t, []string{"rev-parse", "--verify", "--end-of-options", "nonexistent-revision^{tree}"}, log[len(log)-1].Args)
+	assert.Error(t, log[len(log)-1].Err)
+
+	assert.Nil(t, (&Repository{}).DebugLog())
+}
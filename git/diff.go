@@ -0,0 +1,136 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ChangeType is the kind of a Change, as the status letters of git diff.
+type ChangeType byte
+
+const (
+	ChangeAdded       ChangeType = 'A'
+	ChangeDeleted     ChangeType = 'D'
+	ChangeModified    ChangeType = 'M'
+	ChangeRenamed     ChangeType = 'R'
+	ChangeCopied      ChangeType = 'C'
+	ChangeTypeChanged ChangeType = 'T' // e.g. a file became a symlink
+)
+
+func (t ChangeType) String() string { return string(t) }
+
+// Change is a file changed between two revisions.
+type Change struct {
+	Type       ChangeType
+	Path       string
+	OldPath    string // the source of a rename or copy
+	Similarity int    // of the contents of a rename or copy, in percent
+}
+
+// DiffPatch writes a unified diff from the repository's revision to
+// otherRev, limited to paths if any, with renames and copies detected. An empty
+// otherRev compares against the working tree, as git diff does.
+func (repo *Repository) DiffPatch(w io.Writer, otherRev string, paths ...string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	differ, err := repo.differ(otherRev)
+	if err != nil {
+		return err
+	}
+
+	args := repo.diffArgs([]string{"diff", "--no-color", "--no-ext-diff", "--find-renames", "--find-copies"}, otherRev, paths)
+	return differ.gitTo(w, args...)
+}
+
+// ChangedFiles lists the files changed from the repository's revision to
+// otherRev, limited to paths if any, in the order of git diff. Renames and
+// copies are detected, as git diff -M -C does, and reported as single
+// changes from OldPath to Path, so that a moved file need not be deleted
+// and added again. An empty otherRev compares against the working tree.
+func (repo *Repository) ChangedFiles(otherRev string, paths ...string) ([]Change, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	differ, err := repo.differ(otherRev)
+	if err != nil {
+		return nil, err
+	}
+
+	args := repo.diffArgs([]string{"diff", "--no-ext-diff", "--name-status", "-z", "--find-renames", "--find-copies"}, otherRev, paths)
+
+	out, err := differ.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := out.lines('\x00')
+	if err != nil {
+		return nil, err
+	}
+
+	// example output (NUL shown as "|"):
+	//   M|b.txt|R087|a.txt|moved.txt|
+	changes := []Change{}
+	for i := 0; i < len(tokens); i++ {
+		status := tokens[i]
+		if status == "" {
+			continue
+		}
+		if i+1 >= len(tokens) {
+			return nil, fmt.Errorf("could not parse diff output: %q", out.String())
+		}
+
+		c := Change{Type: ChangeType(status[0])}
+		if c.Type == ChangeRenamed || c.Type == ChangeCopied {
+			if i+2 >= len(tokens) {
+				return nil, fmt.Errorf("could not parse diff output: %q", out.String())
+			}
+			c.Similarity, _ = strconv.Atoi(status[1:])
+			c.OldPath, c.Path = repo.decodePath(tokens[i+1]), repo.decodePath(tokens[i+2])
+			i += 2
+		} else {
+			c.Path = repo.decodePath(tokens[i+1])
+			i++
+		}
+
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+// differ returns the Repository to run git diff comparing to otherRev.
+// Against the working tree, it is given the work tree of GitDir, which git
+// would otherwise take to be the current directory.
+func (repo *Repository) differ(otherRev string) (*Repository, error) {
+	if otherRev != "" || repo.GitDir == "" {
+		return repo, nil
+	}
+
+	dir, err := repo.workTreeDir()
+	if err != nil {
+		return nil, fmt.Errorf("cannot compare with the working tree: %w", err)
+	}
+
+	return repo.withEnv("GIT_WORK_TREE=" + dir), nil
+}
+
+// diffArgs completes the arguments to git diff comparing the revision to
+// otherRev, or the working tree if empty, limited to paths.
+func (repo *Repository) diffArgs(args []string, otherRev string, paths []string) []string {
+	args = append(args, "--end-of-options", repo.revision())
+	if otherRev != "" {
+		args = append(args, otherRev)
+	}
+	args = append(args, "--")
+
+	for _, p := range paths {
+		if spec := repo.pathspec(p); spec != "" {
+			args = append(args, spec)
+		}
+	}
+
+	return args
+}
@@ -0,0 +1,145 @@
+package git
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestDiffPatch(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "one\ntwo\nthree\nfour\nfive\n").
+		AddFile("b.txt", "b\n").
+		Commit("v1").
+		Tag("v1").
+		Remove("a.txt").
+		AddFile("moved.txt", "one\ntwo\nthree\nfour\nfive\n").
+		AddFile("b.txt", "B\n").
+		Commit("v2")
+
+	repo, err := NewRepository("v1", r.GitDir)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, repo.DiffPatch(&buf, "HEAD"))
+	assert.Contains(t, buf.String(), "rename from a.txt\nrename to moved.txt\n")
+	assert.Contains(t, buf.String(), "--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-b\n+B\n")
+
+	buf.Reset()
+	require.NoError(t, repo.DiffPatch(&buf, "HEAD", "b.txt"))
+	assert.NotContains(t, buf.String(), "a.txt")
+	assert.Contains(t, buf.String(), "+B\n")
+
+	buf.Reset()
+	require.NoError(t, repo.DiffPatch(&buf, "v1"))
+	assert.Empty(t, buf.String())
+
+	assert.Error(t, repo.DiffPatch(&buf, "nonexistent"))
+}
+
+func TestDiffPatch_workTree(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "a\n").
+		AddFile("b.txt", "b\n").
+		Commit("first")
+	r.Git("checkout", "--", ".")
+	require.NoError(t, os.WriteFile(filepath.Join(r.Dir, "b.txt"), []byte("B\n"), 0644))
+
+	// the work tree is that of GitDir, not the current directory
+	chdir(t, t.TempDir())
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, repo.DiffPatch(&buf, ""))
+	assert.Contains(t, buf.String(), "--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-b\n+B\n")
+	assert.NotContains(t, buf.String(), "a.txt")
+
+	bare := t.TempDir()
+	require.NoError(t, exec.Command("git", "clone", "-q", "--bare", r.Dir, bare).Run())
+
+	repo, err = NewRepository("HEAD", bare)
+	require.NoError(t, err)
+	assert.Error(t, repo.DiffPatch(&buf, ""), "a bare repository has no working tree")
+}
+
+func TestChangedFiles(t *testing.T) {
+	content := "one\ntwo\nthree\nfour\nfive\n"
+	r := gittest.New(t).
+		AddFile("a.txt", content).
+		AddFile("b.txt", "b\n").
+		AddFile("gone.txt", "gone\n").
+		Commit("v1").
+		Tag("v1").
+		Remove("a.txt").
+		AddFile("moved.txt", content+"six\n").
+		AddFile("b.txt", "B\n").
+		AddFile("b-copy.txt", "b\n").
+		Remove("gone.txt").
+		AddFile("new.txt", "new\n").
+		Commit("v2")
+
+	repo, err := NewRepository("v1", r.GitDir)
+	require.NoError(t, err)
+
+	changes, err := repo.ChangedFiles("HEAD")
+	require.NoError(t, err)
+
+	byPath := map[string]Change{}
+	for _, c := range changes {
+		byPath[c.Path] = c
+	}
+	assert.Len(t, changes, 5)
+
+	moved := byPath["moved.txt"]
+	assert.Equal(t, ChangeRenamed, moved.Type)
+	assert.Equal(t, "a.txt", moved.OldPath)
+	assert.True(t, moved.Similarity > 50 && moved.Similarity < 100, moved.Similarity)
+
+	assert.Equal(t, Change{Type: ChangeCopied, Path: "b-copy.txt", OldPath: "b.txt", Similarity: 100}, byPath["b-copy.txt"])
+	assert.Equal(t, Change{Type: ChangeModified, Path: "b.txt"}, byPath["b.txt"])
+	assert.Equal(t, Change{Type: ChangeDeleted, Path: "gone.txt"}, byPath["gone.txt"])
+	assert.Equal(t, Change{Type: ChangeAdded, Path: "new.txt"}, byPath["new.txt"])
+
+	changes, err = repo.ChangedFiles("HEAD", "new.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Type: ChangeAdded, Path: "new.txt"}}, changes)
+
+	changes, err = repo.ChangedFiles("v1")
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestChangedFiles_workTree(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "a\n").
+		AddFile("b.txt", "b\n").
+		Commit("first")
+	r.Git("checkout", "--", ".")
+	require.NoError(t, os.WriteFile(filepath.Join(r.Dir, "b.txt"), []byte("B\n"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(r.Dir, "a.txt")))
+
+	chdir(t, t.TempDir())
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	changes, err := repo.ChangedFiles("")
+	require.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Type: ChangeDeleted, Path: "a.txt"},
+		{Type: ChangeModified, Path: "b.txt"},
+	}, changes)
+
+	changes, err = repo.ChangedFiles("", "b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Type: ChangeModified, Path: "b.txt"}}, changes)
+}
@@ -0,0 +1,195 @@
+package git
+
+import (
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// entriesPageSize is the number of entries Entries reads at a time.
+const entriesPageSize = 256
+
+// Dir is a directory opened by OpenDir, whose entries are read a part at
+// a time. It keeps serving the tree it was opened at even if the revision
+// of the Repository changes.
+type Dir struct {
+	repo *Repository
+	size int
+	at   func(i int) (os.FileInfo, bool) // the i-th entry and if it is exposed
+	pos  int
+}
+
+// OpenDir opens the directory at path for reading its entries
+// incrementally, in the order of ReadDir, which WithReadDirOrder sets. Entries are only made into
+// os.FileInfo values as they are read, so that a directory of many entries
+// can be consumed without building all of them at once.
+func (repo *Repository) OpenDir(path string) (_ *Dir, err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	defer repo.startSpan("OpenDir", path)(&err)
+
+	repo.autoFetch()
+
+	return repo.openDir(path)
+}
+
+func (repo *Repository) openDir(path string) (*Dir, error) {
+	if repo.useSnapshot {
+		entries, err := repo.snapshotReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		repo.readDirOrder.sortEntries(entries)
+
+		return &Dir{
+			repo: repo,
+			size: len(entries),
+			at:   func(i int) (os.FileInfo, bool) { return entries[i], true },
+		}, nil
+	}
+
+	entryMap, err := repo.lsTree(path)
+	if err != nil {
+		return nil, pathError("readdir", path, err)
+	}
+
+	dir := strings.Trim(path, "/")
+	if dir == "." {
+		dir = ""
+	}
+
+	names := make([]string, 0, len(entryMap))
+	for name := range entryMap {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := names[i], names[j]
+		return repo.readDirOrder.less(a, entryMap[a].IsDir(), b, entryMap[b].IsDir())
+	})
+
+	return &Dir{
+		repo: repo,
+		size: len(names),
+		at: func(i int) (os.FileInfo, bool) {
+			e := entryMap[names[i]].in(repo, dir)
+			return e, repo.exposes(e)
+		},
+	}, nil
+}
+
+// ReadDir returns the next n entries of the directory, like
+// os.File.ReadDir. If n > 0, it returns at most n entries, and io.EOF
+// when there are no more. If n <= 0, it returns all the remaining entries
+// and a nil error.
+func (d *Dir) ReadDir(n int) ([]os.FileInfo, error) {
+	d.repo.mu.Lock()
+	defer d.repo.mu.Unlock()
+
+	return d.read(n)
+}
+
+func (d *Dir) read(n int) ([]os.FileInfo, error) {
+	entries := []os.FileInfo{}
+	for ; d.pos < d.size && (n <= 0 || len(entries) < n); d.pos++ {
+		if e, ok := d.at(d.pos); ok {
+			entries = append(entries, e)
+		}
+	}
+
+	if n > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	}
+
+	return entries, nil
+}
+
+// Entries returns an iterator over the entries of the directory at path,
+// in the order of ReadDir, reading them a page at a time. An error opening
+// the directory is yielded as the only element.
+func (repo *Repository) Entries(path string) iter.Seq2[os.FileInfo, error] {
+	return func(yield func(os.FileInfo, error) bool) {
+		d, err := repo.OpenDir(path)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for {
+			entries, err := d.ReadDir(entriesPageSize)
+			if err == io.EOF {
+				return
+			}
+
+			for _, e := range entries {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// dirFile is what Open returns for a directory. Like an *os.File of
+// a directory, it implements fs.ReadDirFile and fails to be read.
+type dirFile struct {
+	dir  *Dir
+	path string
+	fi   os.FileInfo
+}
+
+var _ fs.ReadDirFile = (*dirFile)(nil)
+
+func (repo *Repository) openDirFile(path string, fi os.FileInfo) (vfs.ReadSeekCloser, error) {
+	d, err := repo.openDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dirFile{dir: d, path: path, fi: fi}, nil
+}
+
+func (f *dirFile) Stat() (fs.FileInfo, error) {
+	return f.fi, nil
+}
+
+func (f *dirFile) Read([]byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: f.path, Err: syscall.EISDIR}
+}
+
+// Seek only supports rewinding to the first entry for ReadDir.
+func (f *dirFile) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, &os.PathError{Op: "seek", Path: f.path, Err: syscall.EINVAL}
+	}
+
+	f.dir.repo.mu.Lock()
+	f.dir.pos = 0
+	f.dir.repo.mu.Unlock()
+
+	return 0, nil
+}
+
+func (f *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries, err := f.dir.ReadDir(n)
+	if err != nil {
+		return nil, err
+	}
+
+	dirEntries := make([]fs.DirEntry, len(entries))
+	for i, fi := range entries {
+		dirEntries[i] = fs.FileInfoToDirEntry(fi)
+	}
+
+	return dirEntries, nil
+}
+
+func (f *dirFile) Close() error {
+	return nil
+}
@@ -0,0 +1,141 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestOpenDir(t *testing.T) {
+	r := gittest.New(t)
+	for i := 0; i < 10; i++ {
+		r.AddFile(fmt.Sprintf("dir/%02d.txt", i), "x\n")
+	}
+	r.AddFile("dir/skip.md", "x\n").Commit("initial")
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithExclude("*.md"))
+	require.NoError(t, err)
+
+	all, err := repo.ReadDir("dir")
+	require.NoError(t, err)
+	require.Len(t, all, 10)
+
+	d, err := repo.OpenDir("dir")
+	require.NoError(t, err)
+
+	var names []string
+	for {
+		entries, err := d.ReadDir(3)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(entries), 3)
+
+		for _, fi := range entries {
+			names = append(names, fi.Name())
+		}
+	}
+
+	expected := make([]string, len(all))
+	for i, fi := range all {
+		expected[i] = fi.Name()
+	}
+	assert.Equal(t, expected, names)
+
+	// all the rest
+	d, err = repo.OpenDir("dir")
+	require.NoError(t, err)
+	_, err = d.ReadDir(4)
+	require.NoError(t, err)
+	rest, err := d.ReadDir(0)
+	require.NoError(t, err)
+	assert.Len(t, rest, 6)
+	rest, err = d.ReadDir(0)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+
+	_, err = repo.OpenDir("nonexistent")
+	assert.True(t, errors.Is(err, os.ErrNotExist), "%v", err)
+}
+
+func TestEntries(t *testing.T) {
+	repo := Repository{GitDir: newFixture(t).GitDir}
+
+	all, err := repo.ReadDir("git")
+	require.NoError(t, err)
+
+	i := 0
+	for fi, err := range repo.Entries("git") {
+		require.NoError(t, err)
+		assert.Equal(t, all[i].Name(), fi.Name())
+		i++
+	}
+	assert.Equal(t, len(all), i)
+
+	for fi := range repo.Entries("git") {
+		assert.Equal(t, all[0].Name(), fi.Name())
+		break
+	}
+
+	for _, err := range repo.Entries("nonexistent") {
+		assert.True(t, errors.Is(err, os.ErrNotExist), "%v", err)
+	}
+}
+
+func TestOpen_dir(t *testing.T) {
+	repo := Repository{GitDir: newFixture(t).GitDir}
+
+	all, err := repo.ReadDir("git")
+	require.NoError(t, err)
+
+	f, err := repo.Open("git")
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Read(make([]byte, 1))
+	assert.True(t, errors.Is(err, syscall.EISDIR), "%v", err)
+
+	d, ok := f.(fs.ReadDirFile)
+	require.True(t, ok)
+
+	fi, err := d.Stat()
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+	assert.Equal(t, "git", fi.Name())
+
+	entries, err := d.ReadDir(2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, all[0].Name(), entries[0].Name())
+
+	rest, err := d.ReadDir(-1)
+	require.NoError(t, err)
+	assert.Len(t, rest, len(all)-2)
+
+	_, err = d.ReadDir(1)
+	assert.Equal(t, io.EOF, err)
+
+	// rewinds
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	entries, err = d.ReadDir(1)
+	require.NoError(t, err)
+	assert.Equal(t, all[0].Name(), entries[0].Name())
+
+	_, err = f.Seek(1, io.SeekCurrent)
+	assert.Error(t, err)
+
+	root, err := repo.Open("")
+	require.NoError(t, err)
+	root.Close()
+}
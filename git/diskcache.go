@@ -0,0 +1,185 @@
+package git
+
+import (
+	"container/list"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// DiskCache stores contents of blobs in a directory by object ID, so that
+// they are served from files rather than git, even across restarts of the
+// process. As blobs are immutable, stored contents never go stale. It is
+// safe for concurrent use, also by several processes sharing the
+// directory.
+type DiskCache struct {
+	dir string
+
+	// MinOpens is the number of times a blob is opened in the process
+	// before it is stored, so that only frequently opened ones take disk.
+	// Zero stores every blob opened. Opens are counted for the
+	// diskCacheCounted blobs opened most recently only.
+	MinOpens int
+
+	mu     sync.Mutex
+	lru    *list.List
+	counts map[string]*list.Element // by object ID, of those not stored yet
+}
+
+// diskCacheCounted is how many blobs not stored yet a DiskCache counts the
+// opens of, so that the counts do not grow with every blob ever opened.
+const diskCacheCounted = 10000
+
+type diskCacheCount struct {
+	oid   string
+	opens int
+}
+
+// NewDiskCache returns a DiskCache storing contents under dir, which is
+// created if missing.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+
+	return &DiskCache{dir: dir, lru: list.New(), counts: map[string]*list.Element{}}, nil
+}
+
+// WithDiskCache makes Open serve blobs stored in c, and store blobs opened
+// often enough in c. The blob cache given by WithBlobCache, if any, is
+// consulted first. With WithVerifyContent, stored contents are hashed when
+// opened, and those not matching are removed and read from git again.
+func WithDiskCache(c *DiskCache) Option {
+	return func(repo *Repository) {
+		repo.diskCache = c
+	}
+}
+
+func (c *DiskCache) path(oid string) string {
+	return filepath.Join(c.dir, oid[:2], oid[2:])
+}
+
+// open opens the stored content of oid.
+func (c *DiskCache) open(oid string) (*os.File, bool) {
+	if c == nil || len(oid) < 3 {
+		return nil, false
+	}
+
+	f, err := os.Open(c.path(oid))
+	if err != nil {
+		return nil, false
+	}
+
+	return f, true
+}
+
+// opened counts an open of oid, and reports whether its content is to be
+// stored now.
+func (c *DiskCache) opened(oid string) bool {
+	if c == nil || len(oid) < 3 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.counts[oid]
+	if ok {
+		c.lru.MoveToFront(el)
+	} else {
+		el = c.lru.PushFront(&diskCacheCount{oid: oid})
+		c.counts[oid] = el
+	}
+
+	count := el.Value.(*diskCacheCount)
+	count.opens++
+	if count.opens >= c.MinOpens {
+		c.lru.Remove(el)
+		delete(c.counts, oid)
+		return true
+	}
+
+	for c.lru.Len() > diskCacheCounted {
+		back := c.lru.Back()
+		c.lru.Remove(back)
+		delete(c.counts, back.Value.(*diskCacheCount).oid)
+	}
+
+	return false
+}
+
+// store writes content of oid. The file is written aside and renamed, so
+// that readers never see a partial content.
+func (c *DiskCache) store(oid string, content []byte) error {
+	name := c.path(oid)
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(name), ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
+
+// readVerified reads f, the stored content of oid, and returns it if it
+// hashes to oid. A file not matching is removed, to be stored again.
+func (c *DiskCache) readVerified(f *os.File, oid string) ([]byte, error) {
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyBlob(oid, content); err != nil {
+		os.Remove(c.path(oid))
+		return nil, err
+	}
+
+	return content, nil
+}
+
+func (repo *Repository) openDiskBlob(f *os.File) (vfs.ReadSeekCloser, error) {
+	if repo.observer != nil {
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		repo.observer.FileOpened(fi.Size())
+	}
+
+	return &diskBlob{File: f, observer: repo.observer}, nil
+}
+
+// diskBlob is a blob served from a DiskCache.
+type diskBlob struct {
+	*os.File
+	observer Observer
+	closed   bool
+}
+
+func (b *diskBlob) Close() error {
+	if !b.closed && b.observer != nil {
+		b.observer.FileClosed()
+	}
+	b.closed = true
+	return b.File.Close()
+}
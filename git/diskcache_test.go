@@ -0,0 +1,103 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestDiskCache(t *testing.T) {
+	r := gittest.New(t).AddFile("a", "content of a\n").Commit("initial")
+	oid := r.Git("rev-parse", "HEAD:a")
+
+	cache, err := NewDiskCache(filepath.Join(t.TempDir(), "blobs"))
+	require.NoError(t, err)
+	cache.MinOpens = 2
+
+	read := func(repo *Repository) string {
+		f, err := repo.Open("a")
+		require.NoError(t, err)
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithDiskCache(cache))
+	require.NoError(t, err)
+
+	assert.Equal(t, "content of a\n", read(repo))
+	assert.NoFileExists(t, cache.path(oid), "opened once")
+	assert.Equal(t, "content of a\n", read(repo))
+	assert.FileExists(t, cache.path(oid))
+
+	// a process started later, with git unable to read blobs
+	realGit, err := exec.LookPath("git")
+	require.NoError(t, err)
+	gitPath := filepath.Join(t.TempDir(), "git")
+	script := fmt.Sprintf(`#!/bin/sh
+case "$*" in
+*"cat-file blob"*) exit 1 ;;
+esac
+exec %q "$@"
+`, realGit)
+	require.NoError(t, os.WriteFile(gitPath, []byte(script), 0755))
+
+	cache, err = NewDiskCache(cache.dir)
+	require.NoError(t, err)
+	repo, err = NewRepository("HEAD", r.GitDir, WithDiskCache(cache), WithGitPath(gitPath))
+	require.NoError(t, err)
+
+	assert.Equal(t, "content of a\n", read(repo))
+}
+
+func TestDiskCache_verify(t *testing.T) {
+	r := gittest.New(t).AddFile("a", "content of a\n").Commit("initial")
+	oid := r.Git("rev-parse", "HEAD:a")
+
+	cache, err := NewDiskCache(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, cache.store(oid, []byte("tampered\n")))
+
+	read := func(repo *Repository) string {
+		b, err := vfs.ReadFile(repo, "a")
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithDiskCache(cache))
+	require.NoError(t, err)
+	assert.Equal(t, "tampered\n", read(repo), "trusted without verification")
+
+	repo, err = NewRepository("HEAD", r.GitDir, WithDiskCache(cache), WithVerifyContent())
+	require.NoError(t, err)
+	assert.Equal(t, "content of a\n", read(repo))
+
+	b, err := os.ReadFile(cache.path(oid))
+	require.NoError(t, err)
+	assert.Equal(t, "content of a\n", string(b), "stored again from git")
+	assert.Equal(t, "content of a\n", read(repo))
+}
+
+func TestDiskCache_countsBounded(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+	require.NoError(t, err)
+	cache.MinOpens = 2
+
+	for i := 0; i < diskCacheCounted+100; i++ {
+		assert.False(t, cache.opened(fmt.Sprintf("%040x", i)))
+	}
+	assert.Len(t, cache.counts, diskCacheCounted)
+
+	assert.True(t, cache.opened(fmt.Sprintf("%040x", diskCacheCounted+99)), "recently counted")
+	assert.False(t, cache.opened(fmt.Sprintf("%040x", 0)), "forgotten")
+}
@@ -0,0 +1,180 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Entry is an entry of a directory given to the callback of EachEntry.
+type Entry struct {
+	Name     string
+	Mode     os.FileMode // as Mode of the os.FileInfo of the entry
+	ObjectID string
+}
+
+func (e Entry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+// errStopEntries is returned by the writer parsing ls-tree output once
+// the callback of EachEntry fails, to stop git.
+var errStopEntries = errors.New("stopped listing entries")
+
+// EachEntry calls fn for each entry of the directory at dir in the order
+// of git, parsing the output of git ls-tree as it comes, without building
+// the listing or caching it. It is for walking huge trees with little
+// memory: descend with EachEntry again, which fn may call. Returning an
+// error from fn stops the listing and EachEntry returns it. Sizes are not
+// read; Stat the entries needing them.
+func (repo *Repository) EachEntry(dir string, fn func(Entry) error) (err error) {
+	repo.mu.Lock()
+
+	repo.autoFetch()
+
+	if repo.useSnapshot {
+		entries, err := repo.snapshotReadDir(dir)
+		repo.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		return eachFileInfo(entries, fn)
+	}
+
+	// git is run by a clone so that fn may use repo meanwhile
+	root, err := repo.rootTree()
+	c := repo.clone()
+	repo.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	defer c.startSpan("EachEntry", dir)(&err)
+
+	dir = strings.Trim(dir, "/")
+	if dir == "." {
+		dir = ""
+	}
+
+	object := root
+	if dir != "" {
+		object += ":" + c.encodePath(dir)
+	}
+
+	filtered := c.sparseCheckout || len(c.includes) > 0 || len(c.excludes) > 0
+
+	var fnErr error
+	w := &recordWriter{fn: func(rec []byte) error {
+		if len(rec) == 0 {
+			return nil
+		}
+
+		e, ok := c.parseEntry(rec)
+		if !ok {
+			c.debug("could not parse line", "line", string(rec))
+			return nil
+		}
+		if filtered && !c.exposesPath(path.Join(dir, e.Name), e.IsDir()) {
+			return nil
+		}
+
+		if fnErr = fn(e); fnErr != nil {
+			return errStopEntries
+		}
+		return nil
+	}}
+
+	err = c.gitTo(w, "ls-tree", "--full-tree", "-z", "--end-of-options", object)
+	if fnErr != nil {
+		return fnErr
+	}
+	if err == nil {
+		err = w.flush()
+	}
+	if errors.Is(err, ErrUnknownRevision) {
+		return &os.PathError{Op: "readdir", Path: dir, Err: os.ErrNotExist}
+	}
+
+	return err
+}
+
+// parseEntry parses a record of ls-tree output, "<mode> <type> <oid>\t<name>".
+func (repo *Repository) parseEntry(rec []byte) (Entry, bool) {
+	sp := bytes.IndexByte(rec, ' ')
+	tab := bytes.IndexByte(rec, '\t')
+	if sp != 6 || tab < sp {
+		return Entry{}, false
+	}
+
+	oidStart := bytes.IndexByte(rec[sp+1:tab], ' ')
+	if oidStart < 0 {
+		return Entry{}, false
+	}
+
+	objType, err1 := strconv.ParseUint(string(rec[0:3]), 8, 16)
+	mode, err2 := strconv.ParseUint(string(rec[3:6]), 8, 16)
+	if err1 != nil || err2 != nil {
+		return Entry{}, false
+	}
+
+	te := treeEntry{objType: uint16(objType), mode: uint16(mode)}
+
+	return Entry{
+		Name:     repo.decodePath(string(rec[tab+1:])),
+		Mode:     te.Mode(),
+		ObjectID: string(rec[sp+1+oidStart+1 : tab]),
+	}, true
+}
+
+func eachFileInfo(entries []os.FileInfo, fn func(Entry) error) error {
+	for _, fi := range entries {
+		oid, _ := ObjectID(fi)
+		if err := fn(Entry{Name: fi.Name(), Mode: fi.Mode(), ObjectID: oid}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordWriter calls fn with each NUL-terminated record written to it,
+// reusing its buffer across records.
+type recordWriter struct {
+	buf []byte
+	fn  func(rec []byte) error
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	start := 0
+	for {
+		i := bytes.IndexByte(w.buf[start:], 0)
+		if i < 0 {
+			break
+		}
+
+		if err := w.fn(w.buf[start : start+i]); err != nil {
+			return 0, err
+		}
+		start += i + 1
+	}
+
+	w.buf = w.buf[:copy(w.buf, w.buf[start:])]
+
+	return len(p), nil
+}
+
+// flush calls fn with the record left unterminated, if any.
+func (w *recordWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	rec := w.buf
+	w.buf = nil
+	return w.fn(rec)
+}
@@ -0,0 +1,75 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestEachEntry(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "a").
+		AddExecutable("bin/run", "#!/bin/sh\n").
+		AddSymlink("link", "a.txt").
+		AddFile("vendor/x/x.go", "package x\n").
+		Commit("initial")
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithExclude("vendor/"))
+	require.NoError(t, err)
+
+	var paths []string
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		return repo.EachEntry(dir, func(e Entry) error {
+			name := path.Join(dir, e.Name)
+			paths = append(paths, name)
+
+			assert.Equal(t, r.Git("rev-parse", "HEAD:"+name), e.ObjectID)
+			fi, err := repo.Lstat(name)
+			require.NoError(t, err)
+			assert.Equal(t, fi.Mode(), e.Mode, name)
+
+			if e.IsDir() {
+				return walk(name)
+			}
+			return nil
+		})
+	}
+	require.NoError(t, walk(""))
+	assert.Equal(t, []string{"a.txt", "bin", "bin/run", "link"}, paths)
+
+	stop := errors.New("stop")
+	n := 0
+	err = repo.EachEntry("/", func(Entry) error {
+		n++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, n)
+
+	err = repo.EachEntry("nonexistent", func(Entry) error { return nil })
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRecordWriter(t *testing.T) {
+	var recs []string
+	w := &recordWriter{fn: func(rec []byte) error {
+		recs = append(recs, string(rec))
+		return nil
+	}}
+
+	for _, p := range []string{"a\x00b", "c\x00\x00d", "e"} {
+		n, err := w.Write([]byte(p))
+		require.NoError(t, err)
+		assert.Equal(t, len(p), n)
+	}
+	require.NoError(t, w.flush())
+
+	assert.Equal(t, []string{"a", "bc", "", "de"}, recs)
+}
@@ -0,0 +1,90 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// PathEncoding converts names in trees from and to UTF-8. Encodings of
+// golang.org/x/text can be adapted with their decoders and encoders:
+//
+//	type shiftJIS struct{}
+//
+//	func (shiftJIS) Decode(s string) (string, error) { return japanese.ShiftJIS.NewDecoder().String(s) }
+//	func (shiftJIS) Encode(s string) (string, error) { return japanese.ShiftJIS.NewEncoder().String(s) }
+type PathEncoding interface {
+	Decode(name string) (string, error) // to UTF-8
+	Encode(name string) (string, error) // from UTF-8
+}
+
+// Latin1 is the ISO 8859-1 PathEncoding.
+var Latin1 PathEncoding = latin1{}
+
+type latin1 struct{}
+
+func (latin1) Decode(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		b.WriteRune(rune(s[i]))
+	}
+	return b.String(), nil
+}
+
+func (latin1) Encode(s string) (string, error) {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			return "", fmt.Errorf("not representable in Latin-1: %q", r)
+		}
+		b = append(b, byte(r))
+	}
+	return string(b), nil
+}
+
+// WithPathEncoding makes the Repository regard names in trees as encoded
+// in enc, for repositories made with legacy encodings: names are converted
+// to UTF-8 in what the Repository returns, and paths given to it are
+// converted back to look them up.
+func WithPathEncoding(enc PathEncoding) Option {
+	return func(repo *Repository) {
+		repo.pathEncoding = enc
+	}
+}
+
+// decodePath converts a path in a tree to UTF-8.
+func (repo *Repository) decodePath(p string) string {
+	if repo.pathEncoding == nil || isASCII(p) {
+		return p
+	}
+
+	decoded, err := repo.pathEncoding.Decode(p)
+	if err != nil {
+		repo.debug("could not decode path", "path", p, "error", err)
+		return p
+	}
+	return decoded
+}
+
+// encodePath converts a path in UTF-8 to one in trees.
+func (repo *Repository) encodePath(p string) string {
+	if repo.pathEncoding == nil || isASCII(p) {
+		return p
+	}
+
+	encoded, err := repo.pathEncoding.Encode(p)
+	if err != nil {
+		repo.debug("could not encode path", "path", p, "error", err)
+		return p
+	}
+	return encoded
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
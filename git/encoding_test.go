@@ -0,0 +1,63 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestWithPathEncoding(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("caf\xe9/men\xfa.txt", "latin-1\n").
+		AddFile("plain.txt", "").
+		Commit("first")
+
+	for _, opts := range [][]Option{nil, {WithZipSnapshot()}} {
+		repo, err := NewRepository("HEAD", r.GitDir, append(opts, WithPathEncoding(Latin1))...)
+		require.NoError(t, err)
+
+		entries, err := repo.ReadDir("")
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "café", entries[0].Name())
+
+		entries, err = repo.ReadDir("café")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "menú.txt", entries[0].Name())
+		assert.True(t, gittest.CommitTime(1).Equal(entries[0].ModTime()))
+
+		content, err := vfs.ReadFile(repo, "café/menú.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "latin-1\n", string(content))
+
+		ok, err := repo.Exists("café/menú.txt")
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithPathEncoding(Latin1))
+	require.NoError(t, err)
+
+	history, err := repo.History("café/menú.txt", HistoryOptions{})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "café/menú.txt", history[0].Path)
+}
+
+func TestLatin1(t *testing.T) {
+	s, err := Latin1.Decode("caf\xe9")
+	require.NoError(t, err)
+	assert.Equal(t, "café", s)
+
+	s, err = Latin1.Encode("café")
+	require.NoError(t, err)
+	assert.Equal(t, "caf\xe9", s)
+
+	_, err = Latin1.Encode("日本")
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Errors a *GitError matches with errors.Is, by what git reported.
+var (
+	ErrUnknownRevision = errors.New("unknown revision")
+	ErrMissingObject   = errors.New("missing object")
+	ErrNotRepository   = errors.New("not a git repository")
+)
+
+// gitErrorStderrSize is the number of leading bytes of stderr kept in
+// a GitError.
+const gitErrorStderrSize = 1024
+
+// GitError is returned when a git command exits with a non-zero status.
+// It unwraps to the *exec.ExitError, and matches one of ErrUnknownRevision,
+// ErrMissingObject or ErrNotRepository with errors.Is if the failure is
+// recognized as such.
+type GitError struct {
+	Args     []string // arguments to git, without --git-dir
+	ExitCode int
+	Stderr   string // possibly truncated
+	Err      error
+
+	kind error
+}
+
+func newGitError(cmd *exec.Cmd, err *exec.ExitError, stderr string) *GitError {
+	if len(stderr) > gitErrorStderrSize {
+		stderr = stderr[:gitErrorStderrSize]
+	}
+
+	return &GitError{
+		Args:     commandArgs(cmd),
+		ExitCode: err.ExitCode(),
+		Stderr:   stderr,
+		Err:      err,
+		kind:     classifyStderr(stderr),
+	}
+}
+
+func (e *GitError) Error() string {
+	name := "git"
+	if len(e.Args) > 0 {
+		name += " " + e.Args[0]
+	}
+
+	msg := strings.TrimSpace(e.Stderr)
+	if msg == "" {
+		return fmt.Sprintf("%s: %v", name, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %v: %s", name, e.Err, msg)
+}
+
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+func (e *GitError) Is(target error) bool {
+	return e.kind != nil && target == e.kind
+}
+
+// stderrPatterns are the messages of git telling the kind of failure,
+// matched case-insensitively.
+var stderrPatterns = []struct {
+	substr string
+	kind   error
+}{
+	{"not a git repository", ErrNotRepository},
+	{"bad object", ErrMissingObject},
+	{"bad file", ErrMissingObject},
+	{"missing object", ErrMissingObject},
+	{"unable to read", ErrMissingObject},
+	{"needed a single revision", ErrUnknownRevision},
+	{"not a valid object name", ErrUnknownRevision},
+	{"bad revision", ErrUnknownRevision},
+	{"unknown revision", ErrUnknownRevision},
+	{"invalid reference", ErrUnknownRevision},
+	{"couldn't find remote ref", ErrUnknownRevision},
+	{"log for", ErrUnknownRevision}, // "log for 'stash' only has 2 entries"
+}
+
+func classifyStderr(stderr string) error {
+	stderr = strings.ToLower(stderr)
+	for _, p := range stderrPatterns {
+		if strings.Contains(stderr, p.substr) {
+			return p.kind
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package git
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestGitError(t *testing.T) {
+	gitDir := newFixture(t).GitDir
+
+	_, err := (&Repository{GitDir: gitDir, Revision: "nonexistent-revision"}).Stat("git")
+
+	var gitErr *GitError
+	require.True(t, errors.As(err, &gitErr), "%v", err)
+	assert.Equal(t, []string{"rev-parse", "--verify", "--end-of-options", "nonexistent-revision^{tree}"}, gitErr.Args)
+	assert.Equal(t, 128, gitErr.ExitCode)
+	assert.Contains(t, gitErr.Stderr, "fatal:")
+	assert.Contains(t, err.Error(), "git rev-parse: exit status 128: fatal:")
+
+	var exitErr *exec.ExitError
+	assert.True(t, errors.As(err, &exitErr))
+
+	assert.True(t, errors.Is(err, ErrUnknownRevision))
+	assert.False(t, errors.Is(err, ErrMissingObject))
+
+	_, err = (&Repository{GitDir: gitDir, Revision: "nonexistent-revision"}).History("", HistoryOptions{})
+	assert.True(t, errors.Is(err, ErrUnknownRevision), "%v", err)
+
+	_, err = (&Repository{GitDir: gitDir, Revision: "0123456789012345678901234567890123456789"}).RevisionsTouching("", 1, 0)
+	assert.True(t, errors.Is(err, ErrMissingObject), "%v", err)
+
+	_, err = (&Repository{GitDir: t.TempDir()}).Stat("git")
+	assert.True(t, errors.Is(err, ErrNotRepository), "%v", err)
+}
+
+func TestGitError_localized(t *testing.T) {
+	// git ships German messages
+	t.Setenv("LANG", "C.UTF-8")
+	t.Setenv("LANGUAGE", "de")
+
+	r := gittest.New(t).AddFile("README", "readme\n").Commit("first")
+
+	_, err := (&Repository{GitDir: r.GitDir, Revision: "nonexistent-revision"}).Stat("README")
+	assert.True(t, errors.Is(err, ErrUnknownRevision), "%v", err)
+
+	ok, err := (&Repository{GitDir: r.GitDir}).Exists("nonexistent/file")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestGitError_truncated(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "exit 3")
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr))
+
+	stderr := make([]byte, gitErrorStderrSize*2)
+	for i := range stderr {
+		stderr[i] = 'x'
+	}
+
+	gitErr := newGitError(cmd, exitErr, string(stderr))
+	assert.Equal(t, 3, gitErr.ExitCode)
+	assert.Len(t, gitErr.Stderr, gitErrorStderrSize)
+	assert.False(t, errors.Is(gitErr, ErrUnknownRevision))
+}
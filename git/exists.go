@@ -0,0 +1,83 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+)
+
+// Exists reports whether path exists in the filesystem. Unless the parent
+// directory is already cached, it is answered by a single git cat-file call
+// instead of listing the parent directory.
+func (repo *Repository) Exists(name string) (_ bool, err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	name = path.Clean(strings.Trim(name, "/"))
+	if name == "." {
+		return true, nil
+	}
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return false, nil
+	}
+
+	if repo.useSnapshot {
+		_, err := repo.snapshotLstat(name)
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	dir, filename := splitPath(name)
+	entries, ok := repo.cachedTree(dir)
+	repo.observeCache("tree", ok)
+	if ok {
+		e, ok := entries[filename]
+		return ok && repo.exposes(e.in(repo, dir)), nil
+	}
+
+	treeish := repo.revision()
+	if strings.Contains(treeish, ":") {
+		// would be taken as a part of the path
+		if treeish, err = repo.rootTree(); err != nil {
+			return false, err
+		}
+	}
+
+	// a path after the colon is relative to the current directory only if
+	// it starts with "./" or "../", which a cleaned path does not
+	out, err := repo.git("cat-file", "-t", "--end-of-options", treeish+":"+repo.encodePath(name))
+	if err != nil {
+		if isPathNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	objType, err := out.first()
+	if err != nil {
+		return false, err
+	}
+
+	return repo.exposesPath(name, objType == "tree"), nil
+}
+
+// pathError converts err from listing a directory for the operation on
+// name into an *os.PathError wrapping os.ErrNotExist if the directory does
+// not exist, so that callers can tell missing paths from failures of git.
+func pathError(op, name string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+
+	return err
+}
+
+// isPathNotExist reports whether err is git complaining that a <rev>:<path>
+// object name does not resolve because the path is missing.
+func isPathNotExist(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "does not exist in") || strings.Contains(msg, "exists on disk, but not in")
+}
@@ -0,0 +1,65 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExists(t *testing.T) {
+	repo := Repository{GitDir: newFixture(t).GitDir}
+
+	for _, name := range []string{"", "git", "git/git.go", "README.md"} {
+		ok, err := repo.Exists(name)
+		require.NoError(t, err)
+		assert.True(t, ok, name)
+	}
+
+	ok, err := repo.Exists("git/nonexistent.go")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// answered from the cache after a listing
+	_, err = repo.ReadDir("git")
+	require.NoError(t, err)
+
+	ok, err = repo.Exists("git/git.go")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.Exists("git/nonexistent.go")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = (&Repository{GitDir: repo.GitDir, Revision: "nonexistent-revision"}).Exists("git")
+	assert.Error(t, err)
+}
+
+func TestExists_filtered(t *testing.T) {
+	repo, err := NewRepository("HEAD", newFixture(t).GitDir, WithExclude("*.md"))
+	require.NoError(t, err)
+
+	ok, err := repo.Exists("README.md")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestStat_notExist(t *testing.T) {
+	repo := Repository{GitDir: newFixture(t).GitDir}
+
+	for _, name := range []string{"git/nonexistent.go", "nonexistent/file"} {
+		_, err := repo.Stat(name)
+		assert.True(t, errors.Is(err, os.ErrNotExist), "%s: %v", name, err)
+	}
+
+	_, err := repo.ReadDir("nonexistent")
+	assert.True(t, errors.Is(err, os.ErrNotExist), "%v", err)
+
+	// not existing revisions are errors of other kinds
+	_, err = (&Repository{GitDir: repo.GitDir, Revision: "nonexistent-revision"}).Stat("git/git.go")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, os.ErrNotExist))
+}
@@ -0,0 +1,105 @@
+package git
+
+import (
+	"path"
+	"strings"
+)
+
+// WithInclude limits the files exposed through the filesystem to those
+// matching any of globs. Directories are always listed. See WithExclude
+// for the syntax of globs.
+func WithInclude(globs ...string) Option {
+	return func(repo *Repository) {
+		for _, g := range globs {
+			repo.includes = append(repo.includes, newGlobPattern(g))
+		}
+	}
+}
+
+// WithExclude hides entries matching any of globs, along with everything
+// under excluded directories, from all listing and open operations.
+//
+// Globs are in the syntax of path.Match. A glob without slashes is matched
+// against the base name at any depth (e.g. "*.key"), otherwise against
+// the whole path from the root (e.g. "config/*.pem"). A trailing slash
+// makes the glob match directories only (e.g. "secrets/").
+func WithExclude(globs ...string) Option {
+	return func(repo *Repository) {
+		for _, g := range globs {
+			repo.excludes = append(repo.excludes, newGlobPattern(g))
+		}
+	}
+}
+
+type globPattern struct {
+	pattern  string
+	dirOnly  bool
+	anchored bool
+}
+
+func newGlobPattern(glob string) globPattern {
+	g := globPattern{}
+	if strings.HasSuffix(glob, "/") {
+		g.dirOnly = true
+	}
+
+	g.pattern = strings.Trim(glob, "/")
+	g.anchored = strings.Contains(g.pattern, "/")
+
+	return g
+}
+
+func (g globPattern) match(name string, isDir bool) bool {
+	if g.dirOnly && !isDir {
+		return false
+	}
+
+	if !g.anchored {
+		name = path.Base(name)
+	}
+
+	ok, _ := path.Match(g.pattern, name)
+	return ok
+}
+
+// matchAny reports whether name or any of its leading directories
+// matches one of patterns.
+func matchAny(patterns []globPattern, name string, isDir bool) bool {
+	for {
+		for _, g := range patterns {
+			if g.match(name, isDir) {
+				return true
+			}
+		}
+
+		name, isDir = path.Dir(name), true
+		if name == "." || name == "/" {
+			return false
+		}
+	}
+}
+
+// exposes reports whether e is visible through the filesystem.
+func (repo *Repository) exposes(e *treeEntry) bool {
+	return repo.exposesPath(e.Path(), e.IsDir())
+}
+
+func (repo *Repository) exposesPath(name string, isDir bool) bool {
+	if name == "" {
+		return true
+	}
+
+	if !repo.inSparseCone(name, isDir) {
+		return false
+	}
+
+	if matchAny(repo.excludes, name, isDir) {
+		return false
+	}
+
+	if len(repo.includes) > 0 && !isDir && !matchAny(repo.includes, name, isDir) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,67 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithExclude(t *testing.T) {
+	repo, err := NewRepository("HEAD", newFixture(t).GitDir, WithExclude("*_test.go", "LICENSE"))
+	require.NoError(t, err)
+
+	files, err := repo.ReadDir("git")
+	require.NoError(t, err)
+	for _, fi := range files {
+		assert.NotRegexp(t, `_test\.go$`, fi.Name())
+	}
+
+	_, err = repo.Open("git/git_test.go")
+	assert.Error(t, err)
+
+	_, err = repo.Stat("LICENSE")
+	assert.Error(t, err)
+
+	_, err = repo.Open("git/git.go")
+	assert.NoError(t, err)
+}
+
+func TestWithInclude(t *testing.T) {
+	repo, err := NewRepository("HEAD", newFixture(t).GitDir, WithInclude("*.md"))
+	require.NoError(t, err)
+
+	_, err = repo.Stat("README.md")
+	assert.NoError(t, err)
+
+	_, err = repo.Stat("git")
+	assert.NoError(t, err)
+
+	_, err = repo.Stat("git/git.go")
+	assert.Error(t, err)
+}
+
+func TestExposesPath(t *testing.T) {
+	repo := Repository{}
+	WithExclude("secrets/", "*.key", "config/*.pem")(&repo)
+
+	tests := []struct {
+		name    string
+		isDir   bool
+		exposed bool
+	}{
+		{"secrets", true, false},
+		{"secrets/token", false, false},
+		{"a/secrets/token", false, false},
+		{"secrets", false, true},
+		{"server.key", false, false},
+		{"tls/server.key", false, false},
+		{"config/a.pem", false, false},
+		{"b/config/a.pem", false, true},
+		{"main.go", false, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.exposed, repo.exposesPath(test.name, test.isDir), test.name)
+	}
+}
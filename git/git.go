@@ -2,47 +2,189 @@ package git
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/tools/godoc/vfs"
 )
 
+// Repository is a filesystem of a revision of a git repository. It is safe
+// for concurrent use; operations are serialized.
 type Repository struct {
-	GitDir   string
-	Revision string
-
-	treeCache map[string]map[string]*treeEntry // dir -> path -> entry
+	GitDir string
+	// Revision is the revision served. Use SetRevision to change it once the
+	// Repository is in use.
+	Revision    string
+	ModTimeMode ModTimeMode
+
+	mu sync.Mutex // held throughout each operation
+
+	ctx            context.Context
+	commandTimeout time.Duration
+	gitPath        string
+	env            []string
+	isolatedEnv    bool
+	configs        [][2]string // key, value
+	indexFile      string
+	workTree       string
+
+	autoFetchInterval time.Duration
+	tracking          string
+	lastFetch         time.Time
+	cacheQuota        int64
+
+	treeCache     *treeCache // keyed by tree object ID
+	treeCacheSize int
+	memoryBudget  *MemoryBudget
+	resolved      map[string]string // by arguments to rev-parse
+	commitTime    *time.Time
+
+	sparseDirs     []string
+	sparseCheckout bool
+	includes       []globPattern
+	excludes       []globPattern
+	noMailmap      bool
+	pathEncoding   PathEncoding
+
+	recurseSubmodules bool
+	untracked         bool
+	symlinkPolicy     SymlinkPolicy
+
+	retries      int // negative if disabled
+	retryBackoff time.Duration
+
+	maxFileSize   int64
+	verifyContent bool
+	blobCache     *BlobCache
+	diskCache     *DiskCache
+	useSnapshot   bool
+	snapshot      *treeSnapshot
+
+	readDirOrder ReadDirOrder
+	progress     func(Progress) error
+
+	observer Observer
+	logger   *slog.Logger
+	debugLog *debugLog
+	tracer   trace.Tracer
+	spanCtx  context.Context // of the operation being traced
 }
 
-func NewRepository(revision, gitDir string) (*Repository, error) {
+// ModTimeMode specifies how ModTime of entries are computed.
+type ModTimeMode int
+
+const (
+	// ModTimeLastCommit reports the committer date of the last commit which
+	// touched the entry (default), which costs one git call per entry.
+	ModTimeLastCommit ModTimeMode = iota
+	// ModTimeCommitterDate reports the committer date of the pinned commit
+	// for every entry, which costs one git call in total.
+	ModTimeCommitterDate
+)
+
+func NewRepository(revision, gitDir string, opts ...Option) (*Repository, error) {
 	if revision == "" {
 		revision = "HEAD"
 	}
 
-	if gitDir == "" {
-		out, err := git("rev-parse", "--git-dir")
+	repo := &Repository{
+		Revision: revision,
+		GitDir:   gitDir,
+	}
+
+	for _, opt := range opts {
+		opt(repo)
+	}
+
+	if repo.autoFetchInterval > 0 {
+		repo.tracking = revision
+		repo.lastFetch = time.Now()
+	}
+
+	if repo.GitDir == "" {
+		out, err := repo.git("rev-parse", "--git-dir")
 		if err != nil {
 			return nil, err
 		}
 
-		gitDir, err = out.first()
+		repo.GitDir, err = out.first()
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if repo.sparseCheckout {
+		if err := repo.loadSparseCheckout(); err != nil {
+			return nil, err
+		}
+	}
+
+	return repo, nil
+}
+
+// clone returns a Repository with the configuration of repo, pinned to the
+// same revision, and with state such as caches of its own except for the
+// blob cache, which is shared. The caller must hold repo.mu unless repo
+// is not in use yet.
+func (repo *Repository) clone() *Repository {
 	return &Repository{
-		Revision: revision,
-		GitDir:   gitDir,
-	}, nil
+		GitDir:            repo.GitDir,
+		Revision:          repo.Revision,
+		ModTimeMode:       repo.ModTimeMode,
+		ctx:               repo.ctx,
+		commandTimeout:    repo.commandTimeout,
+		retries:           repo.retries,
+		retryBackoff:      repo.retryBackoff,
+		gitPath:           repo.gitPath,
+		env:               repo.env,
+		isolatedEnv:       repo.isolatedEnv,
+		indexFile:         repo.indexFile,
+		workTree:          repo.workTree,
+		configs:           repo.configs,
+		autoFetchInterval: repo.autoFetchInterval,
+		tracking:          repo.tracking,
+		cacheQuota:        repo.cacheQuota,
+		treeCacheSize:     repo.treeCacheSize,
+		memoryBudget:      repo.memoryBudget,
+		sparseDirs:        repo.sparseDirs,
+		includes:          repo.includes,
+		excludes:          repo.excludes,
+		noMailmap:         repo.noMailmap,
+		pathEncoding:      repo.pathEncoding,
+		recurseSubmodules: repo.recurseSubmodules,
+		untracked:         repo.untracked,
+		symlinkPolicy:     repo.symlinkPolicy,
+		maxFileSize:       repo.maxFileSize,
+		verifyContent:     repo.verifyContent,
+		blobCache:         repo.blobCache,
+		diskCache:         repo.diskCache,
+		useSnapshot:       repo.useSnapshot,
+		readDirOrder:      repo.readDirOrder,
+		progress:          repo.progress,
+		observer:          repo.observer,
+		logger:            repo.logger,
+		debugLog:          repo.debugLog,
+		tracer:            repo.tracer,
+	}
+}
+
+// withEnv returns a clone of repo running git with env, a list of
+// "KEY=value", added to its environment.
+func (repo *Repository) withEnv(env ...string) *Repository {
+	c := repo.clone()
+	c.env = append(append([]string{}, repo.env...), env...)
+	return c
 }
 
 // implements os.FileInfo
@@ -52,7 +194,9 @@ type treeEntry struct {
 	objType uint16
 	mode    uint16
 	sha1    string
-	size    int64 // only meaningful if objectType == "blob"
+	size    int64          // only meaningful if objectType == "blob"
+	sizes   *lazySizes     // resolves size if listed without it
+	link    *readlinkCache // caches what readlink returns for a symlink
 	repo    *Repository
 }
 
@@ -68,24 +212,101 @@ func (e treeEntry) IsDir() bool {
 }
 
 func (e treeEntry) ModTime() time.Time {
-	dateOutput, _ := e.repo.git("log", "-1", "--pretty=format:%aD")
-	date, _ := dateOutput.first()
-	lastMod, _ := time.Parse(time.RFC1123Z, date)
-	return lastMod
+	e.repo.mu.Lock()
+	defer e.repo.mu.Unlock()
+
+	return e.modTime()
+}
+
+// modTime is ModTime for callers holding the lock of the Repository.
+func (e treeEntry) modTime() time.Time {
+	if e.repo.ModTimeMode == ModTimeCommitterDate {
+		t, err := e.repo.committerTime()
+		if err != nil {
+			e.repo.debug("could not get committer date", "path", e.Path(), "error", err)
+		}
+		return t
+	}
+
+	t, err := e.repo.lastCommitTime(e.Path())
+	if err != nil {
+		e.repo.debug("could not get date of last commit", "path", e.Path(), "error", err)
+	}
+	return t
 }
 
 func (e treeEntry) Mode() os.FileMode {
+	switch e.objType {
+	case objTypeDir:
+		return os.ModeDir | 0755
+	case objTypeSymlink:
+		return os.ModeSymlink | 0777
+	case objTypeGitlink:
+		return os.ModeIrregular
+	}
+
 	return os.FileMode(e.mode)
 }
 
 func (e treeEntry) Name() string     { return e.name }
-func (e treeEntry) Size() int64      { return e.size }
 func (e treeEntry) Sys() interface{} { return nil }
 
+func (e treeEntry) Size() int64 {
+	if e.sizes == nil {
+		return e.size
+	}
+
+	e.repo.mu.Lock()
+	defer e.repo.mu.Unlock()
+
+	return e.blobSize()
+}
+
+// blobSize is Size for callers holding the lock of the Repository.
+func (e treeEntry) blobSize() int64 {
+	if e.sizes != nil {
+		return e.sizes.get(e.repo, e.sha1)
+	}
+	return e.size
+}
+
 func (e treeEntry) Path() string {
 	return path.Join(e.parent, e.name)
 }
 
+// in returns a copy of e, an entry listed by lsTree, located in dir of
+// repo. Listings are shared by views of a repository, see At.
+func (e *treeEntry) in(repo *Repository, dir string) *treeEntry {
+	c := *e
+	c.parent = dir
+	c.repo = repo
+	return &c
+}
+
+// ObjectID returns the ID of the git object of the entry.
+func (e treeEntry) ObjectID() string {
+	return e.sha1
+}
+
+// ObjectIDer is implemented by os.FileInfo of entries backed by git
+// objects, such as those returned by Repository.
+type ObjectIDer interface {
+	ObjectID() string
+}
+
+// ObjectID returns the ID of the git object fi describes, which identifies
+// its content: a blob for files and symbolic links, a tree for directories
+// and a commit for submodules. ok is false if fi is not backed by a git
+// object.
+func ObjectID(fi os.FileInfo) (oid string, ok bool) {
+	o, ok := fi.(ObjectIDer)
+	if !ok || o.ObjectID() == "" {
+		return "", false
+	}
+
+	return o.ObjectID(), true
+}
+
 type output struct {
 	*bytes.Buffer
 }
@@ -104,28 +325,118 @@ func (o output) lines(ch byte) ([]string, error) {
 }
 
 func (repo *Repository) git(args ...string) (*output, error) {
-	gitArgs := args
+	return repo.gitInput(nil, args...)
+}
+
+func (repo *Repository) commandContext(ctx context.Context, args ...string) *exec.Cmd {
+	gitPath := repo.gitPath
+	if gitPath == "" {
+		gitPath = "git"
+	}
+
+	// keeps reads from taking locks such as index.lock which would contend
+	// with git commands of the user on a live repository, and messages in
+	// English, which errors are classified by
+	env := []string{"GIT_OPTIONAL_LOCKS=0", "LC_ALL=C", "LANGUAGE=C"}
+	if repo.indexFile != "" {
+		env = append(env, "GIT_INDEX_FILE="+repo.indexFile)
+	}
+	env = append(env, repo.env...)
+	env = append(env, configEnv(repo.configs)...)
+
+	cmd := exec.CommandContext(ctx, gitPath, repo.gitArgs(args)...)
+	cmd.WaitDelay = commandWaitDelay
+	if repo.isolatedEnv {
+		cmd.Env = append(isolatedEnv(), env...)
+	} else {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	return cmd
+}
+
+func (repo *Repository) context() context.Context {
+	if repo.spanCtx != nil {
+		return repo.spanCtx
+	}
+	if repo.ctx != nil {
+		return repo.ctx
+	}
+
+	return context.Background()
+}
+
+func (repo *Repository) gitArgs(args []string) []string {
 	if repo.GitDir != "" {
-		gitArgs = append([]string{"--git-dir=" + repo.GitDir}, args...)
+		return append([]string{"--git-dir=" + repo.GitDir}, args...)
+	}
+
+	return args
+}
+
+func (repo *Repository) gitInput(stdin io.Reader, args ...string) (*output, error) {
+	return repo.retry(stdin, func() (*output, error) {
+		return repo.gitOnce(stdin, args...)
+	})
+}
+
+func (repo *Repository) gitOnce(stdin io.Reader, args ...string) (*output, error) {
+	ctx, cancel := repo.commandCtx()
+	defer cancel()
+
+	cmd := repo.commandContext(ctx, args...)
+	cmd.Stdin = stdin
+
+	out, err := repo.run(cmd)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("%w: %v", ctx.Err(), err)
 	}
 
-	return git(gitArgs...)
+	return out, err
 }
 
-func git(args ...string) (*output, error) {
+// gitTo runs git writing its output to w as it comes, for outputs too
+// large to buffer.
+func (repo *Repository) gitTo(w io.Writer, args ...string) error {
+	ctx, cancel := repo.commandCtx()
+	defer cancel()
+
+	cmd := repo.commandContext(ctx, args...)
+	cmd.Stdout = w
 	stderr := new(bytes.Buffer)
-	cmd := exec.Command("git", args...)
 	cmd.Stderr = stderr
+
+	start := time.Now()
+	endSpan := repo.startExecSpan(cmd)
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		err = newGitError(cmd, exitErr, stderr.String())
+	}
+	endSpan(err)
+	repo.observeExec(cmd, start, nil, err)
+
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: %v", ctx.Err(), err)
+	}
+
+	return err
+}
+
+func (repo *Repository) run(cmd *exec.Cmd) (*output, error) {
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	start := time.Now()
+	endSpan := repo.startExecSpan(cmd)
 	out, err := cmd.Output()
-	if err != nil {
-		if _, ok := err.(*exec.ExitError); ok {
-			return nil, fmt.Errorf("%s: %q", err, stderr.String())
-		} else {
-			return nil, err
-		}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		err = newGitError(cmd, exitErr, stderr.String())
 	}
+	endSpan(err)
+	repo.observeExec(cmd, start, out, err)
 
-	return &output{bytes.NewBuffer(out)}, nil
+	// output is also returned for commands exiting with a status meaningful
+	// to the caller
+	return &output{bytes.NewBuffer(out)}, err
 }
 
 func (repo *Repository) revision() string {
@@ -136,7 +447,66 @@ func (repo *Repository) revision() string {
 	return "HEAD"
 }
 
-var rxLsTreeLine = regexp.MustCompile(`^(?P<mode>[0-7]{6}) +(?P<type>\S+) +(?P<sha1>[0-9a-f]{40}) +(?P<size>\d+|-)\t(?P<name>.+)$`)
+// pathspec converts a path relative to the repository root into a pathspec
+// which does not depend on the working directory.
+func (repo *Repository) pathspec(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" || path == "." {
+		return ""
+	}
+
+	return ":(top)" + repo.encodePath(path)
+}
+
+func (repo *Repository) committerTime() (time.Time, error) {
+	repo.observeCache("commit-time", repo.commitTime != nil)
+	if repo.commitTime != nil {
+		return *repo.commitTime, nil
+	}
+
+	t, err := repo.logTime(repo.revision())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	repo.commitTime = &t
+
+	return t, nil
+}
+
+// lastCommitTime returns the committer date of the last commit up to the
+// revision which touched path.
+func (repo *Repository) lastCommitTime(path string) (time.Time, error) {
+	args := []string{repo.revision(), "--"}
+	if spec := repo.pathspec(path); spec != "" {
+		args = append(args, spec)
+	}
+
+	return repo.logTime(args...)
+}
+
+// logTime returns the committer date of the first commit git log lists
+// with args.
+func (repo *Repository) logTime(args ...string) (time.Time, error) {
+	out, err := repo.git(append([]string{"log", "-1", "--format=%ct", "--end-of-options"}, args...)...)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s := strings.TrimSpace(out.String())
+	if s == "" {
+		return time.Time{}, fmt.Errorf("no commit found: %s", strings.Join(args, " "))
+	}
+
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse commit date: %w", err)
+	}
+
+	return time.Unix(sec, 0), nil
+}
+
+var rxLsTreeLine = regexp.MustCompile(`^(?P<mode>[0-7]{6}) +(?P<type>\S+) +(?P<sha1>[0-9a-f]{40})(?: +(?P<size>\d+|-))?\t(?P<name>.+)$`)
 
 // example output:
 //   040000 tree d564d0bc3dd917926892c55e3706cc116d5b165e    directory
@@ -144,68 +514,228 @@ var rxLsTreeLine = regexp.MustCompile(`^(?P<mode>[0-7]{6}) +(?P<type>\S+) +(?P<s
 //   100644 blob 78981922613b2afb6025042ff6bd878ac1994e85    file
 //   160000 commit 5499f342043544dcc4c437c0eb10b4d721f30dd3  submodule
 //   120000 blob 8d14cbf983b3fad683171c9418998d9f68340823    symlink
+//
+// The entries returned are shared by every path the tree appears at, so
+// their parents are not set; see treeEntry.in.
 func (repo *Repository) lsTree(path string) (map[string]*treeEntry, error) {
-	path = strings.TrimRight(path, "/")
+	path = strings.Trim(path, "/")
 	if path == "." {
 		path = ""
 	}
 
+	oid, err := repo.treeID(path)
+	if err != nil {
+		return nil, err
+	}
+
 	if repo.treeCache == nil {
-		repo.treeCache = map[string]map[string]*treeEntry{}
+		repo.treeCache = repo.newTreeCache()
 	}
 
-	if cached, ok := repo.treeCache[path]; ok {
+	cached, ok := repo.treeCache.get(oid)
+	repo.observeCache("tree", ok)
+	if ok {
 		return cached, nil
 	}
 
-	out, err := repo.git("ls-tree", "--full-tree", "-z", "-l", repo.revision()+":"+path)
+	// sizes are resolved lazily, as listing them costs git reading every
+	// blob header while many consumers never look at them
+	out, err := repo.git("ls-tree", "--full-tree", "-z", oid)
 	if err != nil {
 		return nil, err
 	}
 
-	tree := map[string]*treeEntry{}
-
 	lines, err := out.lines('\x00')
 	if err != nil {
 		return nil, err
 	}
 
+	entries := []*treeEntry{}
 	for _, line := range lines {
 		if line == "" {
 			continue
 		}
 
-		parts := rxLsTreeLine.FindStringSubmatch(line)
-		if parts == nil {
-			return nil, fmt.Errorf("could not parse line: %q", line)
+		e, err := repo.parseLsTreeLine("", line)
+		if err != nil {
+			return nil, err
 		}
 
-		var size int64
-		modeStr, _, sha1, sizeStr, name := parts[1], parts[2], parts[3], parts[4], parts[5]
-		if sizeStr != "-" {
-			size, _ = strconv.ParseInt(sizeStr, 10, 64)
+		entries = append(entries, e)
+	}
+
+	tree := repo.newListing(entries)
+	repo.treeCache.add(oid, tree)
+
+	return tree, nil
+}
+
+// newListing indexes entries of a tree by name, arranging for their sizes
+// to be resolved together.
+func (repo *Repository) newListing(entries []*treeEntry) map[string]*treeEntry {
+	tree := make(map[string]*treeEntry, len(entries))
+	sizes := &lazySizes{}
+
+	for _, e := range entries {
+		e.repo = repo
+		if e.objType == objTypeRegular || e.objType == objTypeSymlink {
+			e.sizes = sizes
+			sizes.oids = append(sizes.oids, e.sha1)
 		}
+		if e.objType == objTypeSymlink {
+			e.link = &readlinkCache{}
+		}
+		tree[e.name] = e
+	}
 
-		objType, _ := strconv.ParseUint(modeStr[0:3], 8, 16)
-		mode, _ := strconv.ParseUint(modeStr[3:6], 8, 16)
+	return tree
+}
 
-		tree[name] = &treeEntry{
-			parent:  path,
-			name:    name,
-			size:    size,
-			objType: uint16(objType),
-			mode:    uint16(mode),
-			sha1:    sha1,
-			repo:    repo,
+// treeID returns the object ID of the tree at path, listing its ancestors
+// to find it.
+func (repo *Repository) treeID(path string) (string, error) {
+	if path == "" {
+		return repo.rootTree()
+	}
+
+	dir, name := splitPath(path)
+	entries, err := repo.lsTree(dir)
+	if err != nil {
+		return "", err
+	}
+
+	e, ok := entries[name]
+	if !ok {
+		return "", &os.PathError{Op: "ls-tree", Path: path, Err: os.ErrNotExist}
+	}
+	if e.objType != objTypeDir {
+		return "", fmt.Errorf("not a directory: %s", path)
+	}
+
+	return e.sha1, nil
+}
+
+// cachedTree returns the listing of the tree at path if it can be found
+// from the caches only, without running git.
+func (repo *Repository) cachedTree(path string) (map[string]*treeEntry, bool) {
+	oid, ok := repo.resolvedObject(repo.revision(), "tree")
+	if !ok {
+		return nil, false
+	}
+
+	if path != "" {
+		for _, name := range strings.Split(path, "/") {
+			entries, ok := repo.treeCache.get(oid)
+			if !ok {
+				return nil, false
+			}
+
+			e, ok := entries[name]
+			if !ok || e.objType != objTypeDir {
+				return nil, false
+			}
+			oid = e.sha1
 		}
 	}
 
-	repo.treeCache[path] = tree
+	return repo.treeCache.get(oid)
+}
 
-	return tree, nil
+// rootTree returns the object ID of the tree of the revision, which is
+// resolved once for each revision the Repository is pinned to.
+func (repo *Repository) rootTree() (string, error) {
+	return repo.resolveObject(repo.revision(), "tree")
+}
+
+// splitPath splits a cleaned path into its directory, "" for the root, and
+// the last element.
+func splitPath(name string) (dir, file string) {
+	dir, file = path.Split(name)
+	return strings.TrimSuffix(dir, "/"), file
+}
+
+// lsTreeRecursive lists all the blobs, symlinks and submodules in the
+// tree of the revision, with their full paths. Directories are also listed
+// if trees is true.
+func (repo *Repository) lsTreeRecursive(trees bool) ([]*treeEntry, error) {
+	args := []string{"ls-tree", "--full-tree", "-r", "-z", "-l"}
+	if trees {
+		args = append(args, "-t")
+	}
+
+	out, err := repo.git(append(args, "--end-of-options", repo.revision())...)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := out.lines('\x00')
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*treeEntry{}
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		e, err := repo.parseLsTreeLine("", line)
+		if err != nil {
+			return nil, err
+		}
+
+		e.parent, e.name = path.Split(e.name)
+		e.parent = strings.TrimSuffix(e.parent, "/")
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// parseLsTreeLine parses a line of git ls-tree output listing dir, with or
+// without -l.
+func (repo *Repository) parseLsTreeLine(dir, line string) (*treeEntry, error) {
+	parts := rxLsTreeLine.FindStringSubmatch(line)
+	if parts == nil {
+		return nil, fmt.Errorf("could not parse line: %q", line)
+	}
+
+	var size int64
+	modeStr, _, sha1, sizeStr, name := parts[1], parts[2], parts[3], parts[4], repo.decodePath(parts[5])
+	if sizeStr != "" && sizeStr != "-" {
+		var err error
+		size, err = strconv.ParseInt(sizeStr, 10, 64)
+		if err != nil {
+			repo.debug("could not parse size", "line", line, "error", err)
+		}
+	}
+
+	objType, _ := strconv.ParseUint(modeStr[0:3], 8, 16)
+	mode, _ := strconv.ParseUint(modeStr[3:6], 8, 16)
+
+	return &treeEntry{
+		parent:  dir,
+		name:    name,
+		size:    size,
+		objType: uint16(objType),
+		mode:    uint16(mode),
+		sha1:    sha1,
+		repo:    repo,
+	}, nil
 }
 
-func (repo *Repository) Lstat(path string) (os.FileInfo, error) {
+func (repo *Repository) Lstat(path string) (_ os.FileInfo, err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	defer repo.startSpan("Lstat", path)(&err)
+
+	repo.autoFetch()
+
+	if repo.useSnapshot {
+		return repo.snapshotStat(path)
+	}
+
 	e, err := repo.lstat(path)
 	if err != nil {
 		return nil, err
@@ -213,8 +743,20 @@ func (repo *Repository) Lstat(path string) (os.FileInfo, error) {
 	return e, nil
 }
 
-// TODO: follow symlinks
-func (repo *Repository) Stat(path string) (os.FileInfo, error) {
+// Stat describes the entry at path, following symbolic links as set by
+// WithSymlinkPolicy. By default links are not followed, as by Lstat.
+func (repo *Repository) Stat(path string) (_ os.FileInfo, err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	defer repo.startSpan("Stat", path)(&err)
+
+	repo.autoFetch()
+
+	if repo.useSnapshot {
+		return repo.snapshotStat(path)
+	}
+
 	e, err := repo.stat(path)
 	if err != nil {
 		return nil, err
@@ -223,13 +765,10 @@ func (repo *Repository) Stat(path string) (os.FileInfo, error) {
 }
 
 func (repo *Repository) lstat(name string) (*treeEntry, error) {
-	if name == "." || name == "" {
-		treeRevOutput, err := repo.git("rev-parse", repo.revision()+"^{tree}")
-		if err != nil {
-			return nil, err
-		}
+	name = strings.Trim(name, "/")
 
-		sha1, err := treeRevOutput.first()
+	if name == "." || name == "" {
+		sha1, err := repo.rootTree()
 		if err != nil {
 			return nil, err
 		}
@@ -241,70 +780,161 @@ func (repo *Repository) lstat(name string) (*treeEntry, error) {
 		}, nil
 	}
 
-	name = strings.TrimRight(name, "/")
-
-	dir, filename := path.Split(name)
+	dir, filename := splitPath(name)
 	entries, err := repo.lsTree(dir)
 	if err != nil {
-		return nil, err
+		return nil, pathError("lstat", name, err)
 	}
 
 	if e, ok := entries[filename]; ok {
-		return e, nil
+		if e := e.in(repo, dir); repo.exposes(e) {
+			return e, nil
+		}
 	}
 
-	return nil, fmt.Errorf("file not found: %s", name)
+	return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
 }
 
 func (repo *Repository) stat(path string) (*treeEntry, error) {
-	return repo.lstat(path)
+	if repo.symlinkPolicy == SymlinkNoFollow {
+		return repo.lstat(path)
+	}
+
+	return repo.follow(path)
 }
 
 func (repo *Repository) String() string {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
 	return fmt.Sprintf("git[rev=%s]", repo.revision())
 }
 
-type byName []os.FileInfo
+// RootType implements vfs.FileSystem. A repository is neither GOROOT nor
+// GOPATH by itself.
+func (repo *Repository) RootType(path string) vfs.RootType {
+	return ""
+}
+
+func (repo *Repository) ReadDir(path string) (_ []os.FileInfo, err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
 
-func (x byName) Len() int           { return len(x) }
-func (x byName) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
-func (x byName) Less(i, j int) bool { return x[i].Name() < x[j].Name() }
+	defer repo.startSpan("ReadDir", path)(&err)
 
-func (repo *Repository) ReadDir(path string) ([]os.FileInfo, error) {
-	entryMap, err := repo.lsTree(path)
+	repo.autoFetch()
+
+	d, err := repo.openDir(path)
 	if err != nil {
 		return nil, err
 	}
 
-	entries := []os.FileInfo{}
-	for _, e := range entryMap {
-		entries = append(entries, e)
+	return d.read(-1)
+}
+
+// Readlink returns the target of the symbolic link at path.
+func (repo *Repository) Readlink(path string) (string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.useSnapshot {
+		return repo.snapshotReadlink(path)
 	}
 
-	sort.Sort(byName(entries))
+	fi, err := repo.lstat(path)
+	if err != nil {
+		return "", err
+	}
+	if fi.objType != objTypeSymlink {
+		return "", fmt.Errorf("not a symlink: %s", path)
+	}
 
-	return entries, nil
+	return repo.readlink(fi)
 }
 
 type blob struct {
 	*bytes.Reader
+	observer Observer
+	closed   bool
+}
+
+func (b *blob) Close() error {
+	if !b.closed && b.observer != nil {
+		b.observer.FileClosed()
+	}
+	b.closed = true
+	return nil
 }
 
-func (b blob) Close() error { return nil }
+func (repo *Repository) Open(path string) (_ vfs.ReadSeekCloser, err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	defer repo.startSpan("Open", path)(&err)
+
+	repo.autoFetch()
+
+	if repo.useSnapshot {
+		return repo.snapshotOpen(path)
+	}
 
-func (repo *Repository) Open(path string) (vfs.ReadSeekCloser, error) {
 	fi, err := repo.stat(path)
 	if err != nil {
 		return nil, err
 	}
+	if fi.IsDir() {
+		return repo.openDirFile(path, fi)
+	}
 	if fi.objType != objTypeRegular {
 		return nil, fmt.Errorf("not a regular blob")
 	}
+	if repo.maxFileSize > 0 && fi.blobSize() > repo.maxFileSize {
+		return nil, &FileTooLargeError{Path: path, Size: fi.blobSize(), Limit: repo.maxFileSize}
+	}
 
-	out, err := repo.git("cat-file", "blob", fi.sha1)
-	if err != nil {
-		return nil, err
+	content, ok := repo.blobCache.get(fi.sha1)
+	if repo.blobCache != nil {
+		repo.observeCache("blob", ok)
+	}
+	fromDisk := false
+	if !ok && repo.diskCache != nil {
+		f, stored := repo.diskCache.open(fi.sha1)
+		repo.observeCache("disk", stored)
+		if stored && !repo.verifyContent {
+			return repo.openDiskBlob(f)
+		}
+		if stored {
+			content, err = repo.diskCache.readVerified(f, fi.sha1)
+			if err != nil {
+				repo.debug("discarding stored blob", "oid", fi.sha1, "error", err)
+			}
+			ok, fromDisk = err == nil, err == nil
+		}
+	}
+	if !ok {
+		out, err := repo.git("cat-file", "blob", fi.sha1)
+		if err != nil {
+			return nil, err
+		}
+
+		content = out.Bytes()
+		if repo.verifyContent {
+			if err := verifyBlob(fi.sha1, content); err != nil {
+				return nil, &os.PathError{Op: "open", Path: path, Err: err}
+			}
+		}
+		repo.blobCache.add(fi.sha1, content)
+	}
+
+	if !fromDisk && repo.diskCache.opened(fi.sha1) {
+		if err := repo.diskCache.store(fi.sha1, content); err != nil {
+			repo.debug("storing blob failed", "oid", fi.sha1, "error", err)
+		}
+	}
+
+	if repo.observer != nil {
+		repo.observer.FileOpened(int64(len(content)))
 	}
 
-	return blob{bytes.NewReader(out.Bytes())}, nil
+	return &blob{Reader: bytes.NewReader(content), observer: repo.observer}, nil
 }
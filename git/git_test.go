@@ -1,49 +1,278 @@
 package git
 
 import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
 )
 
 var _ = vfs.FileSystem((*Repository)(nil))
 
 func TestStat_dir(t *testing.T) {
-	repo := Repository{}
+	repo := Repository{GitDir: newFixture(t).GitDir}
 
 	fi, err := repo.Stat("git")
 	require.NoError(t, err)
 
 	assert.True(t, fi.IsDir())
+	assert.True(t, fi.Mode().IsDir())
 	assert.Equal(t, "git", fi.Name())
 }
 
 func TestStat_file(t *testing.T) {
-	repo := Repository{}
+	repo := Repository{GitDir: newFixture(t).GitDir}
 
 	fi, err := repo.Stat("git/git.go")
 	require.NoError(t, err)
 
 	assert.False(t, fi.IsDir())
+	assert.True(t, fi.Mode().IsRegular())
 	assert.Equal(t, "git.go", fi.Name())
 
 	assert.Equal(t, "git", fi.(*treeEntry).parent)
 }
 
+func TestStat_absolute(t *testing.T) {
+	repo := Repository{GitDir: newFixture(t).GitDir}
+
+	fi, err := repo.Stat("/git/git.go")
+	require.NoError(t, err)
+	assert.Equal(t, "git.go", fi.Name())
+
+	fi, err = repo.Stat("/")
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	files, err := repo.ReadDir("/")
+	require.NoError(t, err)
+	assert.NotEmpty(t, files)
+}
+
 func TestReadDir(t *testing.T) {
-	repo := Repository{}
+	repo := Repository{GitDir: newFixture(t).GitDir}
 
 	files, err := repo.ReadDir("git")
 	require.NoError(t, err)
+	assert.Len(t, files, 4)
 
-	assert.Len(t, files, 2)
+	names := []string{}
+	for _, fi := range files {
+		names = append(names, fi.Name())
+	}
+	assert.Equal(t, []string{"attr.go", "git.go", "git_test.go", "history.go"}, names)
 }
 
 func TestOpen(t *testing.T) {
-	repo := Repository{}
+	repo := Repository{GitDir: newFixture(t).GitDir}
 
 	_, err := repo.Open("git/git.go")
 	require.NoError(t, err)
 }
+
+func TestModTime_committerDate(t *testing.T) {
+	repo := Repository{GitDir: newFixture(t).GitDir, ModTimeMode: ModTimeCommitterDate}
+
+	out, err := repo.git("log", "-1", "--format=%ct", "HEAD")
+	require.NoError(t, err)
+	ct, err := out.first()
+	require.NoError(t, err)
+
+	root, err := repo.Stat(".")
+	require.NoError(t, err)
+	fi, err := repo.Stat("git/git.go")
+	require.NoError(t, err)
+
+	assert.Equal(t, ct, strconv.FormatInt(root.ModTime().Unix(), 10))
+	assert.Equal(t, root.ModTime(), fi.ModTime())
+}
+
+// newFixture builds a repository laid out like a small Go module, for tests
+// which need some tree but not a particular history.
+func newFixture(t testing.TB) *gittest.Repo {
+	r := gittest.New(t).
+		AddFile(".gitignore", "*.so\n*.rlib\nCargo.lock\n").
+		AddFile("LICENSE", "MIT License\n").
+		AddFile("README.md", "# project\n").
+		AddFile("git/attr.go", "package git\n").
+		AddFile("git/git.go", "package git\n\n// Repository is a git repository.\ntype Repository struct{}\n").
+		AddFile("git/git_test.go", "package git\n").
+		AddFile("git/history.go", "package git\n").
+		AddFile("cmd/tool/main.go", "package main\n\nfunc main() {}\n").
+		Commit("initial")
+	r.Git("checkout", "--", ".")
+	return r
+}
+
+// chdir changes the current directory to dir until the test ends, for tests
+// of finding the repository from it.
+func chdir(t testing.TB, dir string) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestStat_submodule(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README", "").
+		AddSubmodule("vendor/lib", "0123456789012345678901234567890123456789").
+		Commit("add submodule")
+
+	repo := Repository{GitDir: r.GitDir}
+
+	fi, err := repo.Stat("vendor/lib")
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeIrregular, fi.Mode().Type())
+	assert.False(t, fi.IsDir())
+}
+
+func TestReadlink(t *testing.T) {
+	r := gittest.New(t).
+		AddSymlink("link", "target/file").
+		Commit("add link")
+
+	repo := Repository{GitDir: r.GitDir}
+
+	fi, err := repo.Lstat("link")
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSymlink, fi.Mode().Type())
+
+	target, err := repo.Readlink("link")
+	require.NoError(t, err)
+	assert.Equal(t, "target/file", target)
+
+	_, err = (&Repository{GitDir: newFixture(t).GitDir}).Readlink("git/git.go")
+	assert.Error(t, err)
+}
+
+func TestModTime_lastCommit(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "a\n").
+		AddFile("dir/b.txt", "b\n").
+		Commit("first").
+		AddFile("dir/b.txt", "B\n").
+		Commit("second").
+		Tag("v2").
+		AddFile("a.txt", "A\n").
+		Commit("third")
+
+	repo, err := NewRepository("v2", r.GitDir)
+	require.NoError(t, err)
+
+	for path, n := range map[string]int{"a.txt": 1, "dir/b.txt": 2, "dir": 2, "": 2} {
+		fi, err := repo.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, gittest.CommitTime(n).Unix(), fi.ModTime().Unix(), "%q", path)
+	}
+}
+
+func TestObjectID(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "a\n").
+		Commit("init")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	fi, err := repo.Stat("a.txt")
+	require.NoError(t, err)
+	oid, ok := ObjectID(fi)
+	assert.True(t, ok)
+	assert.Equal(t, r.Git("rev-parse", "HEAD:a.txt"), oid)
+
+	fi, err = repo.Stat("")
+	require.NoError(t, err)
+	oid, ok = ObjectID(fi)
+	assert.True(t, ok)
+	assert.Equal(t, r.Git("rev-parse", "HEAD^{tree}"), oid)
+
+	fi, err = os.Stat(".")
+	require.NoError(t, err)
+	_, ok = ObjectID(fi)
+	assert.False(t, ok)
+}
+
+func TestHostileNames(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("--help", "help\n").
+		AddFile("-n/--output=x", "output\n").
+		AddFile("a:b", "colon\n").
+		Commit("initial")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	for _, name := range []string{"--help", "-n/--output=x", "a:b"} {
+		fi, err := repo.Stat(name)
+		require.NoError(t, err, name)
+		assert.True(t, fi.Mode().IsRegular(), name)
+
+		ok, err := repo.Exists(name)
+		require.NoError(t, err, name)
+		assert.True(t, ok, name)
+
+		_, err = vfs.ReadFile(repo, name)
+		assert.NoError(t, err, name)
+
+		entries, err := repo.History(name, HistoryOptions{})
+		require.NoError(t, err, name)
+		assert.Len(t, entries, 1, name)
+
+		_, err = repo.Attributes(name)
+		assert.NoError(t, err, name)
+	}
+
+	for _, name := range []string{"../README.md", "-n/../../--help"} {
+		ok, err := repo.Exists(name)
+		require.NoError(t, err, name)
+		assert.False(t, ok, name)
+	}
+
+	_, err = repo.Attributes("--help", "--all")
+	assert.Error(t, err)
+
+	// revisions that look like options are not taken as such
+	out := filepath.Join(t.TempDir(), "out")
+	hostile := &Repository{Revision: "--output=" + out, GitDir: r.GitDir}
+
+	_, err = hostile.Stat("--help")
+	assert.Error(t, err)
+	_, err = hostile.Exists("--help")
+	assert.Error(t, err)
+	_, err = hostile.History("--help", HistoryOptions{})
+	assert.Error(t, err)
+	assert.Error(t, repo.DiffPatch(io.Discard, "--output="+out))
+
+	_, err = os.Stat(out)
+	assert.True(t, os.IsNotExist(err), "%v", err)
+}
+
+func TestOptionalLocks(t *testing.T) {
+	r := gittest.New(t).AddFile("a", "a").Commit("initial")
+
+	// a git command of the user in progress
+	lock := filepath.Join(r.GitDir, "index.lock")
+	require.NoError(t, os.WriteFile(lock, nil, 0644))
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+	assert.Contains(t, repo.commandContext(repo.context(), "status").Env, "GIT_OPTIONAL_LOCKS=0")
+
+	_, err = repo.ReadDir("")
+	assert.NoError(t, err)
+	_, err = repo.Open("a")
+	assert.NoError(t, err)
+
+	repo, err = NewRepository("HEAD", r.GitDir, WithEnv("GIT_OPTIONAL_LOCKS=1"))
+	require.NoError(t, err)
+	env := repo.commandContext(repo.context(), "status").Env
+	assert.Equal(t, "GIT_OPTIONAL_LOCKS=1", env[len(env)-1])
+}
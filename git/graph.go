@@ -0,0 +1,124 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// CommitNode is a commit with its parents, as listed by Ancestors.
+type CommitNode struct {
+	Commit  string
+	Parents []string // in order; the first is the branch merged into
+}
+
+// AncestorsOptions bounds Ancestors.
+type AncestorsOptions struct {
+	Limit       int      // the number of commits listed at most; zero means no limit
+	FirstParent bool     // follow only the first parents of merges
+	Exclude     []string // stop at these commits and their ancestors
+}
+
+// Parents returns the parents of the commit rev resolves to, none for
+// a root commit.
+func (repo *Repository) Parents(rev string) ([]string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.parents(rev)
+}
+
+func (repo *Repository) parents(rev string) ([]string, error) {
+	nodes, err := repo.ancestors(rev, AncestorsOptions{Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRevision, rev)
+	}
+
+	return nodes[0].Parents, nil
+}
+
+// MergeBase returns the best common ancestor of a and b, as git merge
+// would use, or "" if they have none.
+func (repo *Repository) MergeBase(a, b string) (string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	out, err := repo.git("merge-base", "--end-of-options", a, b)
+	if isExitCode(err, 1) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return out.first()
+}
+
+// IsAncestor reports whether a is an ancestor of b. A commit is an
+// ancestor of itself.
+func (repo *Repository) IsAncestor(a, b string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	_, err := repo.git("merge-base", "--is-ancestor", "--end-of-options", a, b)
+	if isExitCode(err, 1) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Ancestors lists rev and its ancestors with their parents, newest first,
+// within opts.
+func (repo *Repository) Ancestors(rev string, opts AncestorsOptions) ([]CommitNode, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.ancestors(rev, opts)
+}
+
+func (repo *Repository) ancestors(rev string, opts AncestorsOptions) ([]CommitNode, error) {
+	args := []string{"rev-list", "--parents"}
+	if opts.Limit > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(opts.Limit))
+	}
+	if opts.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	args = append(args, "--end-of-options", rev)
+	for _, ex := range opts.Exclude {
+		args = append(args, "^"+ex)
+	}
+	args = append(args, "--")
+
+	out, err := repo.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := out.lines('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []CommitNode{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		nodes = append(nodes, CommitNode{Commit: fields[0], Parents: fields[1:]})
+	}
+
+	return nodes, nil
+}
+
+func isExitCode(err error, code int) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == code
+}
@@ -0,0 +1,63 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestGraph(t *testing.T) {
+	r := gittest.New(t).AddFile("a", "a\n").Commit("root")
+	root := r.Head()
+	r.Git("checkout", "--quiet", "-b", "topic")
+	r.AddFile("b", "b\n").Commit("topic")
+	topic := r.Head()
+	r.Git("checkout", "--quiet", "main")
+	r.AddFile("c", "c\n").Commit("main")
+	main := r.Head()
+	r.Git("merge", "--quiet", "--no-ff", "-m", "merge", "topic")
+	merge := r.Head()
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	parents, err := repo.Parents(merge)
+	require.NoError(t, err)
+	assert.Equal(t, []string{main, topic}, parents)
+
+	parents, err = repo.Parents(root)
+	require.NoError(t, err)
+	assert.Empty(t, parents)
+
+	_, err = repo.Parents("nonexistent")
+	assert.ErrorIs(t, err, ErrUnknownRevision)
+
+	base, err := repo.MergeBase(main, "topic")
+	require.NoError(t, err)
+	assert.Equal(t, root, base)
+
+	ok, err := repo.IsAncestor(topic, merge)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.IsAncestor(topic, main)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	nodes, err := repo.Ancestors("HEAD", AncestorsOptions{})
+	require.NoError(t, err)
+	assert.Len(t, nodes, 4)
+	assert.Equal(t, CommitNode{Commit: merge, Parents: []string{main, topic}}, nodes[0])
+
+	nodes, err = repo.Ancestors("HEAD", AncestorsOptions{FirstParent: true, Exclude: []string{root}})
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, main, nodes[1].Commit)
+
+	nodes, err = repo.Ancestors("HEAD", AncestorsOptions{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, nodes, 1)
+}
@@ -0,0 +1,217 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RevisionsTouching returns the commit IDs reachable from the repository's
+// revision which touched path, newest first. At most limit commits are
+// returned after skipping the first skip ones; zero limit means no limit.
+func (repo *Repository) RevisionsTouching(path string, limit, skip int) ([]string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	args := []string{"rev-list"}
+	if limit > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(limit))
+	}
+	if skip > 0 {
+		args = append(args, "--skip="+strconv.Itoa(skip))
+	}
+	args = append(args, "--end-of-options", repo.revision(), "--")
+
+	if spec := repo.pathspec(path); spec != "" {
+		args = append(args, spec)
+	}
+
+	out, err := repo.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := out.lines('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	revs := []string{}
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		revs = append(revs, line)
+	}
+
+	return revs, nil
+}
+
+// HistoryEntry is a commit in the history of a file.
+type HistoryEntry struct {
+	Revision string
+	Author   Signature // resolved by .mailmap unless disabled by WithMailmap
+	Path     string    // path of the file at Revision
+	OldPath  string    // path before Revision if the file was renamed or copied by it
+}
+
+// WithMailmap sets whether names and email addresses of authors are mapped
+// to canonical ones by .mailmap, as git log --use-mailmap does. It is
+// enabled by default. The .mailmap of the revision is used in addition to
+// that of the work tree, if any.
+func WithMailmap(enabled bool) Option {
+	return func(repo *Repository) {
+		repo.noMailmap = !enabled
+	}
+}
+
+// authorFormat returns the log --format placeholders for the author, as
+// NUL-separated name, email and Unix time.
+func (repo *Repository) authorFormat() string {
+	if repo.noMailmap {
+		return "%an%x00%ae%x00%at"
+	}
+	return "%aN%x00%aE%x00%at"
+}
+
+// mailmapArgs returns the global options to read .mailmap of the revision.
+func (repo *Repository) mailmapArgs() []string {
+	if repo.noMailmap {
+		return nil
+	}
+	return []string{"-c", "mailmap.blob=" + repo.revision() + ":.mailmap"}
+}
+
+// HistoryOptions controls History.
+type HistoryOptions struct {
+	Limit  int
+	Skip   int
+	Follow bool // continue listing across renames
+}
+
+var rxStatus = regexp.MustCompile(`^[A-Z][0-9]*$`)
+
+// History returns the commits which touched path, newest first. With
+// opts.Follow the history continues across renames and each entry reports
+// the path the file had at that commit.
+func (repo *Repository) History(path string, opts HistoryOptions) ([]HistoryEntry, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	args := append(repo.mailmapArgs(), "log", "-z", "--format=%H%x00"+repo.authorFormat(), "--name-status")
+	if opts.Follow {
+		args = append(args, "--follow", "-M")
+	}
+	if opts.Limit > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(opts.Limit))
+	}
+	if opts.Skip > 0 {
+		args = append(args, "--skip="+strconv.Itoa(opts.Skip))
+	}
+	args = append(args, "--end-of-options", repo.revision(), "--")
+	if spec := repo.pathspec(path); spec != "" {
+		args = append(args, spec)
+	}
+
+	out, err := repo.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := out.lines('\x00')
+	if err != nil {
+		return nil, err
+	}
+
+	// example output (NUL shown as "|"):
+	//   <sha1>|<name>|<email>|<time>|\nM|d/b|<sha1>|<name>|<email>|<time>|\nA|d/b|
+	entries := []HistoryEntry{}
+	for i := 0; i < len(tokens); i++ {
+		token := strings.TrimLeft(tokens[i], "\n")
+		if token == "" {
+			continue
+		}
+
+		if !rxStatus.MatchString(token) {
+			if i+3 >= len(tokens) {
+				return nil, fmt.Errorf("could not parse log output: %q", out.String())
+			}
+
+			author, err := parseSignature(tokens[i+1 : i+4])
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, HistoryEntry{Revision: token, Author: author})
+			i += 3
+			continue
+		}
+
+		if len(entries) == 0 || i+1 >= len(tokens) {
+			return nil, fmt.Errorf("could not parse log output: %q", out.String())
+		}
+
+		e := &entries[len(entries)-1]
+		if token[0] == 'R' || token[0] == 'C' {
+			if i+2 >= len(tokens) {
+				return nil, fmt.Errorf("could not parse log output: %q", out.String())
+			}
+			e.OldPath, e.Path = repo.decodePath(tokens[i+1]), repo.decodePath(tokens[i+2])
+			i += 2
+		} else {
+			e.Path = repo.decodePath(tokens[i+1])
+			i++
+		}
+	}
+
+	return entries, nil
+}
+
+// BirthTime returns the author date of the first commit which added path,
+// or any file under it if path is a directory. With follow, the history of
+// a file is traced across renames so that the date is of the commit which
+// added it under the original name.
+func (repo *Repository) BirthTime(path string, follow bool) (time.Time, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	args := []string{"log", "--diff-filter=A", "--format=%at"}
+	if follow {
+		args = append(args, "--follow", "-M")
+	}
+	args = append(args, "--end-of-options", repo.revision(), "--")
+	if spec := repo.pathspec(path); spec != "" {
+		args = append(args, spec)
+	}
+
+	out, err := repo.git(args...)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	lines := strings.Fields(out.String())
+	if len(lines) == 0 {
+		return time.Time{}, &os.PathError{Op: "birthtime", Path: path, Err: os.ErrNotExist}
+	}
+
+	// commits are listed newest first
+	sec, err := strconv.ParseInt(lines[len(lines)-1], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse author date: %w", err)
+	}
+
+	return time.Unix(sec, 0), nil
+}
+
+// parseSignature parses the fields of authorFormat.
+func parseSignature(fields []string) (Signature, error) {
+	sec, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return Signature{}, fmt.Errorf("could not parse author date: %w", err)
+	}
+
+	return Signature{Name: fields[0], Email: fields[1], When: time.Unix(sec, 0)}, nil
+}
@@ -0,0 +1,146 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+// newRenameRepo returns a repository where old.txt is renamed to new.txt
+// in the third of five commits, whose IDs are returned oldest first.
+func newRenameRepo(t *testing.T) (*gittest.Repo, []string) {
+	content := "one\ntwo\nthree\nfour\nfive\n"
+
+	r := gittest.New(t)
+	var commits []string
+	for _, step := range []func(){
+		func() { r.AddFile("old.txt", content).AddFile("other.txt", "other\n").Commit("add") },
+		func() { r.AddFile("old.txt", content+"six\n").Commit("edit old.txt") },
+		func() { r.Remove("old.txt").AddFile("new.txt", content+"six\n").Commit("rename") },
+		func() { r.AddFile("new.txt", content+"six\nseven\n").Commit("edit new.txt") },
+		func() { r.AddFile("other.txt", "changed\n").Commit("edit other.txt") },
+	} {
+		step()
+		commits = append(commits, r.Head())
+	}
+
+	return r, commits
+}
+
+func TestRevisionsTouching(t *testing.T) {
+	r, commits := newRenameRepo(t)
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	revs, err := repo.RevisionsTouching("new.txt", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{commits[3], commits[2]}, revs)
+
+	revs, err = repo.RevisionsTouching("", 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, revs, 5)
+
+	limited, err := repo.RevisionsTouching("new.txt", 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{commits[3]}, limited)
+
+	skipped, err := repo.RevisionsTouching("new.txt", 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{commits[2]}, skipped)
+
+	skipped, err = repo.RevisionsTouching("new.txt", 0, 2)
+	require.NoError(t, err)
+	assert.Empty(t, skipped)
+}
+
+func TestHistory(t *testing.T) {
+	r, commits := newRenameRepo(t)
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	entries, err := repo.History("new.txt", HistoryOptions{Follow: true})
+	require.NoError(t, err)
+	require.Len(t, entries, 4, "followed across the rename")
+
+	for i, want := range []HistoryEntry{
+		{Revision: commits[3], Path: "new.txt"},
+		{Revision: commits[2], Path: "new.txt", OldPath: "old.txt"},
+		{Revision: commits[1], Path: "old.txt"},
+		{Revision: commits[0], Path: "old.txt"},
+	} {
+		assert.Equal(t, want.Revision, entries[i].Revision, i)
+		assert.Equal(t, want.Path, entries[i].Path, i)
+		assert.Equal(t, want.OldPath, entries[i].OldPath, i)
+	}
+
+	entries, err = repo.History("new.txt", HistoryOptions{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "not followed")
+	assert.Equal(t, commits[2], entries[1].Revision)
+	assert.Empty(t, entries[1].OldPath)
+
+	entries, err = repo.History("new.txt", HistoryOptions{Follow: true, Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestBirthTime(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("old.txt", "one\ntwo\nthree\n").
+		Commit("add old.txt").
+		AddFile("dir/a.txt", "a\n").
+		Commit("add dir").
+		Remove("old.txt").
+		AddFile("new.txt", "one\ntwo\nthree\n").
+		AddFile("dir/b.txt", "b\n").
+		Commit("rename")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	birth, err := repo.BirthTime("new.txt", false)
+	require.NoError(t, err)
+	assert.Equal(t, gittest.CommitTime(3).Unix(), birth.Unix())
+
+	birth, err = repo.BirthTime("new.txt", true)
+	require.NoError(t, err)
+	assert.Equal(t, gittest.CommitTime(1).Unix(), birth.Unix(), "followed across the rename")
+
+	birth, err = repo.BirthTime("dir", false)
+	require.NoError(t, err)
+	assert.Equal(t, gittest.CommitTime(2).Unix(), birth.Unix())
+
+	_, err = repo.BirthTime("nonexistent", false)
+	assert.True(t, errors.Is(err, os.ErrNotExist), "%v", err)
+}
+
+func TestHistory_mailmap(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("file", "").
+		AddFile(".mailmap", "Test Person <person@example.com> <test@example.com>\n").
+		Commit("first")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	entries, err := repo.History("file", HistoryOptions{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Test Person", entries[0].Author.Name)
+	assert.Equal(t, "person@example.com", entries[0].Author.Email)
+	assert.Equal(t, gittest.CommitTime(1).Unix(), entries[0].Author.When.Unix())
+
+	repo, err = NewRepository("HEAD", r.GitDir, WithMailmap(false))
+	require.NoError(t, err)
+
+	entries, err = repo.History("file", HistoryOptions{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "test", entries[0].Author.Name)
+	assert.Equal(t, "test@example.com", entries[0].Author.Email)
+}
@@ -0,0 +1,146 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// WithWorkTree sets the top of the work tree whose ignore rules IsIgnored
+// and Ignored read. It is needed only if GitDir is given and the work tree
+// is neither core.worktree nor the parent of a GitDir named ".git".
+func WithWorkTree(dir string) Option {
+	return func(repo *Repository) {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		repo.workTree = dir
+	}
+}
+
+// IsIgnored reports whether path is ignored by the ignore rules of the
+// work tree (.gitignore, info/exclude and core.excludesFile), the same
+// way git status decides it.
+func (repo *Repository) IsIgnored(path string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	ignored, err := repo.ignored(path)
+	if err != nil {
+		return false, err
+	}
+
+	return len(ignored) > 0, nil
+}
+
+// Ignored returns the subset of paths which are ignored, checking all
+// of them with a single git check-ignore invocation.
+func (repo *Repository) Ignored(paths ...string) ([]string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.ignored(paths...)
+}
+
+func (repo *Repository) ignored(paths ...string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	// with GitDir, git would take the current directory as the work tree,
+	// so the work tree is given and paths are passed absolute
+	checker, prefix := repo, ""
+	if repo.GitDir != "" {
+		dir, err := repo.workTreeDir()
+		if err != nil {
+			return nil, err
+		}
+		checker = repo.withEnv("GIT_WORK_TREE=" + dir)
+		prefix = filepath.ToSlash(dir) + "/"
+	} else {
+		cdup, err := repo.rootRelative("")
+		if err != nil {
+			return nil, err
+		}
+		prefix = cdup
+	}
+
+	var stdin strings.Builder
+	for _, p := range paths {
+		stdin.WriteString(prefix + repo.encodePath(p))
+		stdin.WriteByte('\x00')
+	}
+
+	out, err := checker.gitInput(strings.NewReader(stdin.String()), "check-ignore", "-z", "--stdin")
+	if err != nil {
+		// exit status 1 means none of the paths are ignored
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	lines, err := out.lines('\x00')
+	if err != nil {
+		return nil, err
+	}
+
+	ignored := []string{}
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		ignored = append(ignored, repo.decodePath(strings.TrimPrefix(line, prefix)))
+	}
+
+	return ignored, nil
+}
+
+// workTreeDir returns the absolute path of the top of the work tree of
+// GitDir: the one given by WithWorkTree, core.worktree, or the parent of a
+// GitDir named ".git".
+func (repo *Repository) workTreeDir() (string, error) {
+	if repo.workTree != "" {
+		return repo.workTree, nil
+	}
+
+	gitDir, err := filepath.Abs(repo.GitDir)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := repo.git("config", "--get-regexp", `^core\.(bare|worktree)$`)
+	if err != nil && !isExitCode(err, 1) {
+		return "", err
+	}
+
+	bare := false
+	if err == nil {
+		lines, err := out.lines('\n')
+		if err != nil {
+			return "", err
+		}
+
+		for _, line := range lines {
+			key, value, _ := strings.Cut(line, " ")
+			switch key {
+			case "core.worktree":
+				if !filepath.IsAbs(value) {
+					value = filepath.Join(gitDir, value)
+				}
+				return filepath.Clean(value), nil
+			case "core.bare":
+				bare = value == "true"
+			}
+		}
+	}
+
+	if !bare && filepath.Base(gitDir) == ".git" {
+		return filepath.Dir(gitDir), nil
+	}
+
+	return "", fmt.Errorf("work tree of %s is not known; use WithWorkTree", repo.GitDir)
+}
@@ -0,0 +1,65 @@
+package git
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestIgnored(t *testing.T) {
+	repo := Repository{GitDir: newFixture(t).GitDir}
+
+	ignored, err := repo.IsIgnored("foo.so")
+	require.NoError(t, err)
+	assert.True(t, ignored)
+
+	ignored, err = repo.IsIgnored("git/git.go")
+	require.NoError(t, err)
+	assert.False(t, ignored)
+
+	paths, err := repo.Ignored("git/git.go", "foo.so", "bar.rlib")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo.so", "bar.rlib"}, paths)
+}
+
+func TestIgnored_gitDir(t *testing.T) {
+	r := gittest.New(t).
+		AddFile(".gitignore", "*.log\n").
+		AddFile("sub/.gitignore", "build/\n").
+		Commit("init")
+	r.Git("checkout", "--", ".")
+
+	// the rules must be read from the work tree, not the current directory
+	chdir(t, t.TempDir())
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	ignored, err := repo.IsIgnored("x.log")
+	require.NoError(t, err)
+	assert.True(t, ignored)
+
+	paths, err := repo.Ignored("x.txt", "sub/y.log", "sub/build/z", "build/z")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub/y.log", "sub/build/z"}, paths)
+
+	bare := t.TempDir()
+	require.NoError(t, exec.Command("git", "clone", "-q", "--bare", r.Dir, bare).Run())
+
+	repo, err = NewRepository("HEAD", bare)
+	require.NoError(t, err)
+
+	_, err = repo.IsIgnored("x.log")
+	assert.Error(t, err, "a bare repository has no ignore rules to read")
+
+	repo, err = NewRepository("HEAD", bare, WithWorkTree(r.Dir))
+	require.NoError(t, err)
+
+	ignored, err = repo.IsIgnored("x.log")
+	require.NoError(t, err)
+	assert.True(t, ignored)
+}
@@ -0,0 +1,18 @@
+package git
+
+import "path/filepath"
+
+// WithIndexFile makes git commands use the index file at path instead of
+// the index of the repository, as GIT_INDEX_FILE does. Attributes then
+// reads .gitattributes from it, and staging done on a temporary index
+// neither disturbs nor waits for the real one. Operations building trees
+// of their own, such as Apply and Overlay.Commit, still use temporary
+// indexes.
+func WithIndexFile(path string) Option {
+	return func(repo *Repository) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		repo.indexFile = path
+	}
+}
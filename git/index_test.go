@@ -0,0 +1,43 @@
+package git
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestWithIndexFile(t *testing.T) {
+	r := gittest.New(t).AddFile(".gitattributes", "*.txt text\n").Commit("initial")
+
+	index := filepath.Join(t.TempDir(), "index")
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithIndexFile(index))
+	require.NoError(t, err)
+
+	// stages another .gitattributes in the index of repo only
+	_, err = repo.git("read-tree", "HEAD")
+	require.NoError(t, err)
+	out, err := repo.gitInput(strings.NewReader("*.txt binary\n"), "hash-object", "-w", "--stdin")
+	require.NoError(t, err)
+	oid, err := out.first()
+	require.NoError(t, err)
+	_, err = repo.git("update-index", "--cacheinfo", "100644,"+oid+",.gitattributes")
+	require.NoError(t, err)
+
+	attrs, err := repo.Attributes("a.txt", "text")
+	require.NoError(t, err)
+	assert.Equal(t, "unset", attrs["text"])
+
+	real, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+	attrs, err = real.Attributes("a.txt", "text")
+	require.NoError(t, err)
+	assert.Equal(t, "set", attrs["text"])
+
+	assert.NotEqual(t, r.Git("ls-files", "-s", ".gitattributes"), "100644 "+oid+" 0\t.gitattributes")
+}
@@ -0,0 +1,50 @@
+package git
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+)
+
+// ErrCorruptObject is matched with errors.Is by the error of Open when
+// WithVerifyContent finds the content of a blob not matching its ID.
+var ErrCorruptObject = errors.New("corrupt object")
+
+// WithVerifyContent makes Open hash the content of each blob it reads from
+// git as git does and check it against the object ID recorded in the tree,
+// so that a corrupted object store or a packfile rewritten while being
+// read fails explicitly rather than serving wrong bytes. Contents served
+// from the blob cache are not hashed again, while those stored by a
+// DiskCache are.
+func WithVerifyContent() Option {
+	return func(repo *Repository) {
+		repo.verifyContent = true
+	}
+}
+
+// verifyBlob reports an error matching ErrCorruptObject unless content
+// hashes to oid as a blob.
+func verifyBlob(oid string, content []byte) error {
+	var h hash.Hash
+	switch len(oid) {
+	case sha1.Size * 2:
+		h = sha1.New()
+	case sha256.Size * 2:
+		h = sha256.New()
+	default:
+		return fmt.Errorf("unknown object ID format: %s", oid)
+	}
+
+	h.Write([]byte("blob " + strconv.Itoa(len(content)) + "\x00"))
+	h.Write(content)
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != oid {
+		return fmt.Errorf("%w: content of %s hashes to %s", ErrCorruptObject, oid, got)
+	}
+
+	return nil
+}
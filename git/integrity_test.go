@@ -0,0 +1,64 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestWithVerifyContent(t *testing.T) {
+	r := gittest.New(t).AddFile("a", "hello\n").Commit("initial")
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithVerifyContent())
+	require.NoError(t, err)
+
+	f, err := repo.Open("a")
+	require.NoError(t, err)
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(b))
+
+	// a git flipping the content of blobs
+	realGit, err := exec.LookPath("git")
+	require.NoError(t, err)
+	gitPath := filepath.Join(t.TempDir(), "git")
+	script := fmt.Sprintf(`#!/bin/sh
+case "$*" in
+*"cat-file blob"*)
+	%[1]q "$@" | tr a-z A-Z
+	exit ;;
+esac
+exec %[1]q "$@"
+`, realGit)
+	require.NoError(t, os.WriteFile(gitPath, []byte(script), 0755))
+
+	repo, err = NewRepository("HEAD", r.GitDir, WithGitPath(gitPath), WithVerifyContent())
+	require.NoError(t, err)
+
+	_, err = repo.Open("a")
+	assert.ErrorIs(t, err, ErrCorruptObject)
+
+	repo, err = NewRepository("HEAD", r.GitDir, WithGitPath(gitPath))
+	require.NoError(t, err)
+
+	f, err = repo.Open("a")
+	require.NoError(t, err)
+	b, err = io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO\n", string(b))
+}
+
+func TestVerifyBlob(t *testing.T) {
+	assert.NoError(t, verifyBlob("e69de29bb2d1d6434b8b29ae775ad8c2e48c5391", nil))
+	assert.NoError(t, verifyBlob("473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813", nil))
+	assert.ErrorIs(t, verifyBlob("e69de29bb2d1d6434b8b29ae775ad8c2e48c5391", []byte("x")), ErrCorruptObject)
+	assert.Error(t, verifyBlob("e69de", nil))
+}
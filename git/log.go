@@ -0,0 +1,17 @@
+package git
+
+import "log/slog"
+
+// WithLogger makes the Repository log git commands it runs, cache lookups
+// and errors it cannot return to logger, all at the debug level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(repo *Repository) {
+		repo.logger = logger
+	}
+}
+
+func (repo *Repository) debug(msg string, args ...any) {
+	if repo.logger != nil {
+		repo.logger.DebugContext(repo.context(), msg, args...)
+	}
+}
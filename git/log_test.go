@@ -0,0 +1,57 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	repo, err := NewRepository("HEAD", newFixture(t).GitDir, WithLogger(logger))
+	require.NoError(t, err)
+
+	_, err = repo.ReadDir("git")
+	require.NoError(t, err)
+
+	_, err = repo.ReadDir("nonexistent")
+	require.Error(t, err)
+
+	require.Error(t, repo.DiffPatch(io.Discard, "nonexistent-revision"))
+
+	records := []map[string]interface{}{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var r map[string]interface{}
+		require.NoError(t, dec.Decode(&r))
+		records = append(records, r)
+	}
+
+	var execs, lookups, failures int
+	for _, r := range records {
+		assert.Equal(t, "DEBUG", r["level"])
+
+		switch r["msg"] {
+		case "git":
+			execs++
+			assert.NotEmpty(t, r["args"])
+			if _, ok := r["error"]; ok {
+				failures++
+			}
+		case "cache lookup":
+			lookups++
+			assert.Equal(t, "tree", r["cache"])
+		}
+	}
+
+	assert.True(t, execs >= 3, "rev-parse and ls-tree twice")
+	assert.True(t, failures >= 1, "diff of nonexistent revision")
+	assert.True(t, lookups >= 2)
+}
@@ -0,0 +1,147 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ManifestFormat is a format of the output of Manifest.
+type ManifestFormat int
+
+const (
+	// ManifestTSV lists entries one per line with tab-separated columns of
+	// path, type ("file", "dir", "link" or "submodule"), octal mode, size
+	// and object ID. Size is "-" except for files and links. Paths with
+	// tabs, newlines or double quotes are quoted as Go strings.
+	ManifestTSV ManifestFormat = iota
+	// ManifestMtree is an mtree(5) specification as written by bsdtar
+	// --format=mtree, which can be checked against an extracted tree.
+	// Submodules are listed as directories.
+	ManifestMtree
+)
+
+// Manifest writes the list of entries in the tree of the revision to w,
+// in format. Entries hidden by filters are omitted.
+func (repo *Repository) Manifest(w io.Writer, format ManifestFormat) (err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	defer repo.startSpan("Manifest", "")(&err)
+
+	entries, err := repo.lsTreeRecursive(true)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	switch format {
+	case ManifestTSV:
+		for _, e := range entries {
+			if repo.exposes(e) {
+				writeTSVEntry(bw, e)
+			}
+		}
+
+	case ManifestMtree:
+		if err := repo.writeMtree(bw, entries); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unknown manifest format: %d", format)
+	}
+
+	return bw.Flush()
+}
+
+func manifestType(e *treeEntry) string {
+	switch e.objType {
+	case objTypeDir:
+		return "dir"
+	case objTypeSymlink:
+		return "link"
+	case objTypeGitlink:
+		return "submodule"
+	}
+	return "file"
+}
+
+func writeTSVEntry(w io.Writer, e *treeEntry) {
+	name := e.Path()
+	if strings.ContainsAny(name, "\t\n\"") {
+		name = strconv.Quote(name)
+	}
+
+	size := "-"
+	if e.objType == objTypeRegular || e.objType == objTypeSymlink {
+		size = strconv.FormatInt(e.size, 10)
+	}
+
+	fmt.Fprintf(w, "%s\t%s\t%04o\t%s\t%s\n", name, manifestType(e), e.Mode().Perm(), size, e.sha1)
+}
+
+func (repo *Repository) writeMtree(w io.Writer, entries []*treeEntry) error {
+	var batch *catFile
+	defer func() {
+		if batch != nil {
+			batch.Close()
+		}
+	}()
+
+	fmt.Fprintln(w, "#mtree")
+	fmt.Fprintln(w, ". type=dir mode=0755")
+
+	for _, e := range entries {
+		if !repo.exposes(e) {
+			continue
+		}
+
+		name := "./" + mtreeEscape(e.Path())
+
+		switch e.objType {
+		case objTypeDir, objTypeGitlink:
+			fmt.Fprintf(w, "%s type=dir mode=0755\n", name)
+
+		case objTypeSymlink:
+			if batch == nil {
+				var err error
+				if batch, err = repo.startCatFile(repo.context()); err != nil {
+					return err
+				}
+			}
+
+			target := new(bytes.Buffer)
+			if err := batch.read(e.sha1, func(string, int64) io.Writer { return target }); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(w, "%s type=link mode=0777 link=%s\n", name, mtreeEscape(target.String()))
+
+		default:
+			fmt.Fprintf(w, "%s type=file mode=%04o size=%d\n", name, e.Mode().Perm(), e.size)
+		}
+	}
+
+	return nil
+}
+
+// mtreeEscape encodes s as in mtree(5): bytes other than printable ASCII,
+// spaces, '#' and backslashes are written as a backslash and three octal
+// digits.
+func mtreeEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || c == '\\' || c == '#' {
+			fmt.Fprintf(&b, "\\%03o", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,49 @@
+package git
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestManifest(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README", "readme\n").
+		AddExecutable("bin/run", "#!/bin/sh\n").
+		AddSymlink("link", "README").
+		AddFile("with space", "").
+		Commit("first")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	oid := func(rev string) string { return r.Git("rev-parse", "HEAD:"+rev) }
+
+	var buf bytes.Buffer
+	require.NoError(t, repo.Manifest(&buf, ManifestTSV))
+	assert.Equal(t, strings.Join([]string{
+		"README\tfile\t0644\t7\t" + oid("README"),
+		"bin\tdir\t0755\t-\t" + oid("bin"),
+		"bin/run\tfile\t0755\t10\t" + oid("bin/run"),
+		"link\tlink\t0777\t6\t" + oid("link"),
+		"with space\tfile\t0644\t0\t" + oid("with space"),
+	}, "\n")+"\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, repo.Manifest(&buf, ManifestMtree))
+	assert.Equal(t, `#mtree
+. type=dir mode=0755
+./README type=file mode=0644 size=7
+./bin type=dir mode=0755
+./bin/run type=file mode=0755 size=10
+./link type=link mode=0777 link=README
+./with\040space type=file mode=0644 size=0
+`, buf.String())
+
+	assert.Error(t, repo.Manifest(&buf, ManifestFormat(-1)))
+}
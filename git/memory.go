@@ -0,0 +1,123 @@
+package git
+
+import "sync"
+
+// Weights of the caches sharing a MemoryBudget. A cache of twice the weight
+// is allowed twice the bytes of another before it is evicted from: tree
+// listings take more git calls to rebuild per byte than blob contents.
+const (
+	blobCacheWeight = 1
+	treeCacheWeight = 2
+)
+
+// treeEntryBytes is the estimated memory taken by a cached tree entry, in
+// addition to its name.
+const treeEntryBytes = 160
+
+// MemoryBudget caps the memory taken by the caches of Repositories given it
+// by WithMemoryBudget: their directory listings and a blob cache of the
+// budget. When the total exceeds the budget, the least recently used items
+// of the cache most over its weighted share are evicted. It is safe for
+// concurrent use and may be shared by any number of Repositories.
+type MemoryBudget struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	usages   map[budgetMember]*budgetUsage
+
+	blobCache *BlobCache
+}
+
+// budgetMember is a cache charging its items to a MemoryBudget.
+type budgetMember interface {
+	// evictOldest evicts the least recently used item and returns its size,
+	// or 0 if the cache is empty. It must not call the budget back.
+	evictOldest() int64
+}
+
+type budgetUsage struct {
+	bytes  int64
+	weight int64
+}
+
+// NewMemoryBudget returns a MemoryBudget of maxBytes.
+func NewMemoryBudget(maxBytes int64) *MemoryBudget {
+	b := &MemoryBudget{
+		maxBytes: maxBytes,
+		usages:   map[budgetMember]*budgetUsage{},
+	}
+	b.blobCache = NewBlobCache(maxBytes)
+	b.blobCache.budget = b
+
+	return b
+}
+
+// WithMemoryBudget makes the Repository charge its directory listings to
+// b, and use the blob cache of b unless WithBlobCache is given.
+func WithMemoryBudget(b *MemoryBudget) Option {
+	return func(repo *Repository) {
+		repo.memoryBudget = b
+		if repo.blobCache == nil {
+			repo.blobCache = b.blobCache
+		}
+	}
+}
+
+// Bytes returns the memory charged to the budget.
+func (b *MemoryBudget) Bytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.bytes
+}
+
+// BlobCache returns the blob cache of the budget.
+func (b *MemoryBudget) BlobCache() *BlobCache {
+	return b.blobCache
+}
+
+// charge adds delta bytes used by m, of weight, and evicts items until the
+// budget is kept. m must not hold its own lock.
+func (b *MemoryBudget) charge(m budgetMember, weight, delta int64) {
+	if b == nil || delta == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	u, ok := b.usages[m]
+	if !ok {
+		u = &budgetUsage{weight: weight}
+		b.usages[m] = u
+	}
+	b.add(m, u, delta)
+
+	for b.bytes > b.maxBytes && len(b.usages) > 0 {
+		var victim budgetMember
+		var vu *budgetUsage
+		for m, u := range b.usages {
+			// compares bytes/weight without dividing
+			if vu == nil || u.bytes*vu.weight > vu.bytes*u.weight {
+				victim, vu = m, u
+			}
+		}
+
+		n := victim.evictOldest()
+		if n == 0 {
+			// the accounting of victim is off; forget it
+			b.add(victim, vu, -vu.bytes)
+			continue
+		}
+		b.add(victim, vu, -n)
+	}
+}
+
+func (b *MemoryBudget) add(m budgetMember, u *budgetUsage, delta int64) {
+	u.bytes += delta
+	b.bytes += delta
+	if u.bytes <= 0 {
+		// lets caches of Repositories no longer used be collected
+		delete(b.usages, m)
+	}
+}
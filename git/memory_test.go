@@ -0,0 +1,67 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestMemoryBudget_weighted(t *testing.T) {
+	budget := NewMemoryBudget(1000)
+	blobs := budget.BlobCache()
+	trees := newTreeCache(0)
+	trees.budget = budget
+
+	for i := range 4 {
+		blobs.add(fmt.Sprint(i), make([]byte, 200))
+	}
+	assert.Equal(t, int64(800), budget.Bytes())
+
+	// 2 entries of 161 bytes; blobs are the most over their share
+	trees.add("t1", map[string]*treeEntry{"a": {}, "b": {}})
+	assert.Equal(t, 3, blobs.Len())
+	assert.Equal(t, int64(600+322), budget.Bytes())
+
+	// blobs are evicted until they take less than half of the trees' bytes
+	// per weight, then trees are
+	trees.add("t2", map[string]*treeEntry{"c": {}, "d": {}, "e": {}})
+	assert.Equal(t, 2, blobs.Len())
+	_, ok := trees.get("t1")
+	assert.False(t, ok)
+	_, ok = trees.get("t2")
+	assert.True(t, ok)
+	assert.Equal(t, int64(400+483), budget.Bytes())
+}
+
+func TestWithMemoryBudget(t *testing.T) {
+	r := gittest.New(t)
+	for i := range 20 {
+		r.AddFile(fmt.Sprintf("dir%02d/file", i), strings.Repeat("x", 500))
+	}
+	r.Commit("initial")
+
+	budget := NewMemoryBudget(2000)
+	repo, err := NewRepository("HEAD", r.GitDir, WithMemoryBudget(budget))
+	require.NoError(t, err)
+	assert.Same(t, budget.BlobCache(), repo.blobCache)
+
+	entries, err := repo.ReadDir("")
+	require.NoError(t, err)
+	for _, e := range entries {
+		f, err := repo.Open(e.Name() + "/file")
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, f)
+		require.NoError(t, err)
+
+		assert.LessOrEqual(t, budget.Bytes(), int64(2000))
+	}
+
+	assert.Positive(t, budget.Bytes())
+	assert.Less(t, repo.treeCache.lru.Len(), 21)
+}
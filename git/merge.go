@@ -0,0 +1,63 @@
+package git
+
+import (
+	"strings"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// MergeConflictError is returned by MergePreview when the merge has
+// conflicts.
+type MergeConflictError struct {
+	Paths []string // conflicted paths, sorted
+}
+
+func (e *MergeConflictError) Error() string {
+	return "merge conflict in " + strings.Join(e.Paths, ", ")
+}
+
+// MergePreview returns a filesystem of the result of merging theirs into
+// ours, as git merge would make, using git merge-tree. Nothing is checked
+// out or committed. base is the merge base; if empty, git finds one, and
+// giving it requires git 2.40 or later.
+//
+// If the merge has conflicts, the filesystem is returned along with a
+// *MergeConflictError, and conflicted files have conflict markers in
+// their content as left in the work tree by git merge.
+func MergePreview(repo *Repository, base, ours, theirs string) (vfs.FileSystem, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	args := []string{"merge-tree", "--write-tree", "-z", "--name-only", "--no-messages"}
+	if base != "" {
+		args = append(args, "--merge-base="+base)
+	}
+	args = append(args, "--end-of-options", ours, theirs)
+
+	out, err := repo.git(args...)
+
+	// exit status 1 means the merge has conflicts
+	if err != nil && !isExitCode(err, 1) {
+		return nil, err
+	}
+
+	fields, _ := out.lines('\x00')
+	tree := fields[0]
+
+	view := repo.treeView(tree, ours)
+	if err == nil {
+		return view, nil
+	}
+
+	conflict := &MergeConflictError{}
+	for _, p := range fields[1:] {
+		if p == "" {
+			break
+		}
+		if n := len(conflict.Paths); n == 0 || conflict.Paths[n-1] != p {
+			conflict.Paths = append(conflict.Paths, p)
+		}
+	}
+
+	return view, conflict
+}
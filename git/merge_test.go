@@ -0,0 +1,55 @@
+package git
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestMergePreview(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "a\n").
+		AddFile("b.txt", "b\n").
+		Commit("base").
+		Tag("base").
+		AddFile("a.txt", "ours\n").
+		Commit("ours").
+		Tag("ours")
+	r.Git("reset", "--quiet", "base")
+	r.AddFile("b.txt", "theirs\n").Commit("theirs").Tag("clean")
+	r.AddFile("a.txt", "theirs\n").Commit("conflicting").Tag("conflicting")
+	head := r.Head()
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	fs, err := MergePreview(repo, "", "ours", "clean")
+	require.NoError(t, err)
+
+	a, err := vfs.ReadFile(fs, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "ours\n", string(a))
+
+	b, err := vfs.ReadFile(fs, "b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "theirs\n", string(b))
+
+	fs, err = MergePreview(repo, "", "ours", "conflicting")
+	var conflict *MergeConflictError
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	assert.Equal(t, []string{"a.txt"}, conflict.Paths)
+
+	a, err = vfs.ReadFile(fs, "a.txt")
+	require.NoError(t, err)
+	assert.Contains(t, string(a), "<<<<<<<")
+
+	_, err = MergePreview(repo, "", "ours", "nonexistent")
+	assert.Error(t, err)
+
+	assert.Equal(t, head, r.Head(), "nothing committed")
+}
@@ -0,0 +1,314 @@
+package git
+
+import (
+	"context"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MirrorsConfig configures how Mirrors keeps its clones fresh.
+type MirrorsConfig struct {
+	// Interval is the time between fetches of each clone. Zero means an
+	// hour.
+	Interval time.Duration
+	// Jitter is the fraction of Interval by which fetches are randomly
+	// delayed, so that the clones are not all fetched at once.
+	Jitter float64
+	// GCEvery makes "git gc --auto" run after every GCEvery fetches of a
+	// clone. Zero means never.
+	GCEvery int
+	// Options are given to the Repositories handed out, and their git
+	// settings are used for fetching as well.
+	Options []Option
+}
+
+// Mirrors keeps mirror clones of remote repositories under a cache
+// directory, laid out as NewFromURL does, and fetches them in the
+// background while Run runs. Repositories pinned to the clones are handed
+// out by Repository without touching the network unless the revision is
+// missing from the clone. Clones of Mirrors may be evicted by PruneCache,
+// to be cloned again when next used.
+type Mirrors struct {
+	cacheDir string
+	config   MirrorsConfig
+
+	mu      sync.Mutex
+	mirrors map[string]*mirror // by URL
+	added   chan struct{}
+}
+
+type mirror struct {
+	mu      sync.Mutex // held while fetching
+	url     string
+	repo    *Repository
+	next    time.Time // when to fetch next
+	fetches int
+	err     error // of the last fetch
+}
+
+// NewMirrors returns Mirrors keeping clones under cacheDir.
+func NewMirrors(cacheDir string, config MirrorsConfig) *Mirrors {
+	if config.Interval <= 0 {
+		config.Interval = time.Hour
+	}
+
+	return &Mirrors{
+		cacheDir: cacheDir,
+		config:   config,
+		mirrors:  map[string]*mirror{},
+		added:    make(chan struct{}, 1),
+	}
+}
+
+// Repository returns a Repository for the revision of the remote at url,
+// pinned to the commit it resolves to in the clone. The clone is made on
+// first use and kept fresh from then on; it is fetched now only if it
+// lacks revision.
+func (ms *Mirrors) Repository(url, revision string) (*Repository, error) {
+	if revision == "" {
+		revision = "HEAD"
+	}
+
+	m, err := ms.mirror(url)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := NewRepository(revision, m.repo.GitDir, ms.config.Options...)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := repo.resolveCommit(revision)
+	if err != nil {
+		if err := ms.fetch(m); err != nil {
+			return nil, err
+		}
+		if commit, err = repo.resolveCommit(revision); err != nil {
+			return nil, err
+		}
+	}
+
+	repo.Revision = commit
+
+	if err := touchCache(repo.GitDir); err != nil {
+		repo.debug("recording cache use failed", "error", err)
+	}
+
+	return repo, nil
+}
+
+// mirror returns the mirror of url, cloning the remote if it is the
+// first use of url.
+func (ms *Mirrors) mirror(url string) (*mirror, error) {
+	ms.mu.Lock()
+	m, ok := ms.mirrors[url]
+	if !ok {
+		m = &mirror{url: url}
+		m.mu.Lock()
+		ms.mirrors[url] = m
+	}
+	ms.mu.Unlock()
+
+	if ok {
+		// waits for the clone made by whoever added it
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.repo == nil {
+			return nil, m.err
+		}
+		return m, nil
+	}
+
+	defer m.mu.Unlock()
+
+	dir := filepath.Join(ms.cacheDir, cacheKey(url))
+	repo := &Repository{Revision: "HEAD", GitDir: dir}
+	for _, opt := range ms.config.Options {
+		opt(repo)
+	}
+
+	if err := ms.ensureClone(repo, url); err != nil {
+		// the next use tries again
+		m.err = err
+		ms.mu.Lock()
+		delete(ms.mirrors, url)
+		ms.mu.Unlock()
+		return nil, err
+	}
+
+	m.repo = repo
+	m.next = ms.nextFetch()
+
+	select {
+	case ms.added <- struct{}{}:
+	default:
+	}
+
+	return m, nil
+}
+
+// ensureClone clones url into the directory of repo unless it exists,
+// which it may not even after the first use if PruneCache evicted it.
+func (ms *Mirrors) ensureClone(repo *Repository, url string) error {
+	if err := os.MkdirAll(ms.cacheDir, 0777); err != nil {
+		return err
+	}
+
+	unlock, err := lockCache(repo.GitDir, false, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, err = os.Stat(repo.GitDir)
+	if os.IsNotExist(err) {
+		err = repo.cloneMirror(url, ms.cacheDir, repo.GitDir)
+	}
+
+	return err
+}
+
+func (ms *Mirrors) nextFetch() time.Time {
+	d := ms.config.Interval
+	if ms.config.Jitter > 0 {
+		d += time.Duration(rand.Float64() * ms.config.Jitter * float64(d))
+	}
+
+	return time.Now().Add(d)
+}
+
+// fetch fetches the clone of m, pruning refs deleted on the remote, and
+// collects garbage if due.
+func (ms *Mirrors) fetch(m *mirror) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.repo.mu.Lock()
+	defer m.repo.mu.Unlock()
+
+	m.next = ms.nextFetch()
+
+	if err := ms.ensureClone(m.repo, m.url); err != nil {
+		m.err = err
+		return err
+	}
+
+	unlock, err := lockCache(m.repo.GitDir, false, true)
+	if err != nil {
+		m.err = err
+		return err
+	}
+	defer unlock()
+
+	if _, err := m.repo.git("fetch", "--quiet", "--prune", "origin"); err != nil {
+		m.err = err
+		m.repo.debug("mirror fetch failed", "error", err)
+		return err
+	}
+	m.err = nil
+	m.fetches++
+
+	if ms.config.GCEvery > 0 && m.fetches%ms.config.GCEvery == 0 {
+		if _, err := m.repo.git("gc", "--auto", "--quiet"); err != nil {
+			m.repo.debug("mirror gc failed", "error", err)
+		}
+	}
+
+	return nil
+}
+
+// Refresh fetches the clone of url now.
+func (ms *Mirrors) Refresh(url string) error {
+	m, err := ms.mirror(url)
+	if err != nil {
+		return err
+	}
+
+	return ms.fetch(m)
+}
+
+// Err returns the error of the last fetch of the clone of url, if it
+// failed.
+func (ms *Mirrors) Err(url string) error {
+	ms.mu.Lock()
+	m, ok := ms.mirrors[url]
+	ms.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.err
+}
+
+// Run fetches each clone in use once its interval has passed, until ctx is
+// done. A failed fetch is retried at the next interval while the
+// Repositories already handed out keep serving what they are pinned to.
+func (ms *Mirrors) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		case <-ms.added:
+		}
+
+		next := time.Now().Add(ms.config.Interval)
+		for _, m := range ms.due() {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			ms.fetch(m)
+		}
+
+		for _, m := range ms.list() {
+			m.mu.Lock()
+			if m.repo != nil && m.next.Before(next) {
+				next = m.next
+			}
+			m.mu.Unlock()
+		}
+
+		timer.Stop()
+		timer.Reset(time.Until(next))
+	}
+}
+
+// list returns the mirrors. Their locks must not be taken while holding
+// ms.mu, which is taken by a clone failing.
+func (ms *Mirrors) list() []*mirror {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	mirrors := make([]*mirror, 0, len(ms.mirrors))
+	for _, m := range ms.mirrors {
+		mirrors = append(mirrors, m)
+	}
+
+	return mirrors
+}
+
+// due returns the mirrors to be fetched now.
+func (ms *Mirrors) due() []*mirror {
+	now := time.Now()
+
+	var due []*mirror
+	for _, m := range ms.list() {
+		m.mu.Lock()
+		if m.repo != nil && !m.next.After(now) {
+			due = append(due, m)
+		}
+		m.mu.Unlock()
+	}
+
+	return due
+}
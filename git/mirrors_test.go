@@ -0,0 +1,57 @@
+package git
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestMirrors(t *testing.T) {
+	r := gittest.New(t).AddFile("a", "1").Commit("first").Tag("v1")
+	first := r.Head()
+
+	ms := NewMirrors(t.TempDir(), MirrorsConfig{Interval: 20 * time.Millisecond, Jitter: 0.5, GCEvery: 1})
+
+	repo, err := ms.Repository(r.Dir, "main")
+	require.NoError(t, err)
+	assert.Equal(t, first, repo.Revision)
+
+	// fetched on demand for a revision missing from the clone
+	r.AddFile("a", "2").Commit("second").Tag("v2")
+	repo, err = ms.Repository(r.Dir, "v2")
+	require.NoError(t, err)
+	assert.Equal(t, r.Head(), repo.Revision)
+	pinned, second := repo, r.Head()
+
+	r.AddFile("a", "3").Commit("third")
+	third := r.Head()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- ms.Run(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		repo, err := ms.Repository(r.Dir, "main")
+		return err == nil && repo.Revision == third
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Repositories handed out stay pinned
+	assert.Equal(t, second, pinned.Revision)
+	assert.NoError(t, ms.Err(r.Dir))
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestMirrors_cloneError(t *testing.T) {
+	ms := NewMirrors(t.TempDir(), MirrorsConfig{})
+
+	_, err := ms.Repository(t.TempDir()+"/nonexistent", "HEAD")
+	assert.Error(t, err)
+	assert.Empty(t, ms.list())
+}
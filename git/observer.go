@@ -0,0 +1,67 @@
+package git
+
+import (
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Observer is notified of the work done by a Repository, to export metrics
+// of it. The metrics package implements one for Prometheus.
+type Observer interface {
+	// GitExec is called when a git command exits. args exclude the git
+	// executable and --git-dir.
+	GitExec(args []string, duration time.Duration, err error)
+	// CacheLookup is called on every lookup of the named cache.
+	CacheLookup(cache string, hit bool)
+	// FileOpened is called when Open reads a blob of size bytes, and
+	// FileClosed when it is closed.
+	FileOpened(size int64)
+	FileClosed()
+}
+
+// WithObserver makes the Repository report its work to o.
+func WithObserver(o Observer) Option {
+	return func(repo *Repository) {
+		repo.observer = o
+	}
+}
+
+func (repo *Repository) observeExec(cmd *exec.Cmd, start time.Time, out []byte, err error) {
+	if repo.observer == nil && repo.logger == nil && repo.debugLog == nil {
+		return
+	}
+
+	args, duration := commandArgs(cmd), time.Since(start)
+
+	repo.debugLog.add(args, duration, out, err)
+
+	if repo.observer != nil {
+		repo.observer.GitExec(args, duration, err)
+	}
+
+	if err != nil {
+		repo.debug("git", "args", args, "duration", duration, "error", err)
+	} else {
+		repo.debug("git", "args", args, "duration", duration)
+	}
+}
+
+// commandArgs returns the arguments of cmd without the git executable and
+// --git-dir.
+func commandArgs(cmd *exec.Cmd) []string {
+	args := cmd.Args[1:]
+	if len(args) > 0 && strings.HasPrefix(args[0], "--git-dir=") {
+		args = args[1:]
+	}
+
+	return args
+}
+
+func (repo *Repository) observeCache(cache string, hit bool) {
+	if repo.observer != nil {
+		repo.observer.CacheLookup(cache, hit)
+	}
+
+	repo.debug("cache lookup", "cache", cache, "hit", hit)
+}
@@ -0,0 +1,61 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testObserver struct {
+	execs      [][]string
+	hits, miss int
+	open       int
+	bytes      int64
+}
+
+func (o *testObserver) GitExec(args []string, d time.Duration, err error) {
+	o.execs = append(o.execs, args)
+}
+
+func (o *testObserver) CacheLookup(cache string, hit bool) {
+	if hit {
+		o.hits++
+	} else {
+		o.miss++
+	}
+}
+
+func (o *testObserver) FileOpened(size int64) { o.open++; o.bytes += size }
+func (o *testObserver) FileClosed()           { o.open-- }
+
+func TestWithObserver(t *testing.T) {
+	chdir(t, newFixture(t).Dir)
+
+	o := &testObserver{}
+	repo, err := NewRepository("HEAD", "", WithObserver(o))
+	require.NoError(t, err)
+
+	_, err = repo.ReadDir("git")
+	require.NoError(t, err)
+	_, err = repo.ReadDir("git")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, o.hits, "root and git/")
+	assert.Equal(t, 2, o.miss)
+
+	f, err := repo.Open("git/git.go")
+	require.NoError(t, err)
+	assert.Equal(t, 1, o.open)
+	assert.True(t, o.bytes > 0)
+
+	f.Close()
+	f.Close()
+	assert.Equal(t, 0, o.open)
+
+	require.NotEmpty(t, o.execs)
+	assert.Equal(t, "rev-parse", o.execs[0][0])
+	assert.Equal(t, "rev-parse", o.execs[1][0])
+	assert.Equal(t, "ls-tree", o.execs[2][0])
+}
@@ -0,0 +1,67 @@
+package git
+
+import (
+	"context"
+	"os"
+)
+
+// Option configures a Repository created by NewRepository.
+type Option func(*Repository)
+
+// WithContext sets the context git commands are run with. Commands are
+// killed when ctx is done.
+func WithContext(ctx context.Context) Option {
+	return func(repo *Repository) {
+		repo.ctx = ctx
+	}
+}
+
+// WithTreeCacheSize bounds the number of directory listings cached,
+// evicting the least recently used ones. Zero means unlimited.
+func WithTreeCacheSize(n int) Option {
+	return func(repo *Repository) {
+		repo.treeCacheSize = n
+	}
+}
+
+// WithModTimeMode sets how ModTime of entries is computed.
+func WithModTimeMode(mode ModTimeMode) Option {
+	return func(repo *Repository) {
+		repo.ModTimeMode = mode
+	}
+}
+
+// WithGitPath sets the path to the git executable. By default "git"
+// is looked up in PATH.
+func WithGitPath(gitPath string) Option {
+	return func(repo *Repository) {
+		repo.gitPath = gitPath
+	}
+}
+
+// WithEnv adds environment variables in the form "KEY=value" to those
+// git commands are run with.
+func WithEnv(env ...string) Option {
+	return func(repo *Repository) {
+		repo.env = append(repo.env, env...)
+	}
+}
+
+// WithIsolatedEnv makes git commands run with an environment which is not
+// inherited from the process, so that neither ambient GIT_* variables nor
+// user or system gitconfig affect the results. Only PATH is passed
+// through; variables such as HOME can be given by WithEnv.
+func WithIsolatedEnv() Option {
+	return func(repo *Repository) {
+		repo.isolatedEnv = true
+	}
+}
+
+func isolatedEnv() []string {
+	return []string{
+		"PATH=" + os.Getenv("PATH"),
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_SYSTEM=" + os.DevNull,
+		"GIT_CONFIG_GLOBAL=" + os.DevNull,
+	}
+}
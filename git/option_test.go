@@ -0,0 +1,63 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRepository_options(t *testing.T) {
+	chdir(t, newFixture(t).Dir)
+
+	repo, err := NewRepository("", "",
+		WithModTimeMode(ModTimeCommitterDate),
+		WithTreeCacheSize(1),
+		WithEnv("GIT_TRACE=0"),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "HEAD", repo.Revision)
+	assert.NotEmpty(t, repo.GitDir)
+	assert.Equal(t, ModTimeCommitterDate, repo.ModTimeMode)
+
+	_, err = repo.ReadDir("git")
+	require.NoError(t, err)
+	_, err = repo.ReadDir(".")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, repo.treeCache.lru.Len())
+}
+
+func TestWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	chdir(t, newFixture(t).Dir)
+	_, err := NewRepository("", "", WithContext(ctx))
+	assert.Error(t, err)
+}
+
+func TestWithGitPath(t *testing.T) {
+	chdir(t, newFixture(t).Dir)
+	_, err := NewRepository("", "", WithGitPath("/nonexistent/git"))
+	assert.Error(t, err)
+}
+
+func TestWithIsolatedEnv(t *testing.T) {
+	chdir(t, newFixture(t).Dir)
+	t.Setenv("GIT_DIR", "/nonexistent")
+
+	_, err := NewRepository("", "")
+	assert.Error(t, err)
+
+	repo, err := NewRepository("", "", WithIsolatedEnv())
+	require.NoError(t, err)
+
+	_, err = repo.Stat("git/git.go")
+	assert.NoError(t, err)
+
+	_, err = NewRepository("", "", WithIsolatedEnv(), WithEnv("GIT_DIR=/nonexistent"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package git
+
+import (
+	"os"
+	"sort"
+)
+
+// ReadDirOrder specifies the order of entries ReadDir and OpenDir list.
+type ReadDirOrder int
+
+const (
+	// OrderByName sorts entries by the bytes of their names (default), as
+	// os.ReadDir does.
+	OrderByName ReadDirOrder = iota
+	// OrderTree sorts entries in the order git stores them in tree
+	// objects, where the name of a directory is compared as if it ends
+	// with "/". It matches the order git ls-tree and git archive list.
+	OrderTree
+	// OrderDirsFirst lists directories before other entries, each sorted
+	// by name.
+	OrderDirsFirst
+)
+
+// WithReadDirOrder sets the order of entries ReadDir and OpenDir list.
+func WithReadDirOrder(order ReadDirOrder) Option {
+	return func(repo *Repository) {
+		repo.readDirOrder = order
+	}
+}
+
+// less reports whether the entry named a, a directory if aDir, comes before
+// b in the order.
+func (order ReadDirOrder) less(a string, aDir bool, b string, bDir bool) bool {
+	switch order {
+	case OrderTree:
+		if aDir {
+			a += "/"
+		}
+		if bDir {
+			b += "/"
+		}
+	case OrderDirsFirst:
+		if aDir != bDir {
+			return aDir
+		}
+	}
+
+	return a < b
+}
+
+func (order ReadDirOrder) sortEntries(entries []os.FileInfo) {
+	if order == OrderByName {
+		// already sorted by name
+		return
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return order.less(entries[i].Name(), entries[i].IsDir(), entries[j].Name(), entries[j].IsDir())
+	})
+}
@@ -0,0 +1,53 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestWithReadDirOrder(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a-b", "x\n").
+		AddFile("a/c", "x\n").
+		AddFile("a.txt", "x\n").
+		AddFile("B/d", "x\n").
+		AddFile("b", "x\n").
+		Commit("initial")
+
+	tests := []struct {
+		order    ReadDirOrder
+		expected []string
+	}{
+		{OrderByName, []string{"B", "a", "a-b", "a.txt", "b"}},
+		{OrderTree, []string{"B", "a-b", "a.txt", "a", "b"}},
+		{OrderDirsFirst, []string{"B", "a", "a-b", "a.txt", "b"}},
+	}
+
+	for _, test := range tests {
+		for _, snapshot := range []bool{false, true} {
+			opts := []Option{WithReadDirOrder(test.order)}
+			if snapshot {
+				opts = append(opts, WithZipSnapshot())
+			}
+
+			repo, err := NewRepository("HEAD", r.GitDir, opts...)
+			require.NoError(t, err)
+
+			entries, err := repo.ReadDir("")
+			require.NoError(t, err)
+
+			names := make([]string, len(entries))
+			for i, fi := range entries {
+				names[i] = fi.Name()
+			}
+			assert.Equal(t, test.expected, names, "order %d, snapshot %v", test.order, snapshot)
+		}
+	}
+
+	// the order git itself stores
+	assert.Equal(t, "B\na-b\na.txt\na\nb", r.Git("ls-tree", "--name-only", "HEAD"))
+}
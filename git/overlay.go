@@ -0,0 +1,175 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Overlay is a set of changes to the tree of the revision of a Repository,
+// kept in memory until Commit writes them as a commit.
+type Overlay struct {
+	// Ref, if set, is updated by Commit to the commit made, provided it
+	// still points to the commit the changes are based on.
+	Ref string
+
+	repo    *Repository
+	changes map[string]*overlayFile // by path; nil if removed
+}
+
+type overlayFile struct {
+	content []byte
+	mode    os.FileMode
+}
+
+// Signature identifies the author of a commit.
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time // Overlay.Commit takes the current time if zero
+}
+
+// NewOverlay returns an empty Overlay on the revision of repo, which must
+// be a commit.
+func NewOverlay(repo *Repository) *Overlay {
+	return &Overlay{repo: repo, changes: map[string]*overlayFile{}}
+}
+
+// WriteFile sets the content of the file name. mode is 0644, 0755 or
+// os.ModeSymlink, in which case content is the target of the link.
+func (o *Overlay) WriteFile(name string, content []byte, mode os.FileMode) {
+	o.changes[cleanPath(name)] = &overlayFile{content: content, mode: mode}
+}
+
+// Remove removes the file name.
+func (o *Overlay) Remove(name string) {
+	o.changes[cleanPath(name)] = nil
+}
+
+func cleanPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// Commit writes the blobs and trees for the changes and a commit of them
+// whose parent is the revision of the Repository, with author as both the
+// author and the committer. It returns the ID of the commit. Nothing is
+// checked out.
+func (o *Overlay) Commit(message string, author Signature) (string, error) {
+	repo := o.repo
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	parent, err := repo.resolveCommit(repo.revision())
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "vcsfs-commit-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	indexed := repo.withEnv("GIT_INDEX_FILE=" + filepath.Join(dir, "index"))
+
+	if _, err := indexed.git("read-tree", parent); err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(o.changes))
+	for name := range o.changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var info bytes.Buffer
+	for _, name := range names {
+		f := o.changes[name]
+		if f == nil {
+			fmt.Fprintf(&info, "0 %s\t%s\x00", strings.Repeat("0", len(parent)), name)
+			continue
+		}
+
+		out, err := repo.gitInput(bytes.NewReader(f.content), "hash-object", "-w", "--stdin")
+		if err != nil {
+			return "", err
+		}
+
+		oid, err := out.first()
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&info, "%s %s\t%s\x00", blobMode(f.mode), oid, name)
+	}
+
+	if _, err := indexed.gitInput(&info, "update-index", "-z", "--index-info"); err != nil {
+		return "", err
+	}
+
+	out, err := indexed.git("write-tree")
+	if err != nil {
+		return "", err
+	}
+
+	tree, err := out.first()
+	if err != nil {
+		return "", err
+	}
+
+	when := author.When
+	if when.IsZero() {
+		when = time.Now()
+	}
+	date := when.Format(time.RFC3339)
+
+	signed := repo.withEnv(
+		"GIT_AUTHOR_NAME="+author.Name,
+		"GIT_AUTHOR_EMAIL="+author.Email,
+		"GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_NAME="+author.Name,
+		"GIT_COMMITTER_EMAIL="+author.Email,
+		"GIT_COMMITTER_DATE="+date,
+	)
+
+	out, err = signed.gitInput(strings.NewReader(message), "commit-tree", tree, "-p", parent)
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := out.first()
+	if err != nil {
+		return "", err
+	}
+
+	if o.Ref != "" {
+		if _, err := repo.git("update-ref", "-m", firstLine(message), "--end-of-options", o.Ref, commit, parent); err != nil {
+			return "", err
+		}
+		// the revision may be the ref moved
+		repo.resolved = nil
+	}
+
+	return commit, nil
+}
+
+func blobMode(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return "120000"
+	case mode&0100 != 0:
+		return "100755"
+	}
+	return "100644"
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,68 @@
+package git
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestOverlay_Commit(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README", "readme\n").
+		AddFile("old.txt", "old\n").
+		Commit("first")
+	parent := r.Head()
+
+	repo, err := NewRepository("main", r.GitDir)
+	require.NoError(t, err)
+
+	o := NewOverlay(repo)
+	o.Ref = "refs/heads/main"
+	o.WriteFile("/dir/new.txt", []byte("new\n"), 0644)
+	o.WriteFile("run.sh", []byte("#!/bin/sh\n"), 0755)
+	o.WriteFile("link", []byte("README"), os.ModeSymlink)
+	o.Remove("old.txt")
+
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	commit, err := o.Commit("update files\n\ndetails", Signature{Name: "Bot", Email: "bot@example.com", When: when})
+	require.NoError(t, err)
+
+	assert.Equal(t, commit, r.Head(), "ref updated")
+	assert.Equal(t, parent, r.Git("rev-parse", commit+"^"))
+	assert.Equal(t, "Bot <bot@example.com> 1577934245", r.Git("log", "-1", "--format=%an <%ae> %at", commit))
+	assert.Equal(t, "Bot", r.Git("log", "-1", "--format=%cn", commit))
+	assert.Equal(t, "update files", r.Git("log", "-1", "--format=%s", commit))
+
+	require.NoError(t, repo.SetRevision(commit))
+
+	content, err := vfs.ReadFile(repo, "dir/new.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(content))
+
+	fi, err := repo.Stat("run.sh")
+	require.NoError(t, err)
+	assert.Equal(t, "-rwxr-xr-x", fi.Mode().String())
+
+	target, err := repo.Readlink("link")
+	require.NoError(t, err)
+	assert.Equal(t, "README", target)
+
+	_, err = repo.Stat("old.txt")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	stale, err := NewRepository(parent, r.GitDir)
+	require.NoError(t, err)
+
+	o = NewOverlay(stale)
+	o.Ref = "refs/heads/main"
+	o.WriteFile("README", []byte("conflict\n"), 0644)
+	_, err = o.Commit("stale", Signature{Name: "Bot", Email: "bot@example.com"})
+	assert.Error(t, err, "ref moved since")
+	assert.Equal(t, commit, r.Head())
+}
@@ -0,0 +1,142 @@
+package git
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"strings"
+)
+
+// Prefetch warms the caches for accessing paths, so that the first
+// accesses do not wait for git. The directories leading to paths, and
+// paths themselves if they are directories, are listed by a single git
+// process. If a BlobCache is given by WithBlobCache, the contents of the
+// files among paths are read into it as well. Paths which do not exist are
+// ignored.
+func (repo *Repository) Prefetch(paths ...string) (err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	defer repo.startSpan("Prefetch", "")(&err)
+
+	repo.autoFetch()
+
+	root, err := repo.rootTree()
+	if err != nil {
+		return err
+	}
+
+	if repo.treeCache == nil {
+		repo.treeCache = repo.newTreeCache()
+	}
+
+	cleaned := make([]string, len(paths))
+	wanted := map[string]bool{}
+	for i, p := range paths {
+		p = strings.Trim(path.Clean("/"+p), "/")
+		cleaned[i] = p
+		for dir := p; dir != ""; dir = parentDir(dir) {
+			wanted[dir] = true
+		}
+	}
+
+	var batch *catFile
+	defer func() {
+		if batch != nil {
+			batch.Close()
+		}
+	}()
+
+	// objects read are reported as entries, as the total is not known
+	progress := repo.startProgress("Prefetch", 0)
+
+	read := func(oid string) ([]byte, error) {
+		if batch == nil {
+			var err error
+			batch, err = repo.startCatFile(repo.context())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		content := new(bytes.Buffer)
+		if err := batch.read(oid, func(string, int64) io.Writer { return content }); err != nil {
+			return nil, err
+		}
+
+		return content.Bytes(), progress.entry(int64(content.Len()))
+	}
+
+	// list trees level by level, from the root
+	type dir struct{ path, oid string }
+	level := []dir{{"", root}}
+	for len(level) > 0 {
+		next := []dir{}
+		for _, d := range level {
+			entries, ok := repo.treeCache.get(d.oid)
+			repo.observeCache("tree", ok)
+			if !ok {
+				content, err := read(d.oid)
+				if err != nil {
+					return err
+				}
+
+				parsed, err := parseTree(content, len(d.oid)/2)
+				if err != nil {
+					return err
+				}
+
+				list := make([]*treeEntry, len(parsed))
+				for i := range parsed {
+					parsed[i].name = repo.decodePath(parsed[i].name)
+					list[i] = &parsed[i]
+				}
+
+				entries = repo.newListing(list)
+				repo.treeCache.add(d.oid, entries)
+			}
+
+			for name, e := range entries {
+				p := path.Join(d.path, name)
+				if e.objType == objTypeDir && wanted[p] {
+					next = append(next, dir{p, e.sha1})
+				}
+			}
+		}
+		level = next
+	}
+
+	if repo.blobCache == nil {
+		return nil
+	}
+
+	for _, p := range cleaned {
+		dir, name := splitPath(p)
+		entries, ok := repo.cachedTree(dir)
+		if !ok {
+			continue
+		}
+
+		e, ok := entries[name]
+		if !ok || e.objType != objTypeRegular {
+			continue
+		}
+		if e = e.in(repo, dir); !repo.exposes(e) {
+			continue
+		}
+		if repo.maxFileSize > 0 && e.blobSize() > repo.maxFileSize {
+			continue
+		}
+		if _, ok := repo.blobCache.get(e.sha1); ok {
+			continue
+		}
+
+		content, err := read(e.sha1)
+		if err != nil {
+			return err
+		}
+		repo.blobCache.add(e.sha1, content)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestPrefetch(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a/b/c/file.txt", "file\n").
+		AddFile("a/b/other.txt", "other\n").
+		AddFile("x/y.txt", "y\n").
+		Commit("init")
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithBlobCache(NewBlobCache(1<<20)), WithDebugLog(20))
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Prefetch("a/b/c/file.txt", "/x/", "nonexistent/file"))
+
+	log := repo.DebugLog()
+	require.Len(t, log, 1, "rev-parse; cat-file --batch is not logged")
+	assert.Equal(t, 1, repo.blobCache.Len())
+
+	b, err := vfs.ReadFile(repo, "a/b/c/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "file\n", string(b))
+
+	entries, err := repo.ReadDir("x")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	ok, err := repo.Exists("a/b/other.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.Len(t, repo.DebugLog(), 1, "no git commands after prefetching")
+}
@@ -0,0 +1,65 @@
+package git
+
+import "io"
+
+// Progress is the state of a long-running operation reported to the
+// function given by WithProgress.
+type Progress struct {
+	Op      string // "Prefetch", "Archive" or "Sync"
+	Entries int    // entries processed so far
+	Total   int    // entries to be processed, or 0 if not known
+	Bytes   int64  // bytes written so far, or read into caches by Prefetch
+}
+
+// WithProgress makes Prefetch, Archive and Sync call fn as they process
+// entries. If fn returns an error, the operation stops and returns it,
+// which lets a server abort operations taking too long.
+func WithProgress(fn func(Progress) error) Option {
+	return func(repo *Repository) {
+		repo.progress = fn
+	}
+}
+
+// progressReporter accumulates the Progress of an operation.
+type progressReporter struct {
+	fn func(Progress) error
+	p  Progress
+}
+
+func (repo *Repository) startProgress(op string, total int) *progressReporter {
+	return &progressReporter{fn: repo.progress, p: Progress{Op: op, Total: total}}
+}
+
+// entry reports an entry processed, with n bytes written for it.
+func (r *progressReporter) entry(n int64) error {
+	r.p.Entries++
+	return r.wrote(n)
+}
+
+// wrote reports n bytes written.
+func (r *progressReporter) wrote(n int64) error {
+	r.p.Bytes += n
+	if r.fn == nil {
+		return nil
+	}
+
+	return r.fn(r.p)
+}
+
+// writer returns w reporting bytes written through it.
+func (r *progressReporter) writer(w io.Writer) io.Writer {
+	return &progressWriter{w: w, r: r}
+}
+
+type progressWriter struct {
+	w io.Writer
+	r *progressReporter
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	if perr := pw.r.wrote(int64(n)); err == nil {
+		err = perr
+	}
+	return n, err
+}
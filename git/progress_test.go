@@ -0,0 +1,73 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestWithProgress(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "aaaa\n").
+		AddFile("dir/b.txt", "bb\n").
+		AddSymlink("link", "a.txt").
+		Commit("initial")
+
+	var reports []Progress
+	record := func(p Progress) error {
+		reports = append(reports, p)
+		return nil
+	}
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithProgress(record), WithBlobCache(NewBlobCache(1<<20)))
+	require.NoError(t, err)
+
+	_, err = Sync(repo, t.TempDir())
+	require.NoError(t, err)
+	require.Len(t, reports, 3)
+	assert.Equal(t, Progress{Op: "Sync", Entries: 3, Total: 3, Bytes: 5 + 3 + 5}, reports[2])
+
+	reports = nil
+	var buf bytes.Buffer
+	require.NoError(t, repo.Archive(&buf, ArchiveOptions{}))
+	last := reports[len(reports)-1]
+	assert.Equal(t, "Archive", last.Op)
+	assert.Equal(t, 4, last.Entries)
+	assert.Equal(t, 4, last.Total)
+	assert.Equal(t, int64(buf.Len()), last.Bytes)
+
+	reports = nil
+	native, err := NewRepository("HEAD", r.GitDir, WithProgress(record), WithModTimeMode(ModTimeCommitterDate))
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, native.Archive(&buf, ArchiveOptions{}))
+	require.NotEmpty(t, reports)
+	assert.Equal(t, int64(buf.Len()), reports[len(reports)-1].Bytes)
+
+	reports = nil
+	require.NoError(t, repo.Prefetch("dir/b.txt"))
+	require.NotEmpty(t, reports)
+	assert.Equal(t, "Prefetch", reports[0].Op)
+
+	// aborts
+	tooLong := errors.New("too long")
+	repo, err = NewRepository("HEAD", r.GitDir, WithProgress(func(p Progress) error {
+		if p.Entries == 2 {
+			return tooLong
+		}
+		return nil
+	}))
+	require.NoError(t, err)
+
+	_, err = Sync(repo, t.TempDir())
+	assert.Equal(t, tooLong, err)
+
+	err = repo.Archive(io.Discard, ArchiveOptions{})
+	assert.Equal(t, tooLong, err)
+}
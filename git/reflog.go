@@ -0,0 +1,88 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReflogEntry is an update of a ref recorded in its reflog.
+type ReflogEntry struct {
+	Selector string    // e.g. "main@{2}", usable as a revision
+	Commit   string    // the commit the ref was updated to
+	Who      Signature // who updated the ref, and when
+	Message  string    // e.g. "commit: fix typo"
+}
+
+// Reflog returns the entries of the reflog of ref, such as "main" or
+// "HEAD", newest first. ref defaults to HEAD. Open the Repository at an
+// entry with At(e.Commit), or use ReflogAt for a point in time.
+func (repo *Repository) Reflog(ref string) ([]ReflogEntry, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	// with --date=unix, %gd is "<ref>@{<time>}"
+	out, err := repo.git("log", "--walk-reflogs", "--date=unix", "--format=%H%x00%gd%x00%gn%x00%ge%x00%gs", "--end-of-options", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := out.lines('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []ReflogEntry{}
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "\x00", 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("could not parse reflog line: %q", line)
+		}
+
+		_, sec, ok := strings.Cut(strings.TrimSuffix(fields[1], "}"), "@{")
+		if !ok {
+			return nil, fmt.Errorf("could not parse reflog selector: %q", fields[1])
+		}
+
+		who, err := parseSignature([]string{fields[2], fields[3], sec})
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, ReflogEntry{
+			Selector: fmt.Sprintf("%s@{%d}", ref, len(entries)),
+			Commit:   fields[0],
+			Who:      who,
+			Message:  fields[4],
+		})
+	}
+
+	return entries, nil
+}
+
+// ReflogAt returns a Repository pinned to the commit ref pointed to at t,
+// according to its reflog, sharing the configuration and the caches of
+// repo like At. It fails with ErrUnknownRevision if the reflog does not go
+// back to t.
+func (repo *Repository) ReflogAt(ref string, t time.Time) (*Repository, error) {
+	entries, err := repo.Reflog(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if !e.Who.When.After(t) {
+			return repo.At(e.Commit)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: reflog of %s does not go back to %s", ErrUnknownRevision, ref, t)
+}
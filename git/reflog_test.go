@@ -0,0 +1,56 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestReflog(t *testing.T) {
+	r := gittest.New(t).AddFile("file", "v1\n").Commit("first")
+	first := r.Head()
+	r.AddFile("file", "v2\n").Commit("second")
+	second := r.Head()
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	entries, err := repo.Reflog("main")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "main@{0}", entries[0].Selector)
+	assert.Equal(t, second, entries[0].Commit)
+	assert.Equal(t, "commit: second", entries[0].Message)
+	assert.Equal(t, "test", entries[0].Who.Name)
+	assert.True(t, gittest.CommitTime(2).Equal(entries[0].Who.When), entries[0].Who.When)
+
+	assert.Equal(t, "main@{1}", entries[1].Selector)
+	assert.Equal(t, first, entries[1].Commit)
+
+	byEntry, err := NewRepository(entries[1].Selector, r.GitDir)
+	require.NoError(t, err)
+	b, err := vfs.ReadFile(byEntry, "file")
+	require.NoError(t, err)
+	assert.Equal(t, "v1\n", string(b))
+
+	head, err := repo.Reflog("")
+	require.NoError(t, err)
+	assert.Equal(t, "HEAD@{0}", head[0].Selector)
+
+	at, err := repo.ReflogAt("main", gittest.CommitTime(1).Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, first, at.Revision)
+
+	at, err = repo.ReflogAt("main", gittest.CommitTime(2))
+	require.NoError(t, err)
+	assert.Equal(t, second, at.Revision)
+
+	_, err = repo.ReflogAt("main", gittest.Epoch)
+	assert.ErrorIs(t, err, ErrUnknownRevision)
+}
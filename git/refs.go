@@ -0,0 +1,66 @@
+package git
+
+import "strings"
+
+// Ref is a branch, tag or other reference of the repository.
+type Ref struct {
+	Name   string // full name, e.g. "refs/heads/main"
+	Commit string // the commit the ref points to, peeling annotated tags
+}
+
+// ShortName returns the name without "refs/heads/", "refs/tags/" or
+// "refs/remotes/".
+func (r Ref) ShortName() string {
+	for _, prefix := range []string{"refs/heads/", "refs/tags/", "refs/remotes/"} {
+		if strings.HasPrefix(r.Name, prefix) {
+			return strings.TrimPrefix(r.Name, prefix)
+		}
+	}
+
+	return r.Name
+}
+
+// Refs lists references matching patterns, which are for-each-ref patterns
+// such as "refs/tags/". Branches and tags are listed if no pattern is given.
+// Refs which do not point to commits are omitted.
+func (repo *Repository) Refs(patterns ...string) ([]Ref, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if len(patterns) == 0 {
+		patterns = []string{"refs/heads/", "refs/tags/"}
+	}
+
+	args := append([]string{"for-each-ref", "--format=%(objectname) %(objecttype) %(*objectname) %(*objecttype) %(refname)", "--end-of-options"}, patterns...)
+	out, err := repo.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := out.lines('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	refs := []Ref{}
+	for _, line := range lines {
+		// objectname objecttype peeledname peeledtype refname; the peeled
+		// ones are empty unless the ref is an annotated tag
+		fields := strings.SplitN(line, " ", 5)
+		if len(fields) != 5 {
+			continue
+		}
+
+		commit, objType := fields[0], fields[1]
+		if fields[2] != "" {
+			commit, objType = fields[2], fields[3]
+		}
+		if objType != "commit" {
+			continue
+		}
+
+		refs = append(refs, Ref{Name: fields[4], Commit: commit})
+	}
+
+	return refs, nil
+}
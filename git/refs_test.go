@@ -0,0 +1,37 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestRefs(t *testing.T) {
+	r := gittest.New(t).Commit("first").Tag("v1")
+	r.Git("tag", "-a", "-m", "annotated", "v2")
+	r.Git("branch", "topic/x")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	head, err := repo.resolveCommit("HEAD")
+	require.NoError(t, err)
+
+	refs, err := repo.Refs()
+	require.NoError(t, err)
+	assert.Equal(t, []Ref{
+		{Name: "refs/heads/main", Commit: head},
+		{Name: "refs/heads/topic/x", Commit: head},
+		{Name: "refs/tags/v1", Commit: head},
+		{Name: "refs/tags/v2", Commit: head},
+	}, refs)
+
+	assert.Equal(t, "topic/x", refs[1].ShortName())
+
+	refs, err = repo.Refs("refs/tags/")
+	require.NoError(t, err)
+	assert.Len(t, refs, 2)
+}
@@ -0,0 +1,203 @@
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// NewFromURL returns a Repository for the revision of the remote repository
+// at url, pinned to the commit the revision resolves to. A mirror clone of
+// the remote is kept under cacheDir: it is created on first use and fetched
+// when the revision is not found in it. A branch or tag is looked up on the
+// remote with ls-remote first, so that the clone is fetched only if it lacks
+// the commit the ref points to, and a stale clone is never served. Clones
+// are kept until evicted by WithCacheQuota or PruneCache.
+func NewFromURL(url, revision, cacheDir string, opts ...Option) (*Repository, error) {
+	if revision == "" {
+		revision = "HEAD"
+	}
+
+	dir := filepath.Join(cacheDir, cacheKey(url))
+
+	repo, err := NewRepository(revision, dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(cacheDir, 0777); err != nil {
+		return nil, err
+	}
+
+	// keeps the clone from being evicted meanwhile
+	unlock, err := lockCache(dir, false, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	commit, err := repo.updateMirror(url, revision, cacheDir, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.Revision = commit
+
+	if err := touchCache(dir); err != nil {
+		repo.debug("recording cache use failed", "error", err)
+	}
+	if repo.cacheQuota > 0 {
+		if err := PruneCache(cacheDir, repo.cacheQuota); err != nil {
+			repo.debug("pruning cache failed", "error", err)
+		}
+	}
+
+	return repo, nil
+}
+
+// updateMirror makes the clone of url at dir have revision, and returns
+// the commit it resolves to.
+func (repo *Repository) updateMirror(url, revision, cacheDir, dir string) (string, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := repo.cloneMirror(url, cacheDir, dir); err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		return "", err
+	} else if oid, ok := repo.remoteRef(revision); ok {
+		// the clone needs fetching only if it lacks where the ref points to
+		commit, err := repo.resolveCommit(oid)
+		if err != nil {
+			if _, err := repo.git("fetch", "--quiet", "--prune", "origin"); err != nil {
+				return "", err
+			}
+			return repo.resolveCommit(oid)
+		}
+		return commit, nil
+	}
+
+	commit, err := repo.resolveCommit(revision)
+	if err == nil {
+		return commit, nil
+	}
+
+	if _, err := repo.git("fetch", "--quiet", "--prune", "origin"); err != nil {
+		return "", err
+	}
+
+	commit, err = repo.resolveCommit(revision)
+	if err == nil {
+		return commit, nil
+	}
+
+	// may be a commit not pointed by any ref
+	if _, err := repo.git("fetch", "--quiet", "--end-of-options", "origin", revision); err != nil {
+		return "", err
+	}
+
+	return repo.resolveCommit(revision)
+}
+
+// cloneMirror clones url into dir. The clone is made in a temporary
+// directory under cacheDir and then moved, so that a concurrent user
+// never sees a half-done clone.
+func (repo *Repository) cloneMirror(url, cacheDir, dir string) error {
+	if err := os.MkdirAll(cacheDir, 0777); err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp(cacheDir, ".clone-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	noDir := repo.clone()
+	noDir.GitDir = ""
+	if _, err := noDir.git("clone", "--quiet", "--mirror", "--", url, tmp); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, dir); err != nil {
+		if _, statErr := os.Stat(dir); statErr == nil {
+			// cloned by someone else meanwhile
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// remoteRefPatterns are the refs a revision may name, in the order
+// rev-parse tries them.
+var remoteRefPatterns = []string{"%s", "refs/%s", "refs/tags/%s", "refs/heads/%s", "refs/remotes/%s", "refs/remotes/%s/HEAD"}
+
+// remoteRef returns the object ID of the ref of the remote "origin" named by
+// revision, as rev-parse would find it in the clone. It reports false if
+// revision is not a ref name, such as a commit ID or an expression, or if
+// the remote cannot be reached.
+func (repo *Repository) remoteRef(revision string) (string, bool) {
+	if !isRefName(revision) {
+		return "", false
+	}
+
+	out, err := repo.git("ls-remote", "--end-of-options", "origin", revision)
+	if err != nil {
+		repo.debug("ls-remote failed", "error", err)
+		return "", false
+	}
+
+	refs := map[string]string{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		if oid, ref, ok := strings.Cut(line, "\t"); ok {
+			refs[ref] = oid
+		}
+	}
+
+	for _, p := range remoteRefPatterns {
+		if oid, ok := refs[fmt.Sprintf(p, revision)]; ok {
+			return oid, true
+		}
+	}
+
+	return "", false
+}
+
+var rxObjectID = regexp.MustCompile(`^[0-9a-f]{4,64}$`)
+
+// isRefName reports whether revision may be a ref rather than an object
+// ID or an expression like "main~1".
+func isRefName(revision string) bool {
+	if rxObjectID.MatchString(revision) {
+		return false
+	}
+
+	return !strings.ContainsAny(revision, "~^:@{}*?[\\ ") && !strings.HasPrefix(revision, "-")
+}
+
+func (repo *Repository) resolveCommit(revision string) (string, error) {
+	return repo.resolveObject(revision, "commit")
+}
+
+var (
+	rxUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	rxUserinfo    = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*://)[^/@]*@`)
+)
+
+// cacheKey returns a directory name for the clone of url, which is
+// readable and does not collide with those of other URLs. Credentials in
+// url are left out of the readable part.
+func cacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	name := rxUnsafeChars.ReplaceAllString(rxUserinfo.ReplaceAllString(url, "$1"), "_")
+	if len(name) > 64 {
+		name = name[len(name)-64:]
+	}
+
+	return name + "-" + hex.EncodeToString(sum[:4]) + ".git"
+}
@@ -0,0 +1,112 @@
+package git
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultRetries      = 3
+	defaultRetryBackoff = 100 * time.Millisecond
+)
+
+// WithRetry makes git commands which fail transiently retried up to n
+// times, waiting backoff before the first retry and doubling it for each
+// next one. Transient failures are those caused by another git process
+// writing to the repository, such as a fetch or gc holding index.lock or
+// rewriting packed-refs, or repacking a packfile git is reading. By
+// default, commands are retried 3 times from 100ms; n of 0 disables it.
+func WithRetry(n int, backoff time.Duration) Option {
+	return func(repo *Repository) {
+		if n == 0 {
+			n = -1
+		}
+		repo.retries = n
+		repo.retryBackoff = backoff
+	}
+}
+
+func (repo *Repository) retryPolicy() (int, time.Duration) {
+	switch {
+	case repo.retries < 0:
+		return 0, 0
+	case repo.retries == 0:
+		return defaultRetries, defaultRetryBackoff
+	}
+
+	return repo.retries, repo.retryBackoff
+}
+
+// transientPatterns are the messages of git failing because of another git
+// process, matched case-insensitively.
+var transientPatterns = []string{
+	".lock': file exists",
+	"cannot lock ref",
+	"unable to lock",
+	"packed-refs",
+	"packfile", // removed by a repack while being read
+}
+
+// isTransient reports whether err is a failure of git which may succeed if
+// the command is run again.
+func isTransient(err error) bool {
+	var gitErr *GitError
+	if !errors.As(err, &gitErr) {
+		return false
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(gitErr.Err, &exitErr) {
+		// a packfile truncated by a concurrent repack while mapped
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() && ws.Signal() == syscall.SIGBUS {
+			return true
+		}
+	}
+
+	stderr := strings.ToLower(gitErr.Stderr)
+	for _, p := range transientPatterns {
+		if strings.Contains(stderr, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retry runs f until it succeeds, fails other than transiently or runs out
+// of retries. stdin, the input f gives to git, is rewound before each retry,
+// and is not retried with if it cannot be.
+func (repo *Repository) retry(stdin io.Reader, f func() (*output, error)) (*output, error) {
+	retries, backoff := repo.retryPolicy()
+
+	for i := 0; ; i++ {
+		out, err := f()
+		if err == nil || i >= retries || !isTransient(err) {
+			return out, err
+		}
+
+		if stdin != nil {
+			s, ok := stdin.(io.Seeker)
+			if !ok {
+				return out, err
+			}
+			if _, serr := s.Seek(0, io.SeekStart); serr != nil {
+				return out, err
+			}
+		}
+
+		repo.debug("retrying git", "error", err, "attempt", i+1)
+
+		t := time.NewTimer(backoff << i)
+		select {
+		case <-repo.context().Done():
+			t.Stop()
+			return out, err
+		case <-t.C:
+		}
+	}
+}
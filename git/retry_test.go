@@ -0,0 +1,137 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyGit writes a git wrapper which runs fail, a shell command, for the
+// first n invocations and the real git after.
+func flakyGit(t *testing.T, n int, fail string) (gitPath, counter string) {
+	realGit, err := exec.LookPath("git")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	gitPath = filepath.Join(dir, "git")
+	counter = filepath.Join(dir, "count")
+
+	script := fmt.Sprintf(`#!/bin/sh
+echo >> %[1]q
+if [ $(wc -l < %[1]q) -le %[2]d ]; then
+	%[3]s
+fi
+exec %[4]q "$@"
+`, counter, n, fail, realGit)
+	require.NoError(t, os.WriteFile(gitPath, []byte(script), 0755))
+
+	return gitPath, counter
+}
+
+func invocations(t *testing.T, counter string) int {
+	b, err := os.ReadFile(counter)
+	require.NoError(t, err)
+	return strings.Count(string(b), "\n")
+}
+
+// statCommands runs Stat of a file with a fresh Repository and returns the number
+// of git commands it took.
+func statCommands(t *testing.T, opts ...Option) int {
+	gitPath, counter := flakyGit(t, 0, ":")
+	repo := &Repository{GitDir: newFixture(t).GitDir}
+	WithGitPath(gitPath)(repo)
+	for _, opt := range opts {
+		opt(repo)
+	}
+
+	_, err := repo.Stat("git/git.go")
+	require.NoError(t, err)
+
+	return invocations(t, counter)
+}
+
+func TestWithRetry(t *testing.T) {
+	gitDir := newFixture(t).GitDir
+
+	lockFail := `echo "fatal: Unable to create '/repo/.git/index.lock': File exists." >&2; exit 128`
+
+	gitPath, counter := flakyGit(t, 2, lockFail)
+	repo := &Repository{GitDir: gitDir}
+	WithGitPath(gitPath)(repo)
+	WithRetry(3, time.Millisecond)(repo)
+
+	_, err := repo.Stat("git/git.go")
+	require.NoError(t, err)
+	assert.Equal(t, 2+statCommands(t), invocations(t, counter))
+
+	// gives up after the retries
+	gitPath, counter = flakyGit(t, 10, lockFail)
+	repo = &Repository{GitDir: gitDir}
+	WithGitPath(gitPath)(repo)
+	WithRetry(2, time.Millisecond)(repo)
+
+	_, err = repo.Stat("git/git.go")
+	assert.Error(t, err)
+	assert.Equal(t, 3, invocations(t, counter))
+
+	// other failures are not retried
+	gitPath, counter = flakyGit(t, 10, `echo "fatal: bad revision 'x'" >&2; exit 128`)
+	repo = &Repository{GitDir: gitDir}
+	WithGitPath(gitPath)(repo)
+
+	_, err = repo.Stat("git/git.go")
+	assert.Error(t, err)
+	assert.Equal(t, 1, invocations(t, counter))
+
+	// nor anything if disabled
+	gitPath, counter = flakyGit(t, 10, lockFail)
+	repo = &Repository{GitDir: gitDir}
+	WithGitPath(gitPath)(repo)
+	WithRetry(0, 0)(repo)
+
+	_, err = repo.Stat("git/git.go")
+	assert.Error(t, err)
+	assert.Equal(t, 1, invocations(t, counter))
+}
+
+func TestWithRetry_sigbus(t *testing.T) {
+	gitDir := newFixture(t).GitDir
+
+	gitPath, counter := flakyGit(t, 1, `kill -BUS $$`)
+	repo := &Repository{GitDir: gitDir}
+	WithGitPath(gitPath)(repo)
+	WithRetry(1, time.Millisecond)(repo)
+
+	_, err := repo.Stat("git/git.go")
+	require.NoError(t, err)
+	assert.Equal(t, 1+statCommands(t), invocations(t, counter))
+}
+
+func TestWithRetry_stdin(t *testing.T) {
+	gitDir := newFixture(t).GitDir
+
+	// fails the first check-ignore after reading its input
+	failed := filepath.Join(t.TempDir(), "failed")
+	gitPath, _ := flakyGit(t, 100, fmt.Sprintf(`case "$*" in *check-ignore*)
+		if [ ! -e %[1]q ]; then
+			touch %[1]q; cat > /dev/null
+			echo "error: cannot lock ref 'refs/heads/main'" >&2; exit 1
+		fi
+	esac`, failed))
+
+	repo := &Repository{GitDir: gitDir}
+	WithGitPath(gitPath)(repo)
+	WithRetry(1, time.Millisecond)(repo)
+
+	ignored, err := repo.Ignored("Cargo.lock", "git/git.go")
+	require.NoError(t, err)
+	assert.FileExists(t, failed)
+	assert.Equal(t, []string{"Cargo.lock"}, ignored)
+}
@@ -0,0 +1,43 @@
+package git
+
+import "strings"
+
+// revParse runs git rev-parse with args and returns the first line of its
+// output. Results are memoized until Refresh or SetRevision re-pins the
+// Repository, as revisions such as "HEAD" or branches may move only then
+// as far as the Repository is concerned.
+func (repo *Repository) revParse(args ...string) (string, error) {
+	key := strings.Join(args, "\x00")
+	if v, ok := repo.resolved[key]; ok {
+		return v, nil
+	}
+
+	out, err := repo.git(append([]string{"rev-parse"}, args...)...)
+	if err != nil {
+		return "", err
+	}
+
+	v, err := out.first()
+	if err != nil {
+		return "", err
+	}
+
+	if repo.resolved == nil {
+		repo.resolved = map[string]string{}
+	}
+	repo.resolved[key] = v
+
+	return v, nil
+}
+
+// resolveObject resolves rev, peeled to objType such as "commit" or
+// "tree", to an object ID.
+func (repo *Repository) resolveObject(rev, objType string) (string, error) {
+	return repo.revParse("--verify", "--end-of-options", rev+"^{"+objType+"}")
+}
+
+// resolvedObject returns what resolveObject returned if memoized.
+func (repo *Repository) resolvedObject(rev, objType string) (string, bool) {
+	v, ok := repo.resolved[strings.Join([]string{"--verify", "--end-of-options", rev + "^{" + objType + "}"}, "\x00")]
+	return v, ok
+}
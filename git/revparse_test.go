@@ -0,0 +1,56 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestRevParse_memoized(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "first\n").
+		Commit("first")
+
+	repo, err := NewRepository("main", r.GitDir, WithDebugLog(100))
+	require.NoError(t, err)
+
+	revParses := func() int {
+		n := 0
+		for _, e := range repo.DebugLog() {
+			if e.Args[0] == "rev-parse" {
+				n++
+			}
+		}
+		return n
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := repo.Stat("a.txt")
+		require.NoError(t, err)
+		_, err = repo.Stat("")
+		require.NoError(t, err)
+		_, err = repo.Attributes("a.txt")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, revParses())
+
+	_, err = repo.WithContext(context.Background()).Stat("a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, 1, revParses(), "views share resolutions")
+
+	// the branch moving is seen after SetRevision
+	r.AddFile("a.txt", "second\n").Commit("second")
+
+	fi, err := repo.Stat("a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), fi.Size())
+
+	require.NoError(t, repo.SetRevision("main"))
+	fi, err = repo.Stat("a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), fi.Size())
+}
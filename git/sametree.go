@@ -0,0 +1,32 @@
+package git
+
+// SameTree reports whether the entries at path of a and b have the same
+// object ID, that is, whether the (sub)tree is identical in content in the
+// revisions of a and b, without listing it. path may also name a file.
+// Callers can use this to skip work such as re-indexing when nothing
+// under path changed.
+func SameTree(a, b *Repository, path string) (bool, error) {
+	oidA, err := a.objectID(path)
+	if err != nil {
+		return false, err
+	}
+
+	oidB, err := b.objectID(path)
+	if err != nil {
+		return false, err
+	}
+
+	return oidA == oidB, nil
+}
+
+func (repo *Repository) objectID(path string) (string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	e, err := repo.lstat(path)
+	if err != nil {
+		return "", err
+	}
+
+	return e.sha1, nil
+}
@@ -0,0 +1,44 @@
+package git
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestSameTree(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("docs/index.md", "# docs\n").
+		AddFile("src/main.go", "package main\n").
+		Commit("first")
+	first := r.Head()
+	r.AddFile("src/main.go", "package main\n\nfunc main() {}\n").Commit("second")
+
+	a, err := NewRepository(first, r.GitDir)
+	require.NoError(t, err)
+	b, err := NewRepository("main", r.GitDir)
+	require.NoError(t, err)
+
+	for path, want := range map[string]bool{
+		"":              false,
+		"docs":          true,
+		"docs/index.md": true,
+		"src":           false,
+		"src/main.go":   false,
+	} {
+		same, err := SameTree(a, b, path)
+		require.NoError(t, err, path)
+		assert.Equal(t, want, same, path)
+	}
+
+	same, err := SameTree(a, a, "")
+	require.NoError(t, err)
+	assert.True(t, same)
+
+	_, err = SameTree(a, b, "nonexistent")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,84 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// WithMaxFileSize makes Open refuse blobs larger than n bytes with
+// a *FileTooLargeError, before reading any of their content.
+func WithMaxFileSize(n int64) Option {
+	return func(repo *Repository) {
+		repo.maxFileSize = n
+	}
+}
+
+// FileTooLargeError is returned by Open when the file exceeds the limit
+// given by WithMaxFileSize. It matches os.ErrPermission with errors.Is, as
+// serving the file is refused.
+type FileTooLargeError struct {
+	Path  string
+	Size  int64
+	Limit int64
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("file too large: %s (%d bytes, limit %d)", e.Path, e.Size, e.Limit)
+}
+
+func (e *FileTooLargeError) Is(target error) bool {
+	return target == os.ErrPermission
+}
+
+// lazySizes resolves the sizes of blobs of a directory listing by one git
+// cat-file --batch-check call, when one of them is first asked for.
+type lazySizes struct {
+	mu    sync.Mutex
+	oids  []string
+	sizes map[string]int64 // by object ID
+}
+
+// get returns the size of the blob oid, resolving sizes with repo if not yet.
+func (s *lazySizes) get(repo *Repository, oid string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sizes == nil {
+		s.sizes = map[string]int64{}
+		if err := s.resolve(repo); err != nil {
+			repo.debug("could not get sizes of blobs", "error", err)
+		}
+	}
+
+	return s.sizes[oid]
+}
+
+func (s *lazySizes) resolve(repo *Repository) error {
+	out, err := repo.gitInput(strings.NewReader(strings.Join(s.oids, "\n")+"\n"), "cat-file", "--batch-check=%(objectname) %(objectsize)")
+	if err != nil {
+		return err
+	}
+
+	lines, err := out.lines('\n')
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		oid, size, ok := strings.Cut(line, " ")
+		if !ok || size == "missing" {
+			continue
+		}
+
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			return fmt.Errorf("could not parse batch-check output: %q", line)
+		}
+		s.sizes[oid] = n
+	}
+
+	return nil
+}
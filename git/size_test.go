@@ -0,0 +1,62 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestWithMaxFileSize(t *testing.T) {
+	gitDir := newFixture(t).GitDir
+
+	repo, err := NewRepository("HEAD", gitDir, WithMaxFileSize(10))
+	require.NoError(t, err)
+
+	_, err = repo.Open("git/git.go")
+	require.Error(t, err)
+
+	var tooLarge *FileTooLargeError
+	require.True(t, errors.As(err, &tooLarge))
+	assert.Equal(t, "git/git.go", tooLarge.Path)
+	assert.Equal(t, int64(10), tooLarge.Limit)
+	assert.True(t, tooLarge.Size > 10)
+	assert.True(t, errors.Is(err, os.ErrPermission))
+
+	repo, err = NewRepository("HEAD", gitDir, WithMaxFileSize(1<<20))
+	require.NoError(t, err)
+
+	_, err = repo.Open("git/git.go")
+	assert.NoError(t, err)
+}
+
+func TestSize_lazy(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "a\n").
+		AddFile("b.txt", "bbbb\n").
+		AddSymlink("link", "a.txt").
+		AddFile("dir/c.txt", "c\n").
+		Commit("init")
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithDebugLog(10))
+	require.NoError(t, err)
+
+	entries, err := repo.ReadDir("")
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Len(t, repo.DebugLog(), 2, "only rev-parse and ls-tree")
+
+	sizes := map[string]int64{}
+	for _, fi := range entries {
+		sizes[fi.Name()] = fi.Size()
+	}
+	assert.Equal(t, map[string]int64{"a.txt": 2, "b.txt": 5, "link": 5, "dir": 0}, sizes)
+
+	log := repo.DebugLog()
+	require.Len(t, log, 3, "sizes resolved at once")
+	assert.Equal(t, "cat-file", log[2].Args[0])
+}
@@ -0,0 +1,216 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// WithZipSnapshot makes the Repository read the whole tree of the revision
+// into memory once and serve Stat, Lstat, ReadDir, Open, Readlink and
+// Exists from it without running git. This trades the time to start up and
+// memory for fast reads, which suits small repositories read at high
+// rates.
+//
+// Contents are those of the blobs, as without the snapshot. Entries are
+// dated by the committer date of the revision and have no object IDs, and
+// submodules are empty directories.
+func WithZipSnapshot() Option {
+	return func(repo *Repository) {
+		repo.useSnapshot = true
+	}
+}
+
+// treeSnapshot is the tree of a revision read into memory.
+type treeSnapshot struct {
+	rev     string
+	modTime time.Time
+	entries map[string]*snapshotEntry   // by path
+	dirs    map[string][]*snapshotEntry // children by path of directory, sorted
+}
+
+// implements os.FileInfo
+type snapshotEntry struct {
+	name    string
+	mode    os.FileMode
+	size    int64
+	modTime time.Time
+	data    []byte // nil for directories
+}
+
+func (e *snapshotEntry) Name() string       { return e.name }
+func (e *snapshotEntry) Size() int64        { return e.size }
+func (e *snapshotEntry) Mode() os.FileMode  { return e.mode }
+func (e *snapshotEntry) ModTime() time.Time { return e.modTime }
+func (e *snapshotEntry) IsDir() bool        { return e.mode.IsDir() }
+func (e *snapshotEntry) Sys() interface{}   { return nil }
+
+func (e *snapshotEntry) content() ([]byte, error) {
+	return e.data, nil
+}
+
+// loadSnapshot returns the snapshot of the revision, making it if not yet.
+func (repo *Repository) loadSnapshot() (*treeSnapshot, error) {
+	rev := repo.revision()
+	if repo.snapshot != nil && repo.snapshot.rev == rev {
+		return repo.snapshot, nil
+	}
+
+	modTime, err := repo.committerTime()
+	if err != nil {
+		return nil, err
+	}
+
+	listed, err := repo.lsTreeRecursive(true)
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := repo.startCatFile(repo.context())
+	if err != nil {
+		return nil, err
+	}
+	defer batch.Close()
+
+	snap := &treeSnapshot{
+		rev:     rev,
+		modTime: modTime,
+		entries: map[string]*snapshotEntry{},
+		dirs:    map[string][]*snapshotEntry{"": nil},
+	}
+
+	for _, le := range listed {
+		name := le.Path()
+		e := &snapshotEntry{
+			name:    le.name,
+			mode:    le.Mode(),
+			modTime: modTime,
+		}
+
+		switch le.objType {
+		case objTypeDir, objTypeGitlink:
+			e.mode = os.ModeDir | 0755
+			snap.dirs[name] = nil
+		default:
+			var buf bytes.Buffer
+			if err := batch.read(le.sha1, func(string, int64) io.Writer { return &buf }); err != nil {
+				return nil, err
+			}
+			e.data = buf.Bytes()
+			e.size = int64(len(e.data))
+		}
+
+		snap.entries[name] = e
+	}
+
+	for name, e := range snap.entries {
+		dir := parentDir(name)
+		snap.dirs[dir] = append(snap.dirs[dir], e)
+	}
+	for _, children := range snap.dirs {
+		sort.Slice(children, func(i, j int) bool { return children[i].name < children[j].name })
+	}
+
+	repo.snapshot = snap
+
+	return snap, nil
+}
+
+func (repo *Repository) snapshotLstat(name string) (*snapshotEntry, error) {
+	snap, err := repo.loadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return &snapshotEntry{mode: os.ModeDir | 0755, modTime: snap.modTime}, nil
+	}
+
+	e, ok := snap.entries[name]
+	if !ok || !repo.exposesPath(name, e.IsDir()) {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+
+	return e, nil
+}
+
+func (repo *Repository) snapshotStat(name string) (os.FileInfo, error) {
+	e, err := repo.snapshotLstat(name)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (repo *Repository) snapshotReadDir(name string) ([]os.FileInfo, error) {
+	snap, err := repo.loadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.Trim(path.Clean("/"+name), "/")
+	children, ok := snap.dirs[name]
+	if !ok || (name != "" && !repo.exposesPath(name, true)) {
+		if _, isFile := snap.entries[name]; isFile {
+			return nil, fmt.Errorf("not a directory: %s", name)
+		}
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: os.ErrNotExist}
+	}
+
+	entries := []os.FileInfo{}
+	for _, e := range children {
+		if repo.exposesPath(path.Join(name, e.name), e.IsDir()) {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, nil
+}
+
+func (repo *Repository) snapshotOpen(name string) (vfs.ReadSeekCloser, error) {
+	e, err := repo.snapshotLstat(name)
+	if err != nil {
+		return nil, err
+	}
+	if e.IsDir() {
+		return repo.openDirFile(name, e)
+	}
+	if !e.mode.IsRegular() {
+		return nil, fmt.Errorf("not a regular blob")
+	}
+	if repo.maxFileSize > 0 && e.size > repo.maxFileSize {
+		return nil, &FileTooLargeError{Path: name, Size: e.size, Limit: repo.maxFileSize}
+	}
+
+	content, err := e.content()
+	if err != nil {
+		return nil, err
+	}
+
+	if repo.observer != nil {
+		repo.observer.FileOpened(int64(len(content)))
+	}
+
+	return &blob{Reader: bytes.NewReader(content), observer: repo.observer}, nil
+}
+
+func (repo *Repository) snapshotReadlink(name string) (string, error) {
+	e, err := repo.snapshotLstat(name)
+	if err != nil {
+		return "", err
+	}
+	if e.mode&os.ModeSymlink == 0 {
+		return "", fmt.Errorf("not a symlink: %s", name)
+	}
+
+	target, err := e.content()
+	return string(target), err
+}
@@ -0,0 +1,85 @@
+package git
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestWithZipSnapshot(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README", "readme\n").
+		AddExecutable("bin/run", "#!/bin/sh\n").
+		AddSymlink("link", "README").
+		Commit("first")
+
+	o := &testObserver{}
+	repo, err := NewRepository("HEAD", r.GitDir, WithZipSnapshot(), WithObserver(o))
+	require.NoError(t, err)
+
+	entries, err := repo.ReadDir("")
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "README", entries[0].Name())
+	assert.Equal(t, "bin", entries[1].Name())
+	assert.True(t, entries[1].IsDir())
+	assert.Equal(t, os.ModeSymlink, entries[2].Mode().Type())
+	execs := len(o.execs)
+
+	fi, err := repo.Stat("bin/run")
+	require.NoError(t, err)
+	assert.Equal(t, "-rwxr-xr-x", fi.Mode().String())
+	assert.True(t, gittest.CommitTime(1).Equal(fi.ModTime()))
+
+	content, err := vfs.ReadFile(repo, "README")
+	require.NoError(t, err)
+	assert.Equal(t, "readme\n", string(content))
+
+	target, err := repo.Readlink("link")
+	require.NoError(t, err)
+	assert.Equal(t, "README", target)
+
+	ok, err := repo.Exists("bin/nonexistent")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = repo.Stat("nonexistent")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.Len(t, o.execs, execs, "no git after the snapshot is made")
+
+	r.AddFile("README", "updated\n").Commit("second")
+	require.NoError(t, repo.SetRevision("HEAD"))
+
+	content, err = vfs.ReadFile(repo, "README")
+	require.NoError(t, err)
+	assert.Equal(t, "updated\n", string(content))
+}
+
+func TestWithZipSnapshot_attributes(t *testing.T) {
+	r := gittest.New(t).
+		AddFile(".gitattributes", "* text eol=crlf\nsecret export-ignore\n").
+		AddFile("a.txt", "a\nb\n").
+		AddFile("secret", "kept\n").
+		Commit("first")
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithZipSnapshot())
+	require.NoError(t, err)
+
+	content, err := vfs.ReadFile(repo, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\n", string(content), "contents of the blob")
+
+	fi, err := repo.Stat("a.txt")
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, fi.Size())
+
+	ok, err := repo.Exists("secret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,112 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// WithSparsePatterns restricts the filesystem to the given directories,
+// in the way cone mode of git sparse-checkout does: everything under the
+// directories, the files directly under their parent directories, and the
+// files at the top level are exposed. Everything else is hidden from
+// ReadDir, Stat and Open. Empty patterns are ignored; "." or "/" exposes
+// the whole tree.
+func WithSparsePatterns(patterns ...string) Option {
+	return func(repo *Repository) {
+		for _, p := range patterns {
+			if p == "" {
+				continue
+			}
+			p = strings.Trim(p, "/")
+			if p == "" || p == "." {
+				// the whole tree
+				repo.sparseDirs = nil
+				return
+			}
+			repo.sparseDirs = append(repo.sparseDirs, path.Clean(p))
+		}
+	}
+}
+
+// WithSparseCheckout restricts the filesystem to the cone of the
+// sparse-checkout of the work tree, as WithSparsePatterns does, so that it
+// shows what is checked out. Nothing is restricted if sparse-checkout is
+// not enabled; NewRepository fails if it is enabled but not in cone mode.
+func WithSparseCheckout() Option {
+	return func(repo *Repository) {
+		repo.sparseCheckout = true
+	}
+}
+
+func (repo *Repository) loadSparseCheckout() error {
+	enabled, err := repo.configBool("core.sparseCheckout")
+	if err != nil || !enabled {
+		return err
+	}
+
+	cone, err := repo.configBool("core.sparseCheckoutCone")
+	if err != nil {
+		return err
+	}
+	if !cone {
+		return fmt.Errorf("sparse-checkout is not in cone mode")
+	}
+
+	out, err := repo.git("sparse-checkout", "list")
+	if err != nil {
+		return err
+	}
+
+	lines, err := out.lines('\n')
+	if err != nil {
+		return err
+	}
+
+	// only the files at the top level if no directories are listed
+	repo.sparseDirs = []string{}
+	for _, line := range lines {
+		if line != "" {
+			WithSparsePatterns(line)(repo)
+		}
+	}
+
+	return nil
+}
+
+func (repo *Repository) configBool(key string) (bool, error) {
+	out, err := repo.git("config", "--type=bool", "--default=false", "--get", key)
+	if err != nil {
+		return false, err
+	}
+
+	v, err := out.first()
+	return v == "true", err
+}
+
+func (repo *Repository) inSparseCone(name string, isDir bool) bool {
+	if repo.sparseDirs == nil || name == "" {
+		return true
+	}
+
+	parent := path.Dir(name)
+	for _, dir := range repo.sparseDirs {
+		if name == dir || strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+
+		if isDir {
+			// leading directories of the cone
+			if strings.HasPrefix(dir, name+"/") {
+				return true
+			}
+		} else {
+			// files directly under the leading directories
+			if parent == "." || strings.HasPrefix(dir, parent+"/") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
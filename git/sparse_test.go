@@ -0,0 +1,105 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestWithSparsePatterns(t *testing.T) {
+	gitDir := newFixture(t).GitDir
+
+	repo, err := NewRepository("HEAD", gitDir, WithSparsePatterns("git"))
+	require.NoError(t, err)
+
+	_, err = repo.Stat("git/git.go")
+	assert.NoError(t, err)
+
+	_, err = repo.Stat("README.md")
+	assert.NoError(t, err)
+
+	repo, err = NewRepository("HEAD", gitDir, WithSparsePatterns("docs/"))
+	require.NoError(t, err)
+
+	_, err = repo.Stat("git/git.go")
+	assert.Error(t, err)
+
+	_, err = repo.Stat("git")
+	assert.Error(t, err)
+
+	files, err := repo.ReadDir(".")
+	require.NoError(t, err)
+	for _, fi := range files {
+		assert.NotEqual(t, "git", fi.Name())
+	}
+
+	// empty patterns do not stop the rest from applying
+	repo, err = NewRepository("HEAD", gitDir, WithSparsePatterns("", "docs"))
+	require.NoError(t, err)
+
+	_, err = repo.Stat("git/git.go")
+	assert.Error(t, err)
+
+	repo, err = NewRepository("HEAD", gitDir, WithSparsePatterns("docs", "/"))
+	require.NoError(t, err)
+
+	_, err = repo.Stat("git/git.go")
+	assert.NoError(t, err)
+}
+
+func TestInSparseCone(t *testing.T) {
+	repo := Repository{sparseDirs: []string{"services/a"}}
+
+	tests := []struct {
+		name    string
+		isDir   bool
+		exposed bool
+	}{
+		{"README.md", false, true},
+		{"services", true, true},
+		{"services/go.mod", false, true},
+		{"services/a", true, true},
+		{"services/a/x/y.go", false, true},
+		{"services/b", true, false},
+		{"services/b/main.go", false, false},
+		{"docs", true, false},
+		{"docs/index.md", false, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.exposed, repo.inSparseCone(test.name, test.isDir), test.name)
+	}
+}
+
+func TestWithSparseCheckout(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("top", "").
+		AddFile("a/f", "").
+		AddFile("a/b/f", "").
+		AddFile("c/f", "").
+		Commit("first")
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithSparseCheckout())
+	require.NoError(t, err)
+
+	_, err = repo.Stat("c/f")
+	assert.NoError(t, err, "not sparse")
+
+	r.Git("sparse-checkout", "set", "a/b")
+
+	repo, err = NewRepository("HEAD", r.GitDir, WithSparseCheckout())
+	require.NoError(t, err)
+
+	for name, exposed := range map[string]bool{"top": true, "a/f": true, "a/b/f": true, "c/f": false, "c": false} {
+		_, err = repo.Stat(name)
+		assert.Equal(t, exposed, err == nil, name)
+	}
+
+	r.Git("sparse-checkout", "set", "--no-cone", "/c/")
+
+	_, err = NewRepository("HEAD", r.GitDir, WithSparseCheckout())
+	assert.Error(t, err)
+}
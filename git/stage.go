@@ -0,0 +1,84 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Stages of a path in the index, as in ":1:path". A path in conflict
+// during a merge has some of the stages 1 to 3 instead of stage 0.
+const (
+	StageBase   = 1 // the common ancestor
+	StageOurs   = 2 // HEAD
+	StageTheirs = 3 // the branch being merged
+)
+
+// StageView returns a Repository serving the tree of the index with the
+// paths in conflict at stage, so that base, ours and theirs versions of
+// files can be read as ":1:path", ":2:path" and ":3:path" name them.
+// Paths not in conflict are served as staged. Paths in conflict without
+// stage, such as one added on the other side only, are absent.
+//
+// The tree is built in a temporary index and served dated as the revision
+// of repo. It is left in the object database unreachable. The index read
+// is the one of WithIndexFile if given.
+func StageView(repo *Repository, stage int) (*Repository, error) {
+	if stage < StageBase || stage > StageTheirs {
+		return nil, fmt.Errorf("invalid stage: %d", stage)
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	out, err := repo.git("ls-files", "--stage", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := out.lines('\x00')
+	if err != nil {
+		return nil, err
+	}
+
+	// "<mode> <oid> <stage>\t<path>", sorted by path and then stage
+	var info bytes.Buffer
+	for _, line := range lines {
+		meta, name, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 3 {
+			continue
+		}
+
+		if fields[2] == "0" || fields[2] == strconv.Itoa(stage) {
+			fmt.Fprintf(&info, "%s %s 0\t%s\x00", fields[0], fields[1], name)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "vcsfs-stage-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	indexed := repo.withEnv("GIT_INDEX_FILE=" + filepath.Join(dir, "index"))
+
+	if _, err := indexed.gitInput(&info, "update-index", "-z", "--index-info"); err != nil {
+		return nil, err
+	}
+
+	out, err = indexed.git("write-tree")
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := out.first()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.treeView(tree, repo.revision()), nil
+}
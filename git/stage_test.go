@@ -0,0 +1,49 @@
+package git
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestStageView(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("conflict", "base\n").
+		AddFile("clean", "clean\n").
+		Commit("base")
+	r.Git("checkout", "--quiet", "-b", "theirs")
+	r.AddFile("conflict", "theirs\n").AddFile("added", "added\n").Commit("theirs")
+	r.Git("checkout", "--quiet", "main")
+	r.AddFile("conflict", "ours\n").Commit("ours")
+	r.Git("checkout", "--", ".")
+
+	// fails with the conflict
+	cmd := exec.Command("git", "-C", r.Dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "merge", "--quiet", "theirs")
+	require.Error(t, cmd.Run())
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	read := func(fs vfs.FileSystem, name string) string {
+		b, err := vfs.ReadFile(fs, name)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	for stage, want := range map[int]string{StageBase: "base\n", StageOurs: "ours\n", StageTheirs: "theirs\n"} {
+		view, err := StageView(repo, stage)
+		require.NoError(t, err)
+		assert.Equal(t, want, read(view, "conflict"), "stage %d", stage)
+		assert.Equal(t, "clean\n", read(view, "clean"))
+		assert.Equal(t, "added\n", read(view, "added"), "merged cleanly")
+		assert.Equal(t, r.Git("show", ":"+string(rune('0'+stage))+":conflict")+"\n", read(view, "conflict"))
+	}
+
+	_, err = StageView(repo, 0)
+	assert.Error(t, err)
+}
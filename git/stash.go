@@ -0,0 +1,76 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Stash returns a Repository serving the stash entry n, stash@{n}, as the
+// work tree was when it was stashed. A stash entry is a merge commit of
+// the work tree whose second parent holds the index, and whose third
+// parent, made by git stash --include-untracked, holds untracked files;
+// these are served along with the tracked ones. Without untracked files,
+// the Repository is pinned to the stash commit, which a Repository with
+// the revision "stash@{n}" serves as well.
+//
+// With untracked files, their tree is combined with that of the stash
+// commit in a temporary index, and the resulting tree is served, dated as
+// the stash commit. The tree is left in the object database unreachable.
+func Stash(repo *Repository, n int) (*Repository, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	commit, err := repo.resolveCommit(fmt.Sprintf("stash@{%d}", n))
+	if err != nil {
+		return nil, err
+	}
+
+	// HEAD, index and untracked files
+	parents, err := repo.parents(commit)
+	if err != nil {
+		return nil, err
+	}
+	if len(parents) < 2 {
+		return nil, fmt.Errorf("not a stash commit: %s", commit)
+	}
+
+	if len(parents) < 3 {
+		if repo.treeCache == nil {
+			repo.treeCache = repo.newTreeCache()
+		}
+
+		view := repo.clone()
+		view.Revision = commit
+		view.autoFetchInterval = 0
+		view.tracking = ""
+		view.treeCache = repo.treeCache
+		return view, nil
+	}
+
+	dir, err := os.MkdirTemp("", "vcsfs-stash-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	indexed := repo.withEnv("GIT_INDEX_FILE=" + filepath.Join(dir, "index"))
+
+	// without -m, the trees are overlaid: untracked files are added to
+	// those of the work tree
+	if _, err := indexed.git("read-tree", "--end-of-options", commit+"^{tree}", parents[2]+"^{tree}"); err != nil {
+		return nil, err
+	}
+
+	out, err := indexed.git("write-tree")
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := out.first()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.treeView(tree, commit), nil
+}
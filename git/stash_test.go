@@ -0,0 +1,60 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestStash(t *testing.T) {
+	r := gittest.New(t).AddFile("file", "committed\n").Commit("init")
+	r.Git("checkout", "--", ".")
+
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(r.Dir, name), []byte(content), 0644))
+	}
+
+	write("file", "first stash\n")
+	r.Git("stash", "push", "--quiet")
+
+	write("file", "second stash\n")
+	write("untracked", "untracked\n")
+	r.Git("stash", "push", "--quiet", "--include-untracked")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	read := func(fs vfs.FileSystem, name string) string {
+		b, err := vfs.ReadFile(fs, name)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	latest, err := Stash(repo, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "second stash\n", read(latest, "file"))
+	assert.Equal(t, "untracked\n", read(latest, "untracked"), "from the third parent")
+
+	older, err := Stash(repo, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "first stash\n", read(older, "file"))
+	ok, err := older.Exists("untracked")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, r.Git("rev-parse", "stash@{1}"), older.Revision)
+
+	byRev, err := NewRepository("stash@{1}", r.GitDir)
+	require.NoError(t, err)
+	assert.Equal(t, "first stash\n", read(byRev, "file"))
+
+	assert.Equal(t, "committed\n", read(repo, "file"))
+
+	_, err = Stash(repo, 2)
+	assert.ErrorIs(t, err, ErrUnknownRevision)
+}
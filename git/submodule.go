@@ -0,0 +1,189 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WithRecurseSubmodules makes Walk, Archive and Sync descend into
+// submodules at the commits recorded in the tree, so that they give what
+// a checkout with --recurse-submodules contains, instead of empty
+// directories. The repositories of submodules are looked up where git
+// submodule update clones them, under "modules" in the git directory, by
+// the names given in .gitmodules of the revision; a submodule not cloned
+// there is an error.
+//
+// Submodules are read with the settings of the Repository, except that
+// filters apply to paths from the top of the superproject and entries of
+// submodules are dated by the committer dates of their commits.
+func WithRecurseSubmodules() Option {
+	return func(repo *Repository) {
+		repo.recurseSubmodules = true
+	}
+}
+
+// submodule returns a Repository of the submodule at dir, pinned to
+// commit.
+func (repo *Repository) submodule(dir, commit string) (*Repository, error) {
+	name, err := repo.submoduleName(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !validSubmoduleName(name) {
+		return nil, fmt.Errorf("submodule %s has an invalid name: %q", dir, name)
+	}
+
+	gitDir := filepath.Join(repo.GitDir, "modules", filepath.FromSlash(name))
+	if _, err := os.Stat(gitDir); err != nil {
+		return nil, fmt.Errorf("submodule %s is not cloned: %w", dir, err)
+	}
+
+	sub := repo.clone()
+	sub.GitDir = gitDir
+	sub.Revision = commit
+	sub.ModTimeMode = ModTimeCommitterDate
+	sub.indexFile = ""
+	sub.autoFetchInterval = 0
+	sub.tracking = ""
+	sub.sparseDirs, sub.sparseCheckout = nil, false
+	sub.includes, sub.excludes = nil, nil
+
+	return sub, nil
+}
+
+// submoduleName returns the name of the submodule at dir in .gitmodules of
+// the revision, which is dir itself unless it was moved.
+func (repo *Repository) submoduleName(dir string) (string, error) {
+	out, err := repo.git("config", "-z", "--blob", repo.revision()+":.gitmodules", "--get-regexp", `^submodule\..*\.path$`)
+	if isExitCode(err, 1) {
+		return dir, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	records, err := out.lines('\x00')
+	if err != nil {
+		return "", err
+	}
+
+	// "submodule.<name>.path\n<path>"
+	for _, rec := range records {
+		key, value, ok := strings.Cut(rec, "\n")
+		if ok && path.Clean(value) == dir {
+			return strings.TrimSuffix(strings.TrimPrefix(key, "submodule."), ".path"), nil
+		}
+	}
+
+	return dir, nil
+}
+
+// validSubmoduleName reports whether name is safe to look up under
+// "modules" in the git directory, as check_submodule_name of git does: it
+// must not be empty nor absolute, nor have ".." components, which would
+// let a repository point into other repositories on the disk.
+func validSubmoduleName(name string) bool {
+	if name == "" || filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return false
+	}
+
+	for _, elem := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if elem == ".." {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkoutEntries is lsTreeRecursive descending into submodules if
+// WithRecurseSubmodules is given. Entries of submodules have the paths
+// from the top of repo and belong to the Repositories of the submodules.
+// With trees, submodules are listed as directories.
+func (repo *Repository) checkoutEntries(trees bool) ([]*treeEntry, error) {
+	entries, err := repo.lsTreeRecursive(trees)
+	if err != nil || !repo.recurseSubmodules {
+		return entries, err
+	}
+
+	all := make([]*treeEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.objType != objTypeGitlink {
+			all = append(all, e)
+			continue
+		}
+
+		dir := e.Path()
+		sub, err := repo.submodule(dir, e.sha1)
+		if err != nil {
+			return nil, err
+		}
+
+		subEntries, err := sub.checkoutEntries(trees)
+		if err != nil {
+			return nil, err
+		}
+
+		if trees {
+			d := *e
+			d.objType, d.mode = objTypeDir, 0
+			d.repo = sub
+			all = append(all, &d)
+		}
+		for _, se := range subEntries {
+			se.parent = path.Join(dir, se.parent)
+			all = append(all, se)
+		}
+	}
+
+	return all, nil
+}
+
+// catFiles reads objects of entries with a cat-file process for each
+// Repository they belong to, which differs for entries of submodules.
+type catFiles struct {
+	repo    *Repository
+	batches map[*Repository]*catFile
+}
+
+func (repo *Repository) newCatFiles() *catFiles {
+	return &catFiles{repo: repo, batches: map[*Repository]*catFile{}}
+}
+
+// of returns the cat-file process for e.
+func (c *catFiles) of(e *treeEntry) (*catFile, error) {
+	r := e.repo
+	if r == nil {
+		r = c.repo
+	}
+
+	if batch, ok := c.batches[r]; ok {
+		return batch, nil
+	}
+
+	batch, err := r.startCatFile(c.repo.context())
+	if err != nil {
+		return nil, err
+	}
+	c.batches[r] = batch
+
+	return batch, nil
+}
+
+func (c *catFiles) Close() {
+	for _, batch := range c.batches {
+		batch.Close()
+	}
+}
+
+// submoduleInfo is a submodule as the directory Walk descends into.
+type submoduleInfo struct {
+	os.FileInfo
+}
+
+func (fi submoduleInfo) Mode() os.FileMode { return os.ModeDir | 0755 }
+func (fi submoduleInfo) IsDir() bool       { return true }
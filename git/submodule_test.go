@@ -0,0 +1,129 @@
+package git
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestRecurseSubmodules(t *testing.T) {
+	sub := gittest.New(t).
+		AddFile("lib.go", "package lib\n").
+		AddFile("internal/x.go", "package internal\n").
+		Commit("lib")
+
+	r := gittest.New(t).
+		AddFile("main.go", "package main\n").
+		AddFile(".gitmodules", "[submodule \"libname\"]\n\tpath = vendor/lib\n\turl = https://example.com/lib.git\n").
+		AddSubmodule("vendor/lib", sub.Head()).
+		Commit("with submodule")
+
+	// where git submodule update clones it
+	out, err := exec.Command("git", "clone", "--quiet", "--bare", sub.GitDir, filepath.Join(r.GitDir, "modules", "libname")).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithRecurseSubmodules(), WithModTimeMode(ModTimeCommitterDate))
+	require.NoError(t, err)
+
+	t.Run("Walk", func(t *testing.T) {
+		var paths []string
+		err := repo.Walk("", WalkOptions{}, func(path string, fi os.FileInfo, err error) error {
+			require.NoError(t, err)
+			if path == "vendor/lib" {
+				assert.True(t, fi.IsDir())
+			}
+			paths = append(paths, path)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{".", ".gitmodules", "main.go", "vendor", "vendor/lib", "vendor/lib/internal", "vendor/lib/internal/x.go", "vendor/lib/lib.go"}, paths)
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, repo.Archive(&buf, ArchiveOptions{}))
+
+		files := map[string]string{}
+		tr := tar.NewReader(&buf)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			b, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			files[hdr.Name] = string(b)
+		}
+
+		assert.Equal(t, map[string]string{
+			".gitmodules":              files[".gitmodules"],
+			"main.go":                  "package main\n",
+			"vendor/":                  "",
+			"vendor/lib/":              "",
+			"vendor/lib/internal/":     "",
+			"vendor/lib/internal/x.go": "package internal\n",
+			"vendor/lib/lib.go":        "package lib\n",
+		}, files)
+	})
+
+	t.Run("Sync", func(t *testing.T) {
+		dst := t.TempDir()
+		report, err := Sync(repo, dst)
+		require.NoError(t, err)
+		assert.Equal(t, []string{".gitmodules", "main.go", "vendor/lib/internal/x.go", "vendor/lib/lib.go"}, report.Written)
+
+		b, err := os.ReadFile(filepath.Join(dst, "vendor/lib/lib.go"))
+		require.NoError(t, err)
+		assert.Equal(t, "package lib\n", string(b))
+	})
+
+	t.Run("not cloned", func(t *testing.T) {
+		r := gittest.New(t).AddSubmodule("lib", sub.Head()).Commit("uncloned")
+		repo, err := NewRepository("HEAD", r.GitDir, WithRecurseSubmodules())
+		require.NoError(t, err)
+		assert.ErrorIs(t, repo.Archive(io.Discard, ArchiveOptions{}), os.ErrNotExist)
+	})
+
+	t.Run("name escaping modules", func(t *testing.T) {
+		r := gittest.New(t).
+			AddFile(".gitmodules", "[submodule \"../../other.git\"]\n\tpath = lib\n\turl = https://example.com/lib.git\n").
+			AddSubmodule("lib", sub.Head()).
+			Commit("hostile")
+
+		// a repository where the name would lead
+		out, err := exec.Command("git", "clone", "--quiet", "--bare", sub.GitDir, filepath.Join(r.GitDir, "modules", "../../other.git")).CombinedOutput()
+		require.NoError(t, err, string(out))
+
+		repo, err := NewRepository("HEAD", r.GitDir, WithRecurseSubmodules())
+		require.NoError(t, err)
+		err = repo.Archive(io.Discard, ArchiveOptions{})
+		assert.ErrorContains(t, err, "invalid name")
+	})
+}
+
+func TestValidSubmoduleName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"lib":           true,
+		"vendor/lib":    true,
+		"lib..x":        true,
+		"":              false,
+		"/etc":          false,
+		"..":            false,
+		"../other":      false,
+		"a/../../b":     false,
+		"a\\..\\b":      false,
+		"vendor/lib/..": false,
+	} {
+		assert.Equal(t, valid, validSubmoduleName(name), name)
+	}
+}
@@ -0,0 +1,147 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// SymlinkPolicy specifies how Stat and Open treat symbolic links.
+type SymlinkPolicy int
+
+const (
+	// SymlinkNoFollow does not follow links: Stat is the same as Lstat
+	// (default).
+	SymlinkNoFollow SymlinkPolicy = iota
+	// SymlinkFollow follows links within the tree. A link pointing outside
+	// it, absolute or with ".." past the root, is returned unresolved.
+	SymlinkFollow
+	// SymlinkReject follows links within the tree, and fails with
+	// ErrSymlinkEscape on a link pointing outside it.
+	SymlinkReject
+)
+
+// ErrSymlinkEscape is returned under SymlinkReject for a path through a
+// symbolic link pointing outside the tree.
+var ErrSymlinkEscape = errors.New("symbolic link points outside the tree")
+
+// maxSymlinks is the number of links followed in resolving a path before
+// giving up with ELOOP, as Linux does.
+const maxSymlinks = 40
+
+// WithSymlinkPolicy sets how Stat and Open treat symbolic links, which are
+// not followed by default. Lstat and ReadDir never follow them.
+func WithSymlinkPolicy(policy SymlinkPolicy) Option {
+	return func(repo *Repository) {
+		repo.symlinkPolicy = policy
+	}
+}
+
+// readlinkCache caches the target of a symbolic link of a cached listing,
+// as read by readlink and not resolved any further: following it is left to
+// follow. It is shared by the copies of the entry, so that a link read
+// often, such as "current -> releases/v3", costs a git call only once for
+// each tree it is in.
+type readlinkCache struct {
+	mu     sync.Mutex
+	target string
+	read   bool
+}
+
+// readlink returns the target of the symbolic link e.
+func (repo *Repository) readlink(e *treeEntry) (string, error) {
+	if e.link != nil {
+		e.link.mu.Lock()
+		defer e.link.mu.Unlock()
+
+		if e.link.read {
+			return e.link.target, nil
+		}
+	}
+
+	out, err := repo.git("cat-file", "blob", e.sha1)
+	if err != nil {
+		return "", err
+	}
+	target := out.String()
+
+	if e.link != nil {
+		e.link.target, e.link.read = target, true
+	}
+
+	return target, nil
+}
+
+// follow returns the entry at name with the symbolic links on the way
+// resolved by the policy, named as name.
+func (repo *Repository) follow(name string) (*treeEntry, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+
+	var (
+		resolved string   // with no links
+		rest     []string // components yet to resolve
+		e        *treeEntry
+		links    int
+	)
+	if name != "" {
+		rest = strings.Split(name, "/")
+	}
+
+	for len(rest) > 0 {
+		next := path.Join(resolved, rest[0])
+		rest = rest[1:]
+
+		var err error
+		e, err = repo.lstat(next)
+		if err != nil {
+			return nil, err
+		}
+		if e.objType != objTypeSymlink {
+			resolved = next
+			continue
+		}
+
+		if links++; links > maxSymlinks {
+			return nil, &os.PathError{Op: "stat", Path: name, Err: syscall.ELOOP}
+		}
+
+		target, err := repo.readlink(e)
+		if err != nil {
+			return nil, err
+		}
+
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(next), target)
+		}
+		if path.IsAbs(target) || target == ".." || strings.HasPrefix(target, "../") {
+			if repo.symlinkPolicy == SymlinkReject {
+				return nil, &os.PathError{Op: "stat", Path: name, Err: ErrSymlinkEscape}
+			}
+			if len(rest) > 0 {
+				return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+			}
+			break
+		}
+
+		resolved, e = "", nil
+		if target != "." {
+			rest = append(strings.Split(target, "/"), rest...)
+		}
+	}
+
+	if e == nil {
+		root, err := repo.lstat("")
+		if err != nil || name == "" {
+			return root, err
+		}
+		e = root
+	}
+
+	dir, base := splitPath(name)
+	c := e.in(repo, dir)
+	c.name = base
+	return c, nil
+}
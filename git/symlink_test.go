@@ -0,0 +1,111 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestReadlink_memoized(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("releases/v3/app", "app\n").
+		AddSymlink("current", "releases/v3").
+		Commit("v3")
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithDebugLog(100))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		target, err := repo.Readlink("current")
+		require.NoError(t, err)
+		assert.Equal(t, "releases/v3", target)
+	}
+
+	view, err := repo.At("HEAD")
+	require.NoError(t, err)
+	target, err := view.Readlink("current")
+	require.NoError(t, err)
+	assert.Equal(t, "releases/v3", target)
+
+	catFiles := 0
+	for _, e := range repo.DebugLog() {
+		if e.Args[0] == "cat-file" {
+			catFiles++
+		}
+	}
+	assert.Equal(t, 1, catFiles, "read once for the tree")
+}
+
+func TestWithSymlinkPolicy(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("releases/v3/app", "app\n").
+		AddSymlink("releases/latest", "v3").
+		AddSymlink("current", "releases/latest").
+		AddSymlink("top", ".").
+		AddSymlink("up", "../outside").
+		AddSymlink("abs", "/etc/passwd").
+		AddSymlink("loop", "loop").
+		Commit("v3")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	fi, err := repo.Stat("current")
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSymlink, fi.Mode()&os.ModeSymlink, "not followed by default")
+
+	t.Run("follow", func(t *testing.T) {
+		repo, err := NewRepository("HEAD", r.GitDir, WithSymlinkPolicy(SymlinkFollow))
+		require.NoError(t, err)
+
+		fi, err := repo.Stat("current")
+		require.NoError(t, err)
+		assert.True(t, fi.IsDir())
+		assert.Equal(t, "current", fi.Name())
+
+		fi, err = repo.Stat("top/current/app")
+		require.NoError(t, err)
+		assert.True(t, fi.Mode().IsRegular())
+		assert.Equal(t, "app", fi.Name())
+
+		content, err := vfs.ReadFile(repo, "current/app")
+		require.NoError(t, err)
+		assert.Equal(t, "app\n", string(content))
+
+		for _, name := range []string{"up", "abs"} {
+			fi, err := repo.Stat(name)
+			require.NoError(t, err, name)
+			assert.Equal(t, os.ModeSymlink, fi.Mode()&os.ModeSymlink, "%s left unresolved", name)
+		}
+
+		_, err = repo.Stat("up/file")
+		assert.True(t, errors.Is(err, os.ErrNotExist), "%v", err)
+
+		_, err = repo.Stat("loop")
+		assert.True(t, errors.Is(err, syscall.ELOOP), "%v", err)
+
+		fi, err = repo.Lstat("current")
+		require.NoError(t, err)
+		assert.Equal(t, os.ModeSymlink, fi.Mode()&os.ModeSymlink)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		repo, err := NewRepository("HEAD", r.GitDir, WithSymlinkPolicy(SymlinkReject))
+		require.NoError(t, err)
+
+		_, err = repo.Stat("current/app")
+		assert.NoError(t, err)
+
+		for _, name := range []string{"up", "abs", "up/file"} {
+			_, err := repo.Stat(name)
+			assert.True(t, errors.Is(err, ErrSymlinkEscape), "%s: %v", name, err)
+		}
+	})
+}
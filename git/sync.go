@@ -0,0 +1,257 @@
+package git
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// SyncReport is the result of Sync. Paths are slash-separated and relative
+// to the destination directory.
+type SyncReport struct {
+	Written     []string // files and symlinks created or rewritten
+	Removed     []string // entries not in the tree
+	ModeChanged []string // files whose executable bit was fixed
+	Unchanged   int
+}
+
+// Sync makes the directory dst a copy of the tree of the revision of repo,
+// like rsync --delete. Files of the same size and object ID as in the tree
+// are left untouched, so that syncing repeatedly only writes what changed.
+// Anything not in the tree is removed, except the .git directory at the top
+// and the contents of submodules, which are created as empty directories
+// unless WithRecurseSubmodules is given.
+func Sync(repo *Repository, dst string) (_ *SyncReport, err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	defer repo.startSpan("Sync", "")(&err)
+
+	entries, err := repo.checkoutEntries(false)
+	if err != nil {
+		return nil, err
+	}
+
+	want := map[string]*treeEntry{}
+	dirs := map[string]bool{}
+	for _, e := range entries {
+		if !repo.exposes(e) {
+			continue
+		}
+
+		want[e.Path()] = e
+		for dir := e.parent; dir != ""; dir = parentDir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return nil, err
+	}
+
+	report := &SyncReport{}
+
+	err = filepath.WalkDir(dst, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dst, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == "." {
+			return nil
+		}
+		if rel == ".git" && d.IsDir() {
+			return filepath.SkipDir
+		}
+
+		if d.IsDir() && dirs[rel] {
+			return nil
+		}
+
+		if e, ok := want[rel]; ok && syncKind(e) == d.Type() {
+			if d.IsDir() {
+				// a submodule
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+		report.Removed = append(report.Removed, rel)
+
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	batches := repo.newCatFiles()
+	defer batches.Close()
+
+	names := make([]string, 0, len(want))
+	for name := range want {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	progress := repo.startProgress("Sync", len(names))
+
+	for _, name := range names {
+		e := want[name]
+		p := filepath.Join(dst, filepath.FromSlash(name))
+		var written int64
+
+		batch, err := batches.of(e)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return nil, err
+		}
+
+		switch e.objType {
+		case objTypeGitlink:
+			if err := os.MkdirAll(p, 0755); err != nil {
+				return nil, err
+			}
+
+		case objTypeSymlink:
+			target := new(bytes.Buffer)
+			if err := batch.read(e.sha1, func(string, int64) io.Writer { return target }); err != nil {
+				return nil, err
+			}
+
+			if current, err := os.Readlink(p); err == nil && current == target.String() {
+				report.Unchanged++
+				break
+			}
+
+			if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			if err := os.Symlink(target.String(), p); err != nil {
+				return nil, err
+			}
+			report.Written = append(report.Written, name)
+			written = int64(target.Len())
+
+		case objTypeRegular:
+			perm := os.FileMode(0644)
+			if e.mode&0100 != 0 {
+				perm = 0755
+			}
+
+			same, fi, err := sameBlob(p, e)
+			if err != nil {
+				return nil, err
+			}
+
+			if !same {
+				if err := writeBlob(batch, e, p, perm); err != nil {
+					return nil, err
+				}
+				report.Written = append(report.Written, name)
+				written = e.size
+			} else if fi.Mode()&0100 != perm&0100 {
+				if err := os.Chmod(p, fi.Mode().Perm()&^0111|perm&0111); err != nil {
+					return nil, err
+				}
+				report.ModeChanged = append(report.ModeChanged, name)
+			} else {
+				report.Unchanged++
+			}
+		}
+
+		if err := progress.entry(written); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// syncKind returns the type of the file Sync creates for e.
+func syncKind(e *treeEntry) fs.FileMode {
+	switch e.objType {
+	case objTypeGitlink:
+		return fs.ModeDir
+	case objTypeSymlink:
+		return fs.ModeSymlink
+	}
+	return 0
+}
+
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// sameBlob reports whether the regular file at p has the content of e,
+// comparing sizes first and then object IDs.
+func sameBlob(p string, e *treeEntry) (bool, os.FileInfo, error) {
+	fi, err := os.Lstat(p)
+	if os.IsNotExist(err) {
+		return false, nil, nil
+	} else if err != nil {
+		return false, nil, err
+	}
+
+	if !fi.Mode().IsRegular() || fi.Size() != e.size {
+		return false, fi, nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return false, nil, err
+	}
+	defer f.Close()
+
+	h := newObjectHash(e.sha1, "blob", fi.Size())
+	if _, err := io.Copy(h, f); err != nil {
+		return false, nil, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)) == e.sha1, fi, nil
+}
+
+// writeBlob replaces the file at p with the content of e atomically.
+func writeBlob(batch *catFile, e *treeEntry, p string, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(p), ".vcsfs-sync-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = batch.read(e.sha1, func(string, int64) io.Writer { return f })
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), p)
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestSync(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("README", "readme\n").
+		AddFile("dir/a.txt", "a\n").
+		AddExecutable("bin/run", "#!/bin/sh\n").
+		AddSymlink("link", "dir/a.txt").
+		AddSubmodule("vendor/lib", "0123456789012345678901234567890123456789").
+		Commit("v1").
+		Tag("v1")
+
+	dst := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dst, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "stale"), []byte("stale"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dst, "README"), 0755))
+
+	repo, err := NewRepository("v1", r.GitDir)
+	require.NoError(t, err)
+
+	report, err := Sync(repo, dst)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README", "bin/run", "dir/a.txt", "link"}, report.Written)
+	assert.ElementsMatch(t, []string{"README", "stale"}, report.Removed)
+
+	content, err := os.ReadFile(filepath.Join(dst, "dir/a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a\n", string(content))
+
+	fi, err := os.Stat(filepath.Join(dst, "bin/run"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+
+	target, err := os.Readlink(filepath.Join(dst, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "dir/a.txt", target)
+
+	assert.DirExists(t, filepath.Join(dst, "vendor/lib"))
+	assert.DirExists(t, filepath.Join(dst, ".git"))
+
+	report, err = Sync(repo, dst)
+	require.NoError(t, err)
+	assert.Empty(t, report.Written)
+	assert.Empty(t, report.Removed)
+	assert.Equal(t, 4, report.Unchanged)
+
+	r.AddFile("dir/a.txt", "A\n").
+		AddFile("bin/run", "#!/bin/sh\n").
+		Remove("link").
+		Commit("v2").
+		Tag("v2")
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "README"), []byte("README\n"), 0644), "same size, different content")
+
+	repo, err = NewRepository("v2", r.GitDir)
+	require.NoError(t, err)
+
+	report, err = Sync(repo, dst)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README", "dir/a.txt"}, report.Written)
+	assert.Equal(t, []string{"link"}, report.Removed)
+	assert.Equal(t, []string{"bin/run"}, report.ModeChanged)
+
+	fi, err = os.Stat(filepath.Join(dst, "bin/run"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), fi.Mode().Perm())
+}
@@ -0,0 +1,194 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// NewLatestTag returns a Repository pinned to the highest tag which is
+// a semantic version satisfying constraint, e.g. "^1.4" or ">= 2, < 3".
+// Tags which are not semantic versions are ignored, and so are
+// prereleases unless the constraint names one. With WithAutoFetch, the
+// tag found is the tracked revision.
+//
+// A constraint is comparisons joined by "," (and) and "||" (or). Each is
+// a version, with or without the leading "v", after one of the operators
+// =, !=, >, >=, <, <=, ~ (same minor version) and ^ (same major version,
+// or minor version for 0.x).
+func NewLatestTag(gitDir, constraint string, opts ...Option) (*Repository, error) {
+	c, err := parseSemverConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := NewRepository("HEAD", gitDir, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := repo.git("for-each-ref", "--format=%(refname:strip=2)", "refs/tags/")
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := out.lines('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	var latestTag, latest string
+	for _, tag := range tags {
+		v, ok := canonicalSemver(tag)
+		if !ok {
+			continue
+		}
+
+		if c.check(v) && (latest == "" || semver.Compare(v, latest) > 0) {
+			latestTag, latest = tag, v
+		}
+	}
+
+	if latest == "" {
+		return nil, fmt.Errorf("no tag satisfies %q", constraint)
+	}
+
+	if err := repo.SetRevision("refs/tags/" + latestTag); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// canonicalSemver returns s as a canonical semantic version such as
+// "v1.4.0", accepting it without the leading "v" or with parts omitted.
+func canonicalSemver(s string) (string, bool) {
+	if !strings.HasPrefix(s, "v") {
+		s = "v" + s
+	}
+	if !semver.IsValid(s) {
+		return "", false
+	}
+
+	return semver.Canonical(s), true
+}
+
+// semverConstraint is alternatives, one of which must hold.
+type semverConstraint []semverAlternative
+
+// semverAlternative is comparisons all of which must hold.
+type semverAlternative struct {
+	comparisons []semverComparison
+	prereleases bool // named by a comparison, and so considered
+}
+
+type semverComparison struct {
+	op      string // one of =, !=, >, >=, <, <=
+	version string // canonical
+}
+
+func parseSemverConstraint(s string) (semverConstraint, error) {
+	var c semverConstraint
+	for _, alt := range strings.Split(s, "||") {
+		var a semverAlternative
+		for _, term := range strings.Split(alt, ",") {
+			term = strings.TrimSpace(term)
+			given := strings.TrimSpace(strings.TrimLeft(term, "=!<>~^"))
+			op := strings.TrimSpace(strings.TrimSuffix(term, given))
+
+			v, ok := canonicalSemver(given)
+			if !ok {
+				return nil, fmt.Errorf("invalid constraint %q: %q is not a version", s, term)
+			}
+			if semver.Prerelease(v) != "" {
+				a.prereleases = true
+			}
+
+			switch op {
+			case "", "=":
+				a.comparisons = append(a.comparisons, semverComparison{"=", v})
+			case "!=", ">", ">=", "<", "<=":
+				a.comparisons = append(a.comparisons, semverComparison{op, v})
+			case "~", "^":
+				a.comparisons = append(a.comparisons,
+					semverComparison{">=", v},
+					semverComparison{"<", semverUpperBound(op, given)},
+				)
+			default:
+				return nil, fmt.Errorf("invalid constraint %q: unknown operator %q", s, op)
+			}
+		}
+		c = append(c, a)
+	}
+
+	return c, nil
+}
+
+// semverUpperBound returns the lowest version above the range of ~given or
+// ^given. Parts omitted from given widen the range: ^0.3 is below v0.4.0,
+// and ^0 below v1.0.0.
+func semverUpperBound(op, given string) string {
+	given = strings.TrimPrefix(given, "v")
+	if i := strings.IndexAny(given, "-+"); i >= 0 {
+		given = given[:i]
+	}
+
+	var nums [3]int
+	parts := strings.Split(given, ".")
+	for i, p := range parts {
+		nums[i], _ = strconv.Atoi(p)
+	}
+	major, minor, patch := nums[0], nums[1], nums[2]
+
+	switch {
+	case len(parts) == 1, op == "^" && major > 0:
+		return fmt.Sprintf("v%d.0.0", major+1)
+	case op == "~", len(parts) == 2, minor > 0:
+		return fmt.Sprintf("v%d.%d.0", major, minor+1)
+	default:
+		return fmt.Sprintf("v%d.%d.%d", major, minor, patch+1)
+	}
+}
+
+// check reports whether the canonical version v satisfies c.
+func (c semverConstraint) check(v string) bool {
+	for _, a := range c {
+		if a.check(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a semverAlternative) check(v string) bool {
+	if semver.Prerelease(v) != "" && !a.prereleases {
+		return false
+	}
+
+	for _, cmp := range a.comparisons {
+		d := semver.Compare(v, cmp.version)
+		var ok bool
+		switch cmp.op {
+		case "=":
+			ok = d == 0
+		case "!=":
+			ok = d != 0
+		case ">":
+			ok = d > 0
+		case ">=":
+			ok = d >= 0
+		case "<":
+			ok = d < 0
+		case "<=":
+			ok = d <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,70 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestNewLatestTag(t *testing.T) {
+	r := gittest.New(t)
+	commits := map[string]string{}
+	for _, tag := range []string{"v1.3.0", "v1.4.2", "v1.5.0", "v1.6.0-rc.1", "v2.0.0", "latest"} {
+		r.Commit(tag).Tag(tag)
+		commits[tag] = r.Head()
+	}
+
+	repo, err := NewLatestTag(r.GitDir, "^1.4")
+	require.NoError(t, err)
+	assert.Equal(t, commits["v1.5.0"], repo.Revision)
+
+	repo, err = NewLatestTag(r.GitDir, ">= 1")
+	require.NoError(t, err)
+	assert.Equal(t, commits["v2.0.0"], repo.Revision)
+
+	repo, err = NewLatestTag(r.GitDir, ">= 1.6.0-rc.0, < 2")
+	require.NoError(t, err)
+	assert.Equal(t, commits["v1.6.0-rc.1"], repo.Revision)
+
+	_, err = NewLatestTag(r.GitDir, "^3")
+	assert.Error(t, err)
+
+	_, err = NewLatestTag(r.GitDir, "=> 1")
+	assert.Error(t, err)
+
+	t.Run("auto-fetch", func(t *testing.T) {
+		repo, err := NewLatestTag(r.GitDir, "^1", WithAutoFetch(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, "refs/tags/v1.5.0", repo.tracking, "the tag is tracked rather than HEAD")
+	})
+}
+
+func TestSemverConstraint(t *testing.T) {
+	for _, tc := range []struct {
+		constraint string
+		in, out    []string
+	}{
+		{"^1.4", []string{"v1.4.0", "v1.9.9"}, []string{"v1.3.9", "v2.0.0", "v1.5.0-rc.1"}},
+		{"^0.3", []string{"v0.3.0", "v0.3.9"}, []string{"v0.4.0"}},
+		{"^0.0.3", []string{"v0.0.3"}, []string{"v0.0.4"}},
+		{"~1.4", []string{"v1.4.0", "v1.4.9"}, []string{"v1.5.0"}},
+		{"~1", []string{"v1.9.0"}, []string{"v2.0.0"}},
+		{">= 2, < 3", []string{"v2.0.0", "v2.9.0"}, []string{"v1.9.0", "v3.0.0"}},
+		{"1.2.3 || >= 3", []string{"v1.2.3", "v3.1.0"}, []string{"v1.2.4", "v2.0.0"}},
+		{"!= 1.0.0", []string{"v1.0.1"}, []string{"v1.0.0"}},
+	} {
+		c, err := parseSemverConstraint(tc.constraint)
+		require.NoError(t, err, tc.constraint)
+
+		for _, v := range tc.in {
+			assert.True(t, c.check(v), "%s satisfies %s", v, tc.constraint)
+		}
+		for _, v := range tc.out {
+			assert.False(t, c.check(v), "%s does not satisfy %s", v, tc.constraint)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package git
+
+import (
+	"context"
+	"time"
+)
+
+// commandWaitDelay bounds how long waiting for a git command lasts after
+// it is killed or has exited, in case its children such as credential
+// helpers or ssh keep its output open.
+var commandWaitDelay = time.Second
+
+// WithCommandTimeout kills each git command which runs longer than d,
+// making the operation fail with an error wrapping
+// context.DeadlineExceeded. Fetches and clones are subject to it too. The
+// long-running cat-file processes of Verify and Sync are not; they end
+// with the context of the Repository.
+func WithCommandTimeout(d time.Duration) Option {
+	return func(repo *Repository) {
+		repo.commandTimeout = d
+	}
+}
+
+// commandCtx returns the context to run a git command with, which must be
+// canceled after the command finishes.
+func (repo *Repository) commandCtx() (context.Context, context.CancelFunc) {
+	if repo.commandTimeout > 0 {
+		return context.WithTimeout(repo.context(), repo.commandTimeout)
+	}
+
+	return context.WithCancel(repo.context())
+}
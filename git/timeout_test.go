@@ -0,0 +1,39 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCommandTimeout(t *testing.T) {
+	// the shell is killed but sleep, its child, keeps stdout open
+	gitPath := filepath.Join(t.TempDir(), "git")
+	require.NoError(t, os.WriteFile(gitPath, []byte("#!/bin/sh\nsleep 10\n"), 0755))
+
+	r := newFixture(t)
+
+	repo := &Repository{GitDir: r.GitDir}
+	WithGitPath(gitPath)(repo)
+	WithCommandTimeout(100 * time.Millisecond)(repo)
+
+	start := time.Now()
+	_, err := repo.Stat("git/git.go")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	_, err = repo.IsBinary("git/git.go")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+
+	repo = &Repository{GitDir: r.GitDir}
+	WithCommandTimeout(10 * time.Second)(repo)
+	_, err = repo.Stat("git/git.go")
+	assert.NoError(t, err)
+}
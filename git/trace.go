@@ -0,0 +1,87 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/motemen/go-vcs-fs/git"
+
+// WithTracerProvider makes Stat, Lstat, ReadDir, Open and Walk record spans
+// by tracers of tp, with git commands run as their children. Spans are
+// parented to the context given by WithContext.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(repo *Repository) {
+		repo.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// startSpan starts a span of an operation on path and makes git commands
+// run until it ends its children. The returned function ends the span,
+// recording *errp if any.
+func (repo *Repository) startSpan(name, path string) func(errp *error) {
+	if repo.tracer == nil {
+		return func(*error) {}
+	}
+
+	parent := repo.spanCtx
+	ctx, end := repo.traceSpan(repo.context(), name, path)
+	repo.spanCtx = ctx
+
+	return func(errp *error) {
+		end(errp)
+		repo.spanCtx = parent
+	}
+}
+
+// traceSpan starts a span of an operation on path as a child of ctx, and
+// returns the context of the span and a function ending it, recording
+// *errp if any. It returns ctx as is if tracing is not enabled.
+func (repo *Repository) traceSpan(ctx context.Context, name, path string) (context.Context, func(errp *error)) {
+	if repo.tracer == nil {
+		return ctx, func(*error) {}
+	}
+
+	ctx, span := repo.tracer.Start(ctx, "git."+name, trace.WithAttributes(
+		attribute.String("vcsfs.revision", repo.revision()),
+		attribute.String("vcsfs.path", path),
+	))
+
+	return ctx, func(errp *error) {
+		if err := *errp; err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+func (repo *Repository) startExecSpan(cmd *exec.Cmd) func(err error) {
+	if repo.tracer == nil {
+		return func(error) {}
+	}
+
+	_, span := repo.tracer.Start(repo.context(), "git exec", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("vcsfs.revision", repo.revision()),
+		attribute.StringSlice("vcsfs.git.args", commandArgs(cmd)),
+	))
+
+	return func(err error) {
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				span.SetAttributes(attribute.Int("vcsfs.git.exit_code", exitErr.ExitCode()))
+			}
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(attribute.Int("vcsfs.git.exit_code", 0))
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,83 @@
+package git
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithTracerProvider(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+
+	repo, err := NewRepository("HEAD", newFixture(t).GitDir, WithContext(ctx), WithTracerProvider(tp))
+	require.NoError(t, err)
+
+	_, err = repo.Stat("git/git.go")
+	require.NoError(t, err)
+
+	_, err = repo.Stat("git/nonexistent.go")
+	require.Error(t, err)
+
+	parent.End()
+
+	spans := rec.Ended()
+	byName := map[string][]sdktrace.ReadOnlySpan{}
+	for _, s := range spans {
+		byName[s.Name()] = append(byName[s.Name()], s)
+	}
+
+	require.Len(t, byName["git.Stat"], 2)
+	stat := byName["git.Stat"][0]
+	assert.Equal(t, parent.SpanContext().SpanID(), stat.Parent().SpanID())
+	assert.Contains(t, stat.Attributes(), attribute.String("vcsfs.path", "git/git.go"))
+	assert.Equal(t, codes.Error, byName["git.Stat"][1].Status().Code)
+
+	execs := byName["git exec"]
+	require.NotEmpty(t, execs)
+	lsTree := execs[len(execs)-1]
+	assert.Equal(t, stat.SpanContext().SpanID(), lsTree.Parent().SpanID())
+	assert.Contains(t, lsTree.Attributes(), attribute.Int("vcsfs.git.exit_code", 0))
+}
+
+func TestWithTracerProvider_walk(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	repo, err := NewRepository("HEAD", newFixture(t).GitDir, WithTracerProvider(tp))
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Walk("git", WalkOptions{}, func(name string, fi os.FileInfo, err error) error {
+		return err
+	}))
+
+	byName := map[string][]sdktrace.ReadOnlySpan{}
+	for _, s := range rec.Ended() {
+		byName[s.Name()] = append(byName[s.Name()], s)
+	}
+
+	require.Len(t, byName["git.Walk"], 1)
+	walk := byName["git.Walk"][0]
+	assert.Contains(t, walk.Attributes(), attribute.String("vcsfs.path", "git"))
+
+	require.NotEmpty(t, byName["git.ReadDir"])
+	for _, s := range append(byName["git.Lstat"], byName["git.ReadDir"]...) {
+		assert.Equal(t, walk.SpanContext().SpanID(), s.Parent().SpanID(), s.Name())
+	}
+
+	// spans after the walk are not its children
+	_, err = repo.Stat("git/git.go")
+	require.NoError(t, err)
+	stat := rec.Ended()[len(rec.Ended())-1]
+	assert.Equal(t, "git.Stat", stat.Name())
+	assert.False(t, stat.Parent().IsValid())
+}
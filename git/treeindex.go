@@ -0,0 +1,136 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// treeIndexHeader starts the output of SaveIndex, followed by lines of the
+// commit and its tree and a blank line, then the output of
+// "git ls-tree -r -t -l -z" of the commit.
+const treeIndexHeader = "go-vcs-fs tree index 1\n"
+
+// SaveIndex writes the listings of every directory in the tree of the
+// revision to w, with the sizes of files, so that LoadIndex can fill the
+// caches of a Repository of the same commit elsewhere without listing the
+// trees. Filters are not applied; the index is of the whole tree.
+func (repo *Repository) SaveIndex(w io.Writer) (err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	defer repo.startSpan("SaveIndex", "")(&err)
+
+	repo.autoFetch()
+
+	commit, err := repo.resolveCommit(repo.revision())
+	if err != nil {
+		return err
+	}
+	root, err := repo.rootTree()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%scommit %s\ntree %s\n\n", treeIndexHeader, commit, root)
+
+	if err := repo.gitTo(bw, "ls-tree", "--full-tree", "-r", "-t", "-l", "-z", "--end-of-options", root); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// LoadIndex reads an index written by SaveIndex into the tree cache, after
+// which Stat, Lstat and ReadDir run no git to list directories. It fails
+// if the index is not of the tree of the revision.
+func (repo *Repository) LoadIndex(r io.Reader) (err error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	defer repo.startSpan("LoadIndex", "")(&err)
+
+	repo.autoFetch()
+
+	br := bufio.NewReader(r)
+
+	var header [4]string
+	for i := range header {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("could not read index: %w", err)
+		}
+		header[i] = line
+	}
+	if header[0] != treeIndexHeader || !strings.HasPrefix(header[1], "commit ") || !strings.HasPrefix(header[2], "tree ") || header[3] != "\n" {
+		return fmt.Errorf("not a tree index")
+	}
+
+	tree := strings.TrimSuffix(strings.TrimPrefix(header[2], "tree "), "\n")
+	root, err := repo.rootTree()
+	if err != nil {
+		return err
+	}
+	if tree != root {
+		return fmt.Errorf("index is of tree %s, not %s", tree, root)
+	}
+
+	trees := map[string]string{"": root}         // oid by path
+	children := map[string][]*treeEntry{"": nil} // by path of directory
+
+	for {
+		rec, err := br.ReadBytes(0)
+		if err == io.EOF && len(rec) == 0 {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("could not read index: %w", err)
+		}
+		rec = bytes.TrimSuffix(rec, []byte{0})
+
+		e, err := repo.parseLsTreeLine("", string(rec))
+		if err != nil {
+			return err
+		}
+
+		p := e.name
+		e.parent, e.name = path.Split(p)
+		e.parent = strings.TrimSuffix(e.parent, "/")
+		children[e.parent] = append(children[e.parent], e)
+
+		if e.objType == objTypeDir {
+			trees[p] = e.sha1
+			if _, ok := children[p]; !ok {
+				children[p] = nil
+			}
+		}
+	}
+
+	if repo.treeCache == nil {
+		repo.treeCache = repo.newTreeCache()
+	}
+
+	for dir, entries := range children {
+		oid, ok := trees[dir]
+		if !ok {
+			return fmt.Errorf("malformed index: no tree for %s", dir)
+		}
+
+		// cached listings are shared by every path the tree appears at
+		for _, e := range entries {
+			e.parent = ""
+		}
+
+		listing := repo.newListing(entries)
+		for _, e := range listing {
+			e.sizes = nil // known from the index
+		}
+		repo.treeCache.add(oid, listing)
+	}
+
+	return nil
+}
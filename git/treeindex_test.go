@@ -0,0 +1,53 @@
+package git
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestSaveIndex(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a/b/file.txt", "file\n").
+		AddFile("a/other.txt", "other\n").
+		AddFile("top.txt", "top\n").
+		Commit("init")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	var index bytes.Buffer
+	require.NoError(t, repo.SaveIndex(&index))
+
+	loaded, err := NewRepository("HEAD", r.GitDir, WithDebugLog(20))
+	require.NoError(t, err)
+	require.NoError(t, loaded.LoadIndex(bytes.NewReader(index.Bytes())))
+	require.Len(t, loaded.DebugLog(), 1, "rev-parse only")
+
+	fi, err := loaded.Stat("a/b/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), fi.Size())
+
+	entries, err := loaded.ReadDir("a")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "b", entries[0].Name())
+	assert.True(t, entries[0].IsDir())
+
+	entries, err = loaded.ReadDir("")
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	assert.Len(t, loaded.DebugLog(), 1, "no git commands after loading")
+
+	r.AddFile("top.txt", "changed\n").Commit("change")
+	moved, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+	assert.Error(t, moved.LoadIndex(bytes.NewReader(index.Bytes())), "index of another tree")
+
+	assert.Error(t, moved.LoadIndex(bytes.NewReader([]byte("garbage\n"))))
+}
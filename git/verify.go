@@ -0,0 +1,177 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"strconv"
+)
+
+// VerifyReport is the result of Verify.
+type VerifyReport struct {
+	Tree     string // object ID of the root tree
+	Checked  int    // number of objects checked
+	Problems []VerifyProblem
+}
+
+// OK reports whether no problems were found.
+func (r *VerifyReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// VerifyProblem describes an object which failed verification.
+type VerifyProblem struct {
+	Path     string
+	ObjectID string
+	Problem  string
+}
+
+func (p VerifyProblem) String() string {
+	return fmt.Sprintf("%s (%s): %s", p.Path, p.ObjectID, p.Problem)
+}
+
+// Verify walks the whole tree of the revision, checking that every object
+// referenced exists, has the expected type, and hashes to its object ID.
+// Submodules are not descended into. The returned error is only for
+// failures in running the check; problems found are in the report.
+func (repo *Repository) Verify(ctx context.Context) (*VerifyReport, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	root, err := repo.rootTree()
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := repo.startCatFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer batch.Close()
+
+	report := &VerifyReport{Tree: root}
+
+	type object struct {
+		path    string
+		oid     string
+		objType string
+	}
+
+	queue := []object{{path: "", oid: root, objType: "tree"}}
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		obj := queue[0]
+		queue = queue[1:]
+
+		var (
+			h       hash.Hash
+			objType string
+			content = new(bytes.Buffer)
+		)
+		err := batch.read(obj.oid, func(t string, size int64) io.Writer {
+			objType = t
+			h = newObjectHash(obj.oid, t, size)
+			if t == "tree" {
+				return io.MultiWriter(h, content)
+			}
+			return h
+		})
+		report.Checked++
+
+		problem := func(s string) {
+			report.Problems = append(report.Problems, VerifyProblem{Path: obj.path, ObjectID: obj.oid, Problem: s})
+		}
+
+		if err == ErrMissingObject {
+			problem("missing")
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if objType != obj.objType {
+			problem(fmt.Sprintf("expected %s, got %s", obj.objType, objType))
+			continue
+		}
+
+		if sum := hex.EncodeToString(h.Sum(nil)); sum != obj.oid {
+			problem("hash mismatch: " + sum)
+			continue
+		}
+
+		if objType != "tree" {
+			continue
+		}
+
+		entries, err := parseTree(content.Bytes(), len(obj.oid)/2)
+		if err != nil {
+			problem(err.Error())
+			continue
+		}
+
+		for _, e := range entries {
+			t := "blob"
+			if e.objType == objTypeDir {
+				t = "tree"
+			} else if e.objType == objTypeGitlink {
+				continue
+			}
+			queue = append(queue, object{path.Join(obj.path, e.name), e.sha1, t})
+		}
+	}
+
+	return report, nil
+}
+
+// newObjectHash returns a hash which computes the object ID of an object,
+// after the content is written to it. SHA-256 is used when oid looks like
+// one of a SHA-256 repository.
+func newObjectHash(oid, objType string, size int64) hash.Hash {
+	var h hash.Hash
+	if len(oid) == 64 {
+		h = sha256.New()
+	} else {
+		h = sha1.New()
+	}
+
+	fmt.Fprintf(h, "%s %d\x00", objType, size)
+
+	return h
+}
+
+// parseTree parses the raw content of a tree object, which is a sequence of
+// "<mode> <name>\x00<binary object ID>".
+func parseTree(data []byte, oidLen int) ([]treeEntry, error) {
+	entries := []treeEntry{}
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp == -1 || nul < sp || nul+1+oidLen > len(data) {
+			return nil, fmt.Errorf("malformed tree")
+		}
+
+		mode, err := strconv.ParseUint(string(data[:sp]), 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("malformed tree: %s", err)
+		}
+
+		entries = append(entries, treeEntry{
+			name:    string(data[sp+1 : nul]),
+			objType: uint16(mode >> 9),
+			mode:    uint16(mode & 0777),
+			sha1:    hex.EncodeToString(data[nul+1 : nul+1+oidLen]),
+		})
+		data = data[nul+1+oidLen:]
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,30 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	repo := Repository{GitDir: newFixture(t).GitDir}
+
+	report, err := repo.Verify(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, report.OK(), "%v", report.Problems)
+	assert.Regexp(t, `^[0-9a-f]{40}$`, report.Tree)
+	assert.True(t, report.Checked > 3)
+}
+
+func TestVerify_canceled(t *testing.T) {
+	repo := Repository{GitDir: newFixture(t).GitDir}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.Verify(ctx)
+	assert.Error(t, err)
+}
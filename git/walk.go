@@ -0,0 +1,156 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// WalkFunc is called by Walk for each entry, as filepath.WalkFunc is.
+// Returning fs.SkipDir for a directory skips it without listing it, and
+// fs.SkipAll stops the walk.
+type WalkFunc func(path string, fi os.FileInfo, err error) error
+
+// WalkOptions limits the entries Walk visits.
+type WalkOptions struct {
+	// MaxDepth is the depth of the deepest entries visited, where entries
+	// directly under the root are of depth 1. Directories at the depth are
+	// visited but not listed. Zero means no limit.
+	MaxDepth int
+	// Exclude are globs of entries neither visited nor, for directories,
+	// listed, in the syntax of WithExclude (e.g. "vendor/", "third_party/").
+	Exclude []string
+}
+
+// Walk walks the tree under root in the order of ReadDir, calling fn for
+// each entry including root, which is "." for the top of the tree. Paths
+// given to fn are relative to the top. Directories are listed only when
+// the walk descends into them, so that skipping a subtree costs nothing.
+// The walk stops with the error of the context of repo once it is done.
+// With WithRecurseSubmodules, submodules are walked as directories.
+func (repo *Repository) Walk(root string, opts WalkOptions, fn WalkFunc) (err error) {
+	root = strings.Trim(root, "/")
+	if root == "" {
+		root = "."
+	}
+
+	excludes := make([]globPattern, len(opts.Exclude))
+	for i, g := range opts.Exclude {
+		excludes[i] = newGlobPattern(g)
+	}
+
+	repo.mu.Lock()
+	ctx, endSpan := repo.traceSpan(repo.context(), "Walk", root)
+	repo.mu.Unlock()
+	defer endSpan(&err)
+
+	// the operations of the walk are traced as children of its span
+	if repo.tracer != nil {
+		repo = repo.WithContext(ctx)
+	}
+
+	w := &walker{ctx: ctx, repo: repo, maxDepth: opts.MaxDepth, excludes: excludes, fn: fn}
+
+	fi, err := repo.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = w.walk(root, fi, 0)
+	}
+
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+type walker struct {
+	ctx      context.Context
+	repo     *Repository
+	prefix   string // path of the submodule repo is of, if it is
+	maxDepth int
+	excludes []globPattern
+	fn       WalkFunc
+}
+
+func (w *walker) walk(name string, fi os.FileInfo, depth int) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	if w.repo.recurseSubmodules && fi.Mode()&os.ModeIrregular != 0 {
+		return w.walkSubmodule(name, fi, depth)
+	}
+
+	if err := w.fn(name, fi, nil); err != nil || !fi.IsDir() {
+		return err
+	}
+
+	if w.maxDepth > 0 && depth >= w.maxDepth {
+		return nil
+	}
+
+	entries, err := w.repo.ReadDir(w.rel(name))
+	if err != nil {
+		err = w.fn(name, fi, err)
+		if errors.Is(err, fs.SkipDir) {
+			return nil
+		}
+		return err
+	}
+
+	for _, e := range entries {
+		child := path.Join(name, e.Name())
+		if matchAny(w.excludes, child, e.IsDir()) {
+			continue
+		}
+
+		if err := w.walk(child, e, depth+1); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				if e.IsDir() {
+					continue
+				}
+				// skips the rest of the directory
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkSubmodule walks the submodule at name with a walker of its own.
+func (w *walker) walkSubmodule(name string, fi os.FileInfo, depth int) error {
+	commit, ok := ObjectID(fi)
+	if !ok {
+		return w.fn(name, fi, nil)
+	}
+
+	w.repo.mu.Lock()
+	sub, err := w.repo.submodule(w.rel(name), commit)
+	w.repo.mu.Unlock()
+	if err != nil {
+		return w.fn(name, fi, err)
+	}
+
+	sw := *w
+	sw.repo, sw.prefix = sub, name
+
+	return sw.walk(name, submoduleInfo{fi}, depth)
+}
+
+// rel returns name relative to the top of the repository of w.
+func (w *walker) rel(name string) string {
+	if w.prefix == "" {
+		return name
+	}
+	if name == w.prefix {
+		return "."
+	}
+
+	return strings.TrimPrefix(name, w.prefix+"/")
+}
@@ -0,0 +1,109 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestWalk(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("a.txt", "x\n").
+		AddFile("src/main.go", "x\n").
+		AddFile("src/sub/deep.go", "x\n").
+		AddFile("vendor/lib/lib.go", "x\n").
+		AddFile("third_party/x/x.go", "x\n").
+		AddFile("z.txt", "x\n").
+		Commit("initial")
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithDebugLog(100))
+	require.NoError(t, err)
+
+	walk := func(root string, opts WalkOptions, skip string) []string {
+		var visited []string
+		err := repo.Walk(root, opts, func(name string, fi os.FileInfo, err error) error {
+			require.NoError(t, err)
+			visited = append(visited, name)
+			if name == skip {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return visited
+	}
+
+	assert.Equal(t, []string{
+		".", "a.txt",
+		"src", "src/main.go", "src/sub", "src/sub/deep.go",
+		"third_party", "third_party/x", "third_party/x/x.go",
+		"vendor", "vendor/lib", "vendor/lib/lib.go",
+		"z.txt",
+	}, walk("", WalkOptions{}, ""))
+
+	assert.Equal(t, []string{"src", "src/main.go", "src/sub"}, walk("src", WalkOptions{MaxDepth: 1}, ""))
+
+	assert.Equal(t, []string{".", "a.txt", "src", "third_party", "vendor", "z.txt"}, walk("/", WalkOptions{MaxDepth: 1}, ""))
+
+	// neither pruned nor excluded directories are listed
+	listed := func() map[string]bool {
+		dirs := map[string]bool{}
+		for _, e := range repo.DebugLog() {
+			if e.Args[0] == "ls-tree" {
+				dirs[e.Args[len(e.Args)-1]] = true
+			}
+		}
+		return dirs
+	}
+
+	repo, err = NewRepository("HEAD", r.GitDir, WithDebugLog(100))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		".", "a.txt",
+		"src", "src/main.go", "src/sub", "src/sub/deep.go",
+		"third_party",
+		"z.txt",
+	}, walk("", WalkOptions{Exclude: []string{"vendor/"}}, "third_party"))
+
+	vendor, err := repo.objectID("vendor")
+	require.NoError(t, err)
+	thirdParty, err := repo.objectID("third_party")
+	require.NoError(t, err)
+	src, err := repo.objectID("src")
+	require.NoError(t, err)
+
+	assert.True(t, listed()[src])
+	assert.False(t, listed()[vendor])
+	assert.False(t, listed()[thirdParty])
+
+	// SkipDir of a file skips the rest of its directory
+	assert.Equal(t, []string{".", "a.txt"}, walk("", WalkOptions{}, "a.txt"))
+
+	var n int
+	err = repo.Walk("", WalkOptions{}, func(name string, fi os.FileInfo, err error) error {
+		if n++; n == 3 {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	boom := errors.New("boom")
+	err = repo.Walk("", WalkOptions{}, func(name string, fi os.FileInfo, err error) error {
+		return boom
+	})
+	assert.Equal(t, boom, err)
+
+	err = repo.Walk("nonexistent", WalkOptions{}, func(name string, fi os.FileInfo, err error) error {
+		return err
+	})
+	assert.True(t, errors.Is(err, os.ErrNotExist), "%v", err)
+}
@@ -0,0 +1,102 @@
+package git
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WithUntracked makes WorkingTreeView include untracked files which are
+// not ignored by .gitignore and the other exclude files, as git add --all
+// would add them. By default the view has tracked files only.
+func WithUntracked(enabled bool) Option {
+	return func(repo *Repository) {
+		repo.untracked = enabled
+	}
+}
+
+// WorkingTreeView returns a Repository serving what git commit -a would
+// commit from the work tree at workTree: the files of the index with the
+// changes to tracked files in the work tree, deletions included. Untracked
+// files are left out, ignored or not, unless WithUntracked is given.
+// Contents are cleaned as git add does by the attributes, so that filters
+// such as eol conversion and LFS take effect. The index of WithIndexFile
+// is used if given.
+//
+// Nothing is staged: the index is copied to a temporary one, which is
+// updated and written as a tree, served dated as the revision of repo.
+// Blobs and trees created are left in the object database unreachable.
+func WorkingTreeView(repo *Repository, workTree string) (*Repository, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	gitDir, err := filepath.Abs(repo.GitDir)
+	if err != nil {
+		return nil, err
+	}
+	workTree, err = filepath.Abs(workTree)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "vcsfs-worktree-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	index := filepath.Join(dir, "index")
+	src := repo.indexFile
+	if src == "" {
+		src = filepath.Join(gitDir, "index")
+	}
+	if err := copyFile(src, index); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	indexed := repo.withEnv("GIT_INDEX_FILE="+index, "GIT_WORK_TREE="+workTree)
+	indexed.GitDir = gitDir
+
+	add := "--update"
+	if repo.untracked {
+		add = "--all"
+	}
+
+	// -C makes the pathspec of add, and thus the update, cover the whole
+	// work tree
+	if _, err := indexed.git("-C", workTree, "add", add); err != nil {
+		return nil, err
+	}
+
+	out, err := indexed.git("write-tree")
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := out.first()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.treeView(tree, repo.revision()), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,102 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestWorkingTreeView(t *testing.T) {
+	r := gittest.New(t).
+		AddFile(".gitattributes", "*.txt text eol=lf\n").
+		AddFile(".gitignore", "*.log\n").
+		AddFile("changed.txt", "old\n").
+		AddFile("deleted.txt", "deleted\n").
+		AddFile("same.txt", "same\n").
+		AddFile("tracked.log", "tracked\n").
+		Commit("init")
+	r.Git("checkout", "--", ".")
+
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(r.Dir, name), []byte(content), 0644))
+	}
+	write("changed.txt", "new\r\n")
+	write("tracked.log", "tracked, changed\n")
+	write("untracked.txt", "untracked\n")
+	write("ignored.log", "ignored\n")
+	write("staged.txt", "staged\n")
+	require.NoError(t, os.Remove(filepath.Join(r.Dir, "deleted.txt")))
+	r.Git("add", "staged.txt")
+
+	repo, err := NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	view, err := WorkingTreeView(repo, r.Dir)
+	require.NoError(t, err)
+
+	read := func(name string) string {
+		b, err := vfs.ReadFile(view, name)
+		require.NoError(t, err, name)
+		return string(b)
+	}
+
+	assert.Equal(t, "new\n", read("changed.txt"), "cleaned by eol=lf")
+	assert.Equal(t, "same\n", read("same.txt"))
+	assert.Equal(t, "tracked, changed\n", read("tracked.log"), "tracked despite the ignore rules")
+	assert.Equal(t, "staged\n", read("staged.txt"))
+
+	for _, name := range []string{"deleted.txt", "untracked.txt", "ignored.log"} {
+		ok, err := view.Exists(name)
+		require.NoError(t, err)
+		assert.False(t, ok, name)
+	}
+
+	b, err := vfs.ReadFile(repo, "changed.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "old\n", string(b), "the repository is untouched")
+	assert.Contains(t, r.Git("status", "--porcelain"), " M changed.txt", "nothing staged")
+}
+
+func TestWorkingTreeView_untracked(t *testing.T) {
+	r := gittest.New(t).
+		AddFile(".gitignore", "*.log\nbuild/\n").
+		AddFile("tracked.txt", "tracked\n").
+		Commit("init")
+	r.Git("checkout", "--", ".")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(r.Dir, "src", "build"), 0755))
+	for name, content := range map[string]string{
+		"untracked.txt":     "untracked\n",
+		"src/new.go":        "package src\n",
+		"ignored.log":       "ignored\n",
+		"src/build/out.txt": "built\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(r.Dir, name), []byte(content), 0644))
+	}
+
+	repo, err := NewRepository("HEAD", r.GitDir, WithUntracked(true))
+	require.NoError(t, err)
+
+	view, err := WorkingTreeView(repo, r.Dir)
+	require.NoError(t, err)
+
+	for name, want := range map[string]bool{
+		"tracked.txt":       true,
+		"untracked.txt":     true,
+		"src/new.go":        true,
+		"ignored.log":       false,
+		"src/build/out.txt": false,
+	} {
+		ok, err := view.Exists(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, ok, name)
+	}
+
+	assert.Contains(t, r.Git("status", "--porcelain"), "?? untracked.txt", "nothing staged")
+}
@@ -0,0 +1,148 @@
+// Package gittest builds throwaway git repositories for tests.
+//
+//	r := gittest.New(t).
+//		AddFile("README.md", "# hello\n").
+//		AddSymlink("docs", "README.md").
+//		Commit("initial").
+//		Tag("v1.0.0")
+//	repo, _ := git.NewRepository("v1.0.0", r.GitDir)
+//
+// Files are written to the index only, so that symbolic links and
+// submodules can be made on any platform. Commits get deterministic
+// authors and dates: the n-th commit is dated n hours after Epoch.
+package gittest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Epoch is the date of the commit before the first one.
+var Epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Repo is a repository under construction, created in t.TempDir().
+// Methods fail the test on errors.
+type Repo struct {
+	Dir    string // the work tree
+	GitDir string
+
+	t       testing.TB
+	commits int
+}
+
+// New creates an empty repository with the branch "main".
+func New(t testing.TB) *Repo {
+	t.Helper()
+
+	dir := t.TempDir()
+	r := &Repo{Dir: dir, GitDir: filepath.Join(dir, ".git"), t: t}
+	r.Git("init", "--quiet", "-b", "main")
+
+	return r
+}
+
+// Git runs git in the repository and returns its output without the
+// trailing newline.
+func (r *Repo) Git(args ...string) string {
+	r.t.Helper()
+	return r.gitInput("", args...)
+}
+
+func (r *Repo) gitInput(stdin string, args ...string) string {
+	r.t.Helper()
+
+	date := Epoch.Add(time.Duration(r.commits) * time.Hour).Format(time.RFC3339)
+
+	cmd := exec.Command("git", append([]string{"-C", r.Dir}, args...)...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_COMMITTER_DATE="+date,
+	)
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		r.t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, stderr.String())
+	}
+
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// AddFile adds a regular file to the index.
+func (r *Repo) AddFile(name, content string) *Repo {
+	r.t.Helper()
+	return r.add(0100644, name, content)
+}
+
+// AddExecutable adds an executable file to the index.
+func (r *Repo) AddExecutable(name, content string) *Repo {
+	r.t.Helper()
+	return r.add(0100755, name, content)
+}
+
+// AddSymlink adds a symbolic link pointing to target to the index.
+func (r *Repo) AddSymlink(name, target string) *Repo {
+	r.t.Helper()
+	return r.add(0120000, name, target)
+}
+
+// AddSubmodule adds a submodule at name checked out at commit, which need
+// not exist, to the index. .gitmodules is not written.
+func (r *Repo) AddSubmodule(name, commit string) *Repo {
+	r.t.Helper()
+
+	r.Git("update-index", "--add", "--cacheinfo", fmt.Sprintf("160000,%s,%s", commit, name))
+	return r
+}
+
+func (r *Repo) add(mode int, name, content string) *Repo {
+	r.t.Helper()
+
+	oid := r.gitInput(content, "hash-object", "-w", "--stdin")
+	r.Git("update-index", "--add", "--cacheinfo", fmt.Sprintf("%06o,%s,%s", mode, oid, name))
+	return r
+}
+
+// Remove removes a file from the index.
+func (r *Repo) Remove(name string) *Repo {
+	r.t.Helper()
+
+	r.Git("update-index", "--force-remove", name)
+	return r
+}
+
+// Commit commits the index to the current branch.
+func (r *Repo) Commit(message string) *Repo {
+	r.t.Helper()
+
+	r.commits++
+	r.Git("commit", "--quiet", "--allow-empty", "-m", message)
+	return r
+}
+
+// Tag creates a lightweight tag at HEAD.
+func (r *Repo) Tag(name string) *Repo {
+	r.t.Helper()
+
+	r.Git("tag", name)
+	return r
+}
+
+// Head returns the commit ID of HEAD.
+func (r *Repo) Head() string {
+	r.t.Helper()
+	return r.Git("rev-parse", "HEAD")
+}
+
+// CommitTime returns the date of the n-th commit, counting from 1.
+func CommitTime(n int) time.Time {
+	return Epoch.Add(time.Duration(n) * time.Hour)
+}
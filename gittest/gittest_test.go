@@ -0,0 +1,27 @@
+package gittest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepo(t *testing.T) {
+	r := New(t).
+		AddFile("README.md", "# hello\n").
+		AddExecutable("bin/run", "#!/bin/sh\n").
+		AddSymlink("docs", "README.md").
+		AddSubmodule("vendor/lib", "0123456789012345678901234567890123456789").
+		Commit("initial").
+		Tag("v1.0.0")
+
+	lines := r.Git("ls-tree", "-r", "--format=%(objectmode) %(path)", "v1.0.0")
+	assert.Equal(t, "100644 README.md\n100755 bin/run\n120000 docs\n160000 vendor/lib", lines)
+
+	assert.Equal(t, "README.md", r.Git("cat-file", "blob", "HEAD:docs"))
+	assert.Equal(t, CommitTime(1).Format("2006-01-02T15:04:05Z"), r.Git("log", "-1", "--format=%cd", "--date=format-local:%Y-%m-%dT%H:%M:%SZ"))
+
+	r.Remove("bin/run").Commit("remove")
+	assert.Equal(t, "README.md\ndocs\nvendor", r.Git("ls-tree", "--name-only", "HEAD"))
+	assert.NotEqual(t, r.Git("rev-parse", "v1.0.0"), r.Head())
+}
@@ -0,0 +1,323 @@
+// Package goproxy answers the Go module proxy protocol for a module hosted
+// in a git repository, so that an internal proxy can serve versions right
+// from the tags of a bare repository without checking anything out.
+//
+//	http.Handle("/", goproxy.New(gitDir, "example.com/mod"))
+//
+// and then
+//
+//	GOPROXY=http://localhost:8080 go get example.com/mod@v1.2.3
+//
+// Versions are tags which are canonical semantic versions, prefixed by Dir
+// and a slash if the module is in a subdirectory, as the go command expects.
+package goproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	modzip "golang.org/x/mod/zip"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// Handler serves a single module at the root of a proxy.
+type Handler struct {
+	GitDir     string
+	ModulePath string
+	Dir        string // of the module in the repository; empty for the root
+	Options    []git.Option
+
+	mu   sync.Mutex
+	repo *git.Repository // made on the first request
+}
+
+// New returns a Handler serving modulePath from the root of the repository
+// at gitDir. opts are applied to the Repository serving requests, which is
+// made once and shared by them.
+func New(gitDir, modulePath string, opts ...git.Option) *Handler {
+	return &Handler{GitDir: gitDir, ModulePath: modulePath, Options: opts}
+}
+
+// Info is the response of .info and @latest.
+type Info struct {
+	Version string
+	Time    time.Time
+}
+
+type notFoundError struct {
+	msg string
+}
+
+func (e *notFoundError) Error() string { return e.msg }
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	escaped, err := module.EscapePath(h.ModulePath)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/"+escaped+"/")
+	if rest == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+
+	if rest == "@latest" {
+		err = h.serveLatest(w)
+	} else if file := strings.TrimPrefix(rest, "@v/"); file == "list" {
+		err = h.serveList(w)
+	} else if file != rest {
+		err = h.serveVersion(w, file)
+	} else {
+		err = &notFoundError{"not found: " + r.URL.Path}
+	}
+
+	if err != nil {
+		serveError(w, err)
+	}
+}
+
+// serveError reports err, keeping the details of other than notFoundError
+// in the log.
+func serveError(w http.ResponseWriter, err error) {
+	if _, ok := err.(*notFoundError); ok {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	log.Printf("goproxy: %v", err)
+	http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+}
+
+func (h *Handler) serveList(w http.ResponseWriter) error {
+	versions, err := h.versions()
+	if err != nil {
+		return err
+	}
+
+	list := make([]string, 0, len(versions))
+	for v := range versions {
+		list = append(list, v)
+	}
+	semver.Sort(list)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, v := range list {
+		fmt.Fprintln(w, v)
+	}
+
+	return nil
+}
+
+func (h *Handler) serveLatest(w http.ResponseWriter) error {
+	versions, err := h.versions()
+	if err != nil {
+		return err
+	}
+
+	// the latest release, or the latest prerelease if there are none
+	var latestRelease, latestPrerelease string
+	for v := range versions {
+		if semver.Prerelease(v) == "" {
+			latestRelease = semver.Max(latestRelease, v)
+		} else {
+			latestPrerelease = semver.Max(latestPrerelease, v)
+		}
+	}
+
+	latest := latestRelease
+	if latest == "" {
+		latest = latestPrerelease
+	}
+	if latest == "" {
+		return &notFoundError{"no versions of " + h.ModulePath}
+	}
+
+	return h.serveInfo(w, latest, versions[latest])
+}
+
+func (h *Handler) serveVersion(w http.ResponseWriter, file string) error {
+	ext := path.Ext(file)
+	escapedVersion := strings.TrimSuffix(file, ext)
+
+	version, err := module.UnescapeVersion(escapedVersion)
+	if err != nil {
+		return &notFoundError{err.Error()}
+	}
+
+	versions, err := h.versions()
+	if err != nil {
+		return err
+	}
+
+	commit, ok := versions[version]
+	if !ok {
+		return &notFoundError{fmt.Sprintf("unknown version %s of %s", version, h.ModulePath)}
+	}
+
+	switch ext {
+	case ".info":
+		return h.serveInfo(w, version, commit)
+	case ".mod":
+		return h.serveMod(w, commit)
+	case ".zip":
+		return h.serveZip(w, version, commit)
+	}
+
+	return &notFoundError{"not found: " + file}
+}
+
+func (h *Handler) serveInfo(w http.ResponseWriter, version, commit string) error {
+	repo, err := h.repository(commit)
+	if err != nil {
+		return err
+	}
+
+	fi, err := repo.Stat("")
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(Info{Version: version, Time: fi.ModTime().UTC()})
+}
+
+func (h *Handler) serveMod(w http.ResponseWriter, commit string) error {
+	repo, err := h.repository(commit)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	f, err := repo.Open(path.Join(h.Dir, "go.mod"))
+	if os.IsNotExist(err) {
+		// as the go command synthesizes for modules without go.mod
+		_, err = fmt.Fprintf(w, "module %s\n", h.ModulePath)
+		return err
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (h *Handler) serveZip(w http.ResponseWriter, version, commit string) error {
+	repo, err := h.repository(commit)
+	if err != nil {
+		return err
+	}
+
+	var files []modzip.File
+	if err := repo.Walk(h.Dir, git.WalkOptions{}, func(name string, fi os.FileInfo, err error) error {
+		if err == nil && fi.Mode().IsRegular() {
+			files = append(files, zipFile{repo: repo, name: name, rel: strings.TrimPrefix(name, h.Dir+"/"), fi: fi})
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	return modzip.Create(w, module.Version{Path: h.ModulePath, Version: version}, files)
+}
+
+func (h *Handler) repository(commit string) (*git.Repository, error) {
+	base, err := h.base()
+	if err != nil {
+		return nil, err
+	}
+
+	return base.At(commit)
+}
+
+// base returns the Repository shared by requests, making it if not yet.
+func (h *Handler) base() (*git.Repository, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.repo == nil {
+		opts := append([]git.Option{git.WithModTimeMode(git.ModTimeCommitterDate)}, h.Options...)
+		repo, err := git.NewRepository("HEAD", h.GitDir, opts...)
+		if err != nil {
+			return nil, err
+		}
+		h.repo = repo
+	}
+
+	return h.repo, nil
+}
+
+// versions maps versions of the module to their commits.
+func (h *Handler) versions() (map[string]string, error) {
+	repo, err := h.base()
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := repo.Refs("refs/tags/")
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := ""
+	if h.Dir != "" {
+		prefix = h.Dir + "/"
+	}
+	_, pathMajor, _ := module.SplitPathVersion(h.ModulePath)
+
+	versions := map[string]string{}
+	for _, ref := range refs {
+		tag := ref.ShortName()
+		if !strings.HasPrefix(tag, prefix) {
+			continue
+		}
+
+		v := strings.TrimPrefix(tag, prefix)
+		if semver.Canonical(v) != v || semver.Build(v) != "" {
+			continue
+		}
+		if module.CheckPathMajor(v, pathMajor) != nil {
+			continue
+		}
+
+		versions[v] = ref.Commit
+	}
+
+	return versions, nil
+}
+
+// zipFile implements modzip.File.
+type zipFile struct {
+	repo *git.Repository
+	name string // in the repository
+	rel  string // to the module root
+	fi   os.FileInfo
+}
+
+func (f zipFile) Path() string { return f.rel }
+
+func (f zipFile) Lstat() (os.FileInfo, error) { return f.fi, nil }
+
+func (f zipFile) Open() (io.ReadCloser, error) {
+	return f.repo.Open(f.name)
+}
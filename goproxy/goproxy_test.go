@@ -0,0 +1,123 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestHandler(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("go.mod", "module example.com/m\n").
+		AddFile("m.go", "package m\n").
+		AddFile("internal/x/x.go", "package x\n").
+		AddFile("nested/go.mod", "module example.com/m/nested\n").
+		AddFile("nested/n.go", "package nested\n").
+		Commit("init").
+		Tag("v1.0.0").
+		Tag("latest").
+		Commit("rc")
+	r.Git("tag", "-a", "-m", "rc", "v1.1.0-rc.1")
+
+	s := httptest.NewServer(New(r.GitDir, "example.com/m"))
+	defer s.Close()
+
+	get := func(path string) (int, []byte) {
+		resp, err := http.Get(s.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp.StatusCode, body
+	}
+
+	status, body := get("/example.com/m/@v/list")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "v1.0.0\nv1.1.0-rc.1\n", string(body))
+
+	status, body = get("/example.com/m/@v/v1.0.0.info")
+	assert.Equal(t, http.StatusOK, status)
+	var info Info
+	require.NoError(t, json.Unmarshal(body, &info))
+	assert.Equal(t, "v1.0.0", info.Version)
+	assert.True(t, gittest.CommitTime(1).Equal(info.Time))
+
+	status, body = get("/example.com/m/@latest")
+	assert.Equal(t, http.StatusOK, status)
+	require.NoError(t, json.Unmarshal(body, &info))
+	assert.Equal(t, "v1.0.0", info.Version)
+
+	status, body = get("/example.com/m/@v/v1.0.0.mod")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "module example.com/m\n", string(body))
+
+	status, body = get("/example.com/m/@v/v1.0.0.zip")
+	require.Equal(t, http.StatusOK, status)
+
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	names := []string{}
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"example.com/m@v1.0.0/go.mod",
+		"example.com/m@v1.0.0/m.go",
+		"example.com/m@v1.0.0/internal/x/x.go",
+	}, names)
+
+	status, _ = get("/example.com/m/@v/v2.0.0.info")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = get("/example.com/other/@v/list")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestHandler_sharedRepository(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("go.mod", "module example.com/m\n").
+		Commit("init").
+		Tag("v1.0.0")
+
+	h := New(r.GitDir, "example.com/m")
+
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	_, body := get("/example.com/m/@v/list")
+	assert.Equal(t, "v1.0.0\n", body)
+
+	// tags made after the first request are served
+	r.AddFile("m.go", "package m\n").Commit("second").Tag("v1.1.0")
+
+	_, body = get("/example.com/m/@v/list")
+	assert.Equal(t, "v1.0.0\nv1.1.0\n", body)
+
+	status, body := get("/example.com/m/@v/v1.1.0.info")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"Version":"v1.1.0"`)
+
+	status, body = get("/example.com/m/@v/v9.0.0.zip")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Contains(t, body, "unknown version")
+
+	// details of failures are not sent
+	rec := httptest.NewRecorder()
+	New(t.TempDir(), "example.com/m").ServeHTTP(rec, httptest.NewRequest("GET", "/example.com/m/@v/list", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "500 Internal Server Error\n", rec.Body.String())
+}
@@ -0,0 +1,280 @@
+// Package httpapi serves JSON endpoints to browse a git repository at any
+// revision, for clients which cannot run git themselves.
+//
+//	GET /tree/<path>?rev=<rev>  lists a directory
+//	GET /stat/<path>?rev=<rev>  describes an entry
+//	GET /blob/<path>?rev=<rev>  streams the content of a file
+//
+// rev defaults to HEAD. Responses other than /blob are JSON objects, and
+// errors are reported as {"error": "..."}.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// Handler serves the API for the repository at GitDir.
+type Handler struct {
+	GitDir  string
+	Options []git.Option
+
+	mux  *http.ServeMux
+	mu   sync.Mutex
+	repo *git.Repository // made on the first request
+}
+
+// New returns a Handler for the repository at gitDir. opts are applied to
+// the Repository serving requests, which is made once and shared by them.
+func New(gitDir string, opts ...git.Option) *Handler {
+	h := &Handler{GitDir: gitDir, Options: opts}
+
+	h.mux = http.NewServeMux()
+	h.mux.Handle("/tree/", http.StripPrefix("/tree", h.handler(h.serveTree)))
+	h.mux.Handle("/stat/", http.StripPrefix("/stat", h.handler(h.serveStat)))
+	h.mux.Handle("/blob/", http.StripPrefix("/blob", h.handler(h.serveBlob)))
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Entry describes an entry of a tree.
+type Entry struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Type     string    `json:"type"` // "file", "dir", "symlink" or "submodule"
+	Mode     string    `json:"mode"` // git file mode in octal, such as "100644"
+	Size     int64     `json:"size"`
+	ObjectID string    `json:"oid"`
+	ModTime  time.Time `json:"mtime"`
+}
+
+// Tree is the response of /tree.
+type Tree struct {
+	Revision string  `json:"revision"`
+	Path     string  `json:"path"`
+	Entries  []Entry `json:"entries"`
+}
+
+// Stat is the response of /stat.
+type Stat struct {
+	Revision string `json:"revision"`
+	Entry
+	Target string `json:"target,omitempty"` // of a symlink
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+func (h *Handler) handler(serve func(w http.ResponseWriter, r *http.Request, repo *git.Repository, name string) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeError(w, &httpError{http.StatusMethodNotAllowed, fmt.Errorf("method not allowed: %s", r.Method)})
+			return
+		}
+
+		repo, err := h.repository(r.Context(), r.URL.Query().Get("rev"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		name := strings.Trim(path.Clean("/"+r.URL.Path), "/")
+
+		ok, err := repo.Exists(name)
+		if err == nil && !ok {
+			err = &httpError{http.StatusNotFound, fmt.Errorf("not found: %s", name)}
+		}
+		if err == nil {
+			err = serve(w, r, repo, name)
+		}
+		if err != nil {
+			writeError(w, err)
+		}
+	})
+}
+
+// repository returns a view of the shared Repository pinned to the commit
+// rev points to, so that a response is consistent even if rev moves while
+// it is served. Its git commands are killed once ctx, of the request, is
+// done.
+func (h *Handler) repository(ctx context.Context, rev string) (*git.Repository, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	base, err := h.base()
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := base.WithContext(ctx).At(rev)
+	if errors.Is(err, git.ErrUnknownRevision) {
+		return nil, &httpError{http.StatusNotFound, fmt.Errorf("unknown revision: %s", rev)}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// base returns the Repository shared by requests, making it if not yet.
+func (h *Handler) base() (*git.Repository, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.repo == nil {
+		repo, err := git.NewRepository("HEAD", h.GitDir, h.Options...)
+		if err != nil {
+			return nil, err
+		}
+		h.repo = repo
+	}
+
+	return h.repo, nil
+}
+
+func (h *Handler) serveTree(w http.ResponseWriter, r *http.Request, repo *git.Repository, name string) error {
+	fi, err := repo.Stat(name)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return &httpError{http.StatusBadRequest, fmt.Errorf("not a directory: %s", name)}
+	}
+
+	fis, err := repo.ReadDir(name)
+	if err != nil {
+		return err
+	}
+
+	tree := Tree{
+		Revision: repo.Revision,
+		Path:     name,
+		Entries:  make([]Entry, len(fis)),
+	}
+	for i, fi := range fis {
+		tree.Entries[i] = newEntry(path.Join(name, fi.Name()), fi)
+	}
+
+	return writeJSON(w, tree)
+}
+
+func (h *Handler) serveStat(w http.ResponseWriter, r *http.Request, repo *git.Repository, name string) error {
+	fi, err := repo.Lstat(name)
+	if err != nil {
+		return err
+	}
+
+	stat := Stat{
+		Revision: repo.Revision,
+		Entry:    newEntry(name, fi),
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		stat.Target, err = repo.Readlink(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeJSON(w, stat)
+}
+
+func (h *Handler) serveBlob(w http.ResponseWriter, r *http.Request, repo *git.Repository, name string) error {
+	fi, err := repo.Stat(name)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return &httpError{http.StatusBadRequest, fmt.Errorf("not a file: %s", name)}
+	}
+
+	f, err := repo.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(fi.Size(), 10))
+	w.Header().Set("X-Git-Revision", repo.Revision)
+	if oid, ok := git.ObjectID(fi); ok {
+		w.Header().Set("X-Git-Object-ID", oid)
+	}
+
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func newEntry(name string, fi os.FileInfo) Entry {
+	e := Entry{
+		Name:    fi.Name(),
+		Path:    name,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+	}
+
+	mode := fi.Mode()
+	switch {
+	case mode.IsDir():
+		e.Type, e.Mode, e.Size = "dir", "040000", 0
+	case mode&os.ModeSymlink != 0:
+		e.Type, e.Mode = "symlink", "120000"
+	case mode&os.ModeIrregular != 0:
+		e.Type, e.Mode = "submodule", "160000"
+	default:
+		e.Type, e.Mode = "file", fmt.Sprintf("100%03o", mode.Perm())
+	}
+
+	e.ObjectID, _ = git.ObjectID(fi)
+
+	return e
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(v)
+}
+
+// writeError reports err, keeping the details of other than httpError,
+// such as git's messages and paths of the server, in the log.
+func writeError(w http.ResponseWriter, err error) {
+	status, msg := http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+	if e, ok := err.(*httpError); ok {
+		status, msg = e.status, e.Error()
+	} else {
+		log.Printf("httpapi: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: msg})
+}
@@ -0,0 +1,131 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestHandler(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("LICENSE", "MIT License\n").
+		AddFile("git/git.go", "package git\n").
+		Commit("initial")
+
+	s := httptest.NewServer(New(r.GitDir, git.WithModTimeMode(git.ModTimeCommitterDate)))
+	defer s.Close()
+
+	get := func(path string) *http.Response {
+		resp, err := http.Get(s.URL + path)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("tree", func(t *testing.T) {
+		resp := get("/tree/git")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var tree Tree
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&tree))
+		assert.Equal(t, "git", tree.Path)
+		assert.Len(t, tree.Revision, 40)
+
+		var found bool
+		for _, e := range tree.Entries {
+			if e.Name == "git.go" {
+				found = true
+				assert.Equal(t, "git/git.go", e.Path)
+				assert.Equal(t, "file", e.Type)
+				assert.Equal(t, "100644", e.Mode)
+				assert.Len(t, e.ObjectID, 40)
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		resp := get("/stat/git?rev=HEAD")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var stat Stat
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&stat))
+		assert.Equal(t, "dir", stat.Type)
+		assert.Len(t, stat.ObjectID, 40)
+		assert.False(t, stat.ModTime.IsZero())
+	})
+
+	t.Run("blob", func(t *testing.T) {
+		resp := get("/blob/git/git.go")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Len(t, resp.Header.Get("X-Git-Object-ID"), 40)
+
+		content, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "package git")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		resp := get("/stat/no-such-file")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		var e errorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&e))
+		assert.Contains(t, e.Error, "no-such-file")
+
+		resp = get("/tree/?rev=no-such-revision")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("not a directory", func(t *testing.T) {
+		resp := get("/tree/LICENSE")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestHandler_movingRevision(t *testing.T) {
+	r := gittest.New(t).AddFile("README", "first\n").Commit("first").Tag("v1")
+
+	h := New(r.GitDir)
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	read := func(path string) string {
+		resp, err := http.Get(s.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	assert.Equal(t, "first\n", read("/blob/README"))
+	repo := h.repo
+
+	r.AddFile("README", "second\n").Commit("second")
+	assert.Equal(t, "second\n", read("/blob/README"))
+	assert.Equal(t, "first\n", read("/blob/README?rev=v1"))
+	assert.Same(t, repo, h.repo, "one Repository serves every request")
+}
+
+func TestHandler_internalError(t *testing.T) {
+	dir := t.TempDir()
+
+	rec := httptest.NewRecorder()
+	New(dir).ServeHTTP(rec, httptest.NewRequest("GET", "/tree/", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	var e errorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&e))
+	assert.Equal(t, "Internal Server Error", e.Error)
+	assert.NotContains(t, e.Error, dir)
+}
@@ -0,0 +1,159 @@
+// Package memfs provides an in-memory file system behaving like
+// git.Repository, so that code built on this module can be tested without
+// creating repositories.
+//
+//	fs := memfs.New(map[string]memfs.File{
+//		"README.md": {Content: "# hello\n"},
+//		"bin/run":   {Mode: 0755, Content: "#!/bin/sh\n"},
+//		"docs":      {Mode: os.ModeSymlink, Content: "README.md"},
+//	})
+package memfs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// File is a regular file or a symbolic link. Mode defaults to 0644; for
+// symbolic links Content is the target.
+type File struct {
+	Mode    os.FileMode
+	Content string
+}
+
+// FS is a read-only vfs.FileSystem of Files. Like git.Repository, Stat does
+// not follow symbolic links.
+type FS struct {
+	// ModTime is reported as the modification time of every entry.
+	ModTime time.Time
+
+	entries map[string]*entry
+	dirs    map[string][]os.FileInfo // sorted by name
+}
+
+var _ vfs.FileSystem = (*FS)(nil)
+
+type entry struct {
+	name string
+	File
+	fs *FS
+}
+
+// New returns an FS of files keyed by slash-separated paths. Parent
+// directories are added implicitly.
+func New(files map[string]File) *FS {
+	fs := &FS{
+		entries: map[string]*entry{},
+		dirs:    map[string][]os.FileInfo{},
+	}
+	fs.entries[""] = &entry{File: File{Mode: os.ModeDir | 0755}, fs: fs}
+
+	for name, f := range files {
+		name = clean(name)
+		if f.Mode == 0 {
+			f.Mode = 0644
+		}
+
+		for name != "" {
+			if _, ok := fs.entries[name]; ok {
+				break
+			}
+
+			e := &entry{name: path.Base(name), File: f, fs: fs}
+			fs.entries[name] = e
+
+			dir := clean(path.Dir(name))
+			fs.dirs[dir] = append(fs.dirs[dir], e)
+
+			name = dir
+			f = File{Mode: os.ModeDir | 0755}
+		}
+	}
+
+	for _, entries := range fs.dirs {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	}
+
+	return fs
+}
+
+func clean(name string) string {
+	return strings.Trim(path.Clean("/"+name), "/")
+}
+
+func (fs *FS) lookup(op, name string) (*entry, error) {
+	e, ok := fs.entries[clean(name)]
+	if !ok {
+		return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+
+	return e, nil
+}
+
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	return fs.lookup("lstat", name)
+}
+
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	return fs.lookup("lstat", name)
+}
+
+func (fs *FS) ReadDir(name string) ([]os.FileInfo, error) {
+	e, err := fs.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", name)
+	}
+
+	return append([]os.FileInfo{}, fs.dirs[clean(name)]...), nil
+}
+
+func (fs *FS) Open(name string) (vfs.ReadSeekCloser, error) {
+	e, err := fs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.Mode().IsRegular() {
+		return nil, fmt.Errorf("not a regular file: %s", name)
+	}
+
+	return nopCloser{strings.NewReader(e.Content)}, nil
+}
+
+// Readlink returns the target of the symbolic link at name.
+func (fs *FS) Readlink(name string) (string, error) {
+	e, err := fs.lookup("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	if e.Mode()&os.ModeSymlink == 0 {
+		return "", fmt.Errorf("not a symlink: %s", name)
+	}
+
+	return e.Content, nil
+}
+
+func (fs *FS) RootType(path string) vfs.RootType { return "" }
+
+func (fs *FS) String() string { return "memfs" }
+
+func (e *entry) Name() string       { return e.name }
+func (e *entry) Size() int64        { return int64(len(e.Content)) }
+func (e *entry) Mode() os.FileMode  { return e.File.Mode }
+func (e *entry) ModTime() time.Time { return e.fs.ModTime }
+func (e *entry) IsDir() bool        { return e.File.Mode.IsDir() }
+func (e *entry) Sys() interface{}   { return nil }
+
+type nopCloser struct {
+	*strings.Reader
+}
+
+func (nopCloser) Close() error { return nil }
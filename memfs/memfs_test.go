@@ -0,0 +1,46 @@
+package memfs
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/vcsfstest"
+)
+
+func TestConformance(t *testing.T) {
+	vcsfstest.Run(t, func(t *testing.T, files []vcsfstest.File) vfs.FileSystem {
+		m := map[string]File{}
+		for _, f := range files {
+			m[f.Path] = File{Mode: f.Mode, Content: f.Content}
+		}
+		return New(m)
+	})
+}
+
+func TestFS(t *testing.T) {
+	fs := New(map[string]File{
+		"/a/b.txt": {Content: "b"},
+		"c":        {Mode: 0755},
+	})
+	fs.ModTime = time.Unix(1000, 0)
+
+	fi, err := fs.Stat("a/b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), fi.Mode())
+	assert.Equal(t, time.Unix(1000, 0), fi.ModTime())
+
+	fi, err = fs.Stat("a")
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeDir|0755, fi.Mode())
+
+	entries, err := fs.ReadDir("/")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "a", entries[0].Name())
+	assert.Equal(t, os.FileMode(0755), entries[1].Mode())
+}
@@ -0,0 +1,124 @@
+// Package metrics exports metrics of git.Repository to Prometheus.
+//
+//	m := metrics.New("vcsfs")
+//	prometheus.MustRegister(m)
+//	repo, _ := git.NewRepository(rev, gitDir, git.WithObserver(m))
+//
+// One Metrics can observe any number of Repositories.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// Metrics implements git.Observer and prometheus.Collector.
+type Metrics struct {
+	gitExecs        *prometheus.CounterVec
+	gitExecDuration *prometheus.HistogramVec
+	cacheLookups    *prometheus.CounterVec
+	blobBytes       prometheus.Counter
+	openFiles       prometheus.Gauge
+}
+
+var (
+	_ git.Observer         = (*Metrics)(nil)
+	_ prometheus.Collector = (*Metrics)(nil)
+)
+
+// New returns Metrics whose names are prefixed by namespace.
+func New(namespace string) *Metrics {
+	return &Metrics{
+		gitExecs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "git_execs_total",
+			Help:      "Number of git commands run, by subcommand and status.",
+		}, []string{"command", "status"}),
+		gitExecDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "git_exec_duration_seconds",
+			Help:      "Time taken by git commands, by subcommand.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+		}, []string{"command"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Number of cache lookups, by cache and result (hit or miss).",
+		}, []string{"cache", "result"}),
+		blobBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "blob_bytes_total",
+			Help:      "Bytes of blobs read to open files.",
+		}),
+		openFiles: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "open_files",
+			Help:      "Number of files opened and not closed yet.",
+		}),
+	}
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.gitExecs.Describe(ch)
+	m.gitExecDuration.Describe(ch)
+	m.cacheLookups.Describe(ch)
+	m.blobBytes.Describe(ch)
+	m.openFiles.Describe(ch)
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.gitExecs.Collect(ch)
+	m.gitExecDuration.Collect(ch)
+	m.cacheLookups.Collect(ch)
+	m.blobBytes.Collect(ch)
+	m.openFiles.Collect(ch)
+}
+
+func (m *Metrics) GitExec(args []string, duration time.Duration, err error) {
+	command := subcommand(args)
+
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+
+	m.gitExecs.WithLabelValues(command, status).Inc()
+	m.gitExecDuration.WithLabelValues(command).Observe(duration.Seconds())
+}
+
+func (m *Metrics) CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	m.cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+func (m *Metrics) FileOpened(size int64) {
+	m.blobBytes.Add(float64(size))
+	m.openFiles.Inc()
+}
+
+func (m *Metrics) FileClosed() {
+	m.openFiles.Dec()
+}
+
+// subcommand returns the first argument which is not an option, keeping
+// the cardinality of the label low.
+func subcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-c" || args[i] == "-C":
+			i++
+		case len(args[i]) > 0 && args[i][0] == '-':
+		default:
+			return args[i]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New("vcsfs")
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(m))
+
+	r := gittest.New(t).AddFile("git/git.go", "package git\n").Commit("initial")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir, git.WithObserver(m))
+	require.NoError(t, err)
+
+	_, err = repo.ReadDir("git")
+	require.NoError(t, err)
+	_, err = repo.ReadDir("git")
+	require.NoError(t, err)
+
+	f, err := repo.Open("git/git.go")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.openFiles))
+	assert.True(t, testutil.ToFloat64(m.blobBytes) > 0)
+
+	f.Close()
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.openFiles))
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP vcsfs_cache_lookups_total Number of cache lookups, by cache and result (hit or miss).
+# TYPE vcsfs_cache_lookups_total counter
+vcsfs_cache_lookups_total{cache="tree",result="hit"} 4
+vcsfs_cache_lookups_total{cache="tree",result="miss"} 2
+# HELP vcsfs_git_execs_total Number of git commands run, by subcommand and status.
+# TYPE vcsfs_git_execs_total counter
+vcsfs_git_execs_total{command="cat-file",status="ok"} 1
+vcsfs_git_execs_total{command="ls-tree",status="ok"} 2
+vcsfs_git_execs_total{command="rev-parse",status="ok"} 1
+`), "vcsfs_cache_lookups_total", "vcsfs_git_execs_total"))
+}
+
+func TestSubcommand(t *testing.T) {
+	assert.Equal(t, "ls-tree", subcommand([]string{"ls-tree", "-z", "HEAD"}))
+	assert.Equal(t, "fetch", subcommand([]string{"-c", "http.extraHeader=x", "--no-pager", "fetch"}))
+	assert.Equal(t, "", subcommand(nil))
+}
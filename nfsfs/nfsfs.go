@@ -0,0 +1,152 @@
+// Package nfsfs exports a git.Repository read-only over NFSv3 using
+// github.com/willscott/go-nfs, for hosts where FUSE is unavailable.
+//
+//	l, _ := net.Listen("tcp", ":2049")
+//	nfsfs.Serve(l, repo)
+//
+// Clients can then mount it by
+//
+//	mount -t nfs -o vers=3,tcp,port=2049,mountport=2049,nolock,ro host:/ /mnt
+package nfsfs
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path"
+
+	"github.com/go-git/go-billy/v5"
+	nfs "github.com/willscott/go-nfs"
+	nfshelper "github.com/willscott/go-nfs/helpers"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// DefaultHandleCacheSize is the number of file handles Handler remembers.
+// Clients holding handles evicted from the cache get ESTALE.
+const DefaultHandleCacheSize = 1024
+
+// Serve serves repo over NFSv3 on l.
+func Serve(l net.Listener, repo *git.Repository) error {
+	return nfs.Serve(l, Handler(repo))
+}
+
+// Handler returns an nfs.Handler exporting repo without authentication.
+func Handler(repo *git.Repository) nfs.Handler {
+	return nfshelper.NewCachingHandler(nfshelper.NewNullAuthHandler(New(repo)), DefaultHandleCacheSize)
+}
+
+// FileSystem implements billy.Filesystem over a Repository. All
+// modifications fail with billy.ErrReadOnly.
+type FileSystem struct {
+	repo *git.Repository
+}
+
+var (
+	_ billy.Filesystem = (*FileSystem)(nil)
+	_ billy.Capable    = (*FileSystem)(nil)
+)
+
+// New returns a read-only billy.Filesystem serving repo.
+func New(repo *git.Repository) *FileSystem {
+	return &FileSystem{repo: repo}
+}
+
+// Capabilities reports the filesystem is not writable, which makes go-nfs
+// answer modifying requests with NFS3ERR_ROFS.
+func (fs *FileSystem) Capabilities() billy.Capability {
+	return billy.ReadCapability | billy.SeekCapability
+}
+
+func (fs *FileSystem) Open(filename string) (billy.File, error) {
+	if _, err := fs.repo.Stat(filename); err != nil {
+		return nil, err
+	}
+
+	f, err := fs.repo.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := f.(readSeekerAt)
+	if !ok {
+		content, err := io.ReadAll(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		r = bytes.NewReader(content)
+	}
+
+	return &file{name: filename, readSeekerAt: r, Closer: f}, nil
+}
+
+func (fs *FileSystem) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, billy.ErrReadOnly
+	}
+
+	return fs.Open(filename)
+}
+
+func (fs *FileSystem) Stat(filename string) (os.FileInfo, error) {
+	return fs.repo.Stat(filename)
+}
+
+func (fs *FileSystem) Lstat(filename string) (os.FileInfo, error) {
+	return fs.repo.Lstat(filename)
+}
+
+func (fs *FileSystem) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return fs.repo.ReadDir(dirname)
+}
+
+func (fs *FileSystem) Readlink(link string) (string, error) {
+	return fs.repo.Readlink(link)
+}
+
+func (fs *FileSystem) Join(elem ...string) string {
+	return path.Join(elem...)
+}
+
+func (fs *FileSystem) Root() string { return "/" }
+
+func (fs *FileSystem) Chroot(dir string) (billy.Filesystem, error) {
+	return nil, billy.ErrNotSupported
+}
+
+func (fs *FileSystem) Create(filename string) (billy.File, error) { return nil, billy.ErrReadOnly }
+
+func (fs *FileSystem) Rename(oldpath, newpath string) error { return billy.ErrReadOnly }
+
+func (fs *FileSystem) Remove(filename string) error { return billy.ErrReadOnly }
+
+func (fs *FileSystem) TempFile(dir, prefix string) (billy.File, error) {
+	return nil, billy.ErrReadOnly
+}
+
+func (fs *FileSystem) MkdirAll(filename string, perm os.FileMode) error { return billy.ErrReadOnly }
+
+func (fs *FileSystem) Symlink(target, link string) error { return billy.ErrReadOnly }
+
+type readSeekerAt interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+type file struct {
+	name string
+	readSeekerAt
+	io.Closer
+}
+
+func (f *file) Name() string { return f.name }
+
+func (f *file) Write(p []byte) (int, error) { return 0, billy.ErrReadOnly }
+
+func (f *file) Truncate(size int64) error { return billy.ErrReadOnly }
+
+func (f *file) Lock() error { return nil }
+
+func (f *file) Unlock() error { return nil }
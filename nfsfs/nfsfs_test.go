@@ -0,0 +1,51 @@
+package nfsfs
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestFileSystem(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("LICENSE", "MIT License\n").
+		AddFile("git/git.go", "package git\n\nfunc NewRepository() {}\n").
+		Commit("initial")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir, git.WithModTimeMode(git.ModTimeCommitterDate))
+	require.NoError(t, err)
+
+	fs := New(repo)
+
+	f, err := fs.Open(fs.Join("git", "git.go"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	buf := make([]byte, 11)
+	_, err = f.ReadAt(buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "package git", string(buf))
+
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "func NewRepository")
+
+	entries, err := fs.ReadDir("git")
+	require.NoError(t, err)
+	assert.NotEmpty(t, entries)
+
+	_, err = fs.Stat("no-such-file")
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = fs.OpenFile("LICENSE", os.O_RDWR, 0)
+	assert.Equal(t, billy.ErrReadOnly, err)
+
+	assert.False(t, billy.CapabilityCheck(fs, billy.WriteCapability))
+}
@@ -0,0 +1,168 @@
+package ninep
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// message types of 9P2000
+const (
+	tversion = 100
+	rversion = 101
+	tauth    = 102
+	tattach  = 104
+	rattach  = 105
+	rerror   = 107
+	tflush   = 108
+	rflush   = 109
+	twalk    = 110
+	rwalk    = 111
+	topen    = 112
+	ropen    = 113
+	tcreate  = 114
+	tread    = 116
+	rread    = 117
+	twrite   = 118
+	tclunk   = 120
+	rclunk   = 121
+	tremove  = 122
+	tstat    = 124
+	rstat    = 125
+	twstat   = 126
+)
+
+const (
+	qtDir     = 0x80
+	qtSymlink = 0x02
+	qtFile    = 0x00
+
+	dmDir     = 0x80000000
+	dmSymlink = 0x02000000
+
+	noTag = 0xFFFF
+	noFid = 0xFFFFFFFF
+
+	oTrunc  = 0x10
+	oRclose = 0x40
+)
+
+const headerSize = 4 + 1 + 2 // size[4] type[1] tag[2]
+
+var errShortMessage = errors.New("short message")
+
+type qid struct {
+	typ     uint8
+	version uint32
+	path    uint64
+}
+
+// decoder reads fields of a message in order, remembering the first error.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.buf) < n {
+		d.err = errShortMessage
+		return nil
+	}
+
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) u8() uint8 {
+	if b := d.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) u16() uint16 {
+	if b := d.take(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) u32() uint32 {
+	if b := d.take(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) u64() uint64 {
+	if b := d.take(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) str() string {
+	n := d.u16()
+	return string(d.take(int(n)))
+}
+
+// encoder builds a message.
+type encoder struct {
+	buf []byte
+}
+
+func newMessage(typ uint8, tag uint16) *encoder {
+	e := &encoder{buf: make([]byte, 4, 64)} // size is filled by bytes
+	e.u8(typ)
+	e.u16(tag)
+	return e
+}
+
+func (e *encoder) u8(v uint8) { e.buf = append(e.buf, v) }
+
+func (e *encoder) u16(v uint16) { e.buf = binary.LittleEndian.AppendUint16(e.buf, v) }
+
+func (e *encoder) u32(v uint32) { e.buf = binary.LittleEndian.AppendUint32(e.buf, v) }
+
+func (e *encoder) u64(v uint64) { e.buf = binary.LittleEndian.AppendUint64(e.buf, v) }
+
+func (e *encoder) str(s string) {
+	e.u16(uint16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) qid(q qid) {
+	e.u8(q.typ)
+	e.u32(q.version)
+	e.u64(q.path)
+}
+
+func (e *encoder) bytes() []byte {
+	binary.LittleEndian.PutUint32(e.buf, uint32(len(e.buf)))
+	return e.buf
+}
+
+// readMessage reads a whole message, rejecting ones larger than msize.
+func readMessage(r io.Reader, msize uint32) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.LittleEndian.Uint32(size[:])
+	if n < headerSize || n > msize {
+		return nil, errors.New("invalid message size")
+	}
+
+	buf := make([]byte, n)
+	copy(buf, size[:])
+	if _, err := io.ReadFull(r, buf[4:]); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
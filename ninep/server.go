@@ -0,0 +1,430 @@
+// Package ninep implements a read-only 9P2000 (Styx) file server over
+// a git.Repository, so that revisions can be mounted by plan9port,
+// v9fs on Linux guests and other 9P clients without FUSE.
+//
+//	l, _ := net.Listen("tcp", ":5640")
+//	ninep.NewServer(repo).Serve(l)
+//
+// Linux clients can then mount it by
+//
+//	mount -t 9p -o trans=tcp,port=5640,version=9p2000 host /mnt
+package ninep
+
+import (
+	"errors"
+	"hash/fnv"
+	"io"
+	"net"
+	"os"
+	"path"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// maxMessageSize is the largest msize the server negotiates.
+const maxMessageSize = 64 * 1024
+
+// Server serves a Repository over 9P2000.
+type Server struct {
+	repo *git.Repository
+}
+
+// NewServer returns a Server for repo.
+func NewServer(repo *git.Repository) *Server {
+	return &Server{repo: repo}
+}
+
+// Serve accepts connections on l and serves each of them in a goroutine.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn serves a single connection until it is closed.
+func (s *Server) ServeConn(rwc io.ReadWriteCloser) error {
+	defer rwc.Close()
+
+	c := &conn{
+		server: s,
+		msize:  maxMessageSize,
+		fids:   map[uint32]*fid{},
+	}
+
+	for {
+		msg, err := readMessage(rwc, c.msize)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if _, err := rwc.Write(c.handle(msg)); err != nil {
+			return err
+		}
+	}
+}
+
+type conn struct {
+	server *Server
+	msize  uint32
+	fids   map[uint32]*fid
+}
+
+type fid struct {
+	path string
+	fi   os.FileInfo
+
+	open    bool
+	content []byte   // of an opened file
+	dirents [][]byte // encoded stats of entries of an opened directory
+	dirPos  uint64   // offset next directory read must start at
+	dirIdx  int      // index of the entry at dirPos
+}
+
+var (
+	errUnknownFid = errors.New("unknown fid")
+	errFidInUse   = errors.New("fid already in use")
+	errReadOnly   = errors.New("read-only file system")
+	errNotExist   = errors.New("file does not exist")
+	errIsOpen     = errors.New("fid is open")
+	errNotOpen    = errors.New("fid is not open")
+	errBadOffset  = errors.New("bad offset in directory read")
+)
+
+func errorMessage(tag uint16, err error) []byte {
+	m := newMessage(rerror, tag)
+	m.str(err.Error())
+	return m.bytes()
+}
+
+func (c *conn) handle(msg []byte) []byte {
+	d := &decoder{buf: msg[4:]}
+	typ := d.u8()
+	tag := d.u16()
+
+	var (
+		resp []byte
+		err  error
+	)
+	switch typ {
+	case tversion:
+		resp, err = c.version(tag, d)
+	case tauth:
+		err = errors.New("authentication not required")
+	case tattach:
+		resp, err = c.attach(tag, d)
+	case tflush:
+		resp = newMessage(rflush, tag).bytes()
+	case twalk:
+		resp, err = c.walk(tag, d)
+	case topen:
+		resp, err = c.openFid(tag, d)
+	case tread:
+		resp, err = c.read(tag, d)
+	case tclunk:
+		delete(c.fids, d.u32())
+		resp = newMessage(rclunk, tag).bytes()
+	case tremove:
+		// the fid is clunked even if remove fails
+		delete(c.fids, d.u32())
+		err = errReadOnly
+	case tstat:
+		resp, err = c.stat(tag, d)
+	case tcreate, twrite, twstat:
+		err = errReadOnly
+	default:
+		err = errors.New("unknown message type")
+	}
+
+	if err == nil && d.err != nil {
+		err = d.err
+	}
+	if err != nil {
+		return errorMessage(tag, err)
+	}
+
+	return resp
+}
+
+func (c *conn) version(tag uint16, d *decoder) ([]byte, error) {
+	msize := d.u32()
+	version := d.str()
+
+	if msize < c.msize {
+		c.msize = msize
+	}
+
+	// a new session begins
+	c.fids = map[uint32]*fid{}
+
+	if len(version) < 6 || version[:6] != "9P2000" {
+		version = "unknown"
+	} else {
+		version = "9P2000"
+	}
+
+	m := newMessage(rversion, tag)
+	m.u32(c.msize)
+	m.str(version)
+	return m.bytes(), nil
+}
+
+func (c *conn) attach(tag uint16, d *decoder) ([]byte, error) {
+	fidNum := d.u32()
+	if _, ok := c.fids[fidNum]; ok {
+		return nil, errFidInUse
+	}
+
+	f, err := c.newFid("")
+	if err != nil {
+		return nil, err
+	}
+	c.fids[fidNum] = f
+
+	m := newMessage(rattach, tag)
+	m.qid(qidOf(f.path, f.fi))
+	return m.bytes(), nil
+}
+
+func (c *conn) newFid(name string) (*fid, error) {
+	ok, err := c.server.repo.Exists(name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errNotExist
+	}
+
+	fi, err := c.server.repo.Lstat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fid{path: name, fi: fi}, nil
+}
+
+func (c *conn) walk(tag uint16, d *decoder) ([]byte, error) {
+	fidNum, newFidNum := d.u32(), d.u32()
+	names := make([]string, d.u16())
+	for i := range names {
+		names[i] = d.str()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	f, ok := c.fids[fidNum]
+	if !ok {
+		return nil, errUnknownFid
+	}
+	if f.open {
+		return nil, errIsOpen
+	}
+	if _, ok := c.fids[newFidNum]; ok && newFidNum != fidNum {
+		return nil, errFidInUse
+	}
+
+	cur := f
+	qids := []qid{}
+	for i, name := range names {
+		if !cur.fi.IsDir() {
+			if i == 0 {
+				return nil, errors.New("not a directory")
+			}
+			break
+		}
+
+		next, err := c.newFid(path.Clean(path.Join("/", cur.path, name))[1:])
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			break
+		}
+
+		cur = next
+		qids = append(qids, qidOf(cur.path, cur.fi))
+	}
+
+	if len(qids) == len(names) {
+		c.fids[newFidNum] = &fid{path: cur.path, fi: cur.fi}
+	}
+
+	m := newMessage(rwalk, tag)
+	m.u16(uint16(len(qids)))
+	for _, q := range qids {
+		m.qid(q)
+	}
+	return m.bytes(), nil
+}
+
+func (c *conn) openFid(tag uint16, d *decoder) ([]byte, error) {
+	fidNum, mode := d.u32(), d.u8()
+
+	f, ok := c.fids[fidNum]
+	if !ok {
+		return nil, errUnknownFid
+	}
+	if f.open {
+		return nil, errIsOpen
+	}
+
+	// only OREAD (0) and OEXEC (3) are allowed
+	if access := mode & 3; (access != 0 && access != 3) || mode&(oTrunc|oRclose) != 0 {
+		return nil, errReadOnly
+	}
+
+	if err := f.load(c.server.repo); err != nil {
+		return nil, err
+	}
+
+	f.open = true
+
+	m := newMessage(ropen, tag)
+	m.qid(qidOf(f.path, f.fi))
+	m.u32(c.msize - 24)
+	return m.bytes(), nil
+}
+
+func (f *fid) load(repo *git.Repository) error {
+	if !f.fi.IsDir() {
+		r, err := repo.Open(f.path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		f.content, err = io.ReadAll(r)
+		return err
+	}
+
+	entries, err := repo.ReadDir(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.dirents = make([][]byte, len(entries))
+	for i, e := range entries {
+		f.dirents[i] = encodeStat(path.Join(f.path, e.Name()), e)
+	}
+
+	return nil
+}
+
+func (c *conn) read(tag uint16, d *decoder) ([]byte, error) {
+	fidNum, offset, count := d.u32(), d.u64(), d.u32()
+
+	f, ok := c.fids[fidNum]
+	if !ok {
+		return nil, errUnknownFid
+	}
+	if !f.open {
+		return nil, errNotOpen
+	}
+
+	if max := c.msize - 24; count > max {
+		count = max
+	}
+
+	var data []byte
+	if f.fi.IsDir() {
+		if offset == 0 {
+			f.dirPos, f.dirIdx = 0, 0
+		} else if offset != f.dirPos {
+			return nil, errBadOffset
+		}
+
+		// entries are not split across reads
+		for f.dirIdx < len(f.dirents) && len(data)+len(f.dirents[f.dirIdx]) <= int(count) {
+			data = append(data, f.dirents[f.dirIdx]...)
+			f.dirIdx++
+		}
+		f.dirPos += uint64(len(data))
+	} else if offset < uint64(len(f.content)) {
+		end := offset + uint64(count)
+		if end > uint64(len(f.content)) {
+			end = uint64(len(f.content))
+		}
+		data = f.content[offset:end]
+	}
+
+	m := newMessage(rread, tag)
+	m.u32(uint32(len(data)))
+	m.buf = append(m.buf, data...)
+	return m.bytes(), nil
+}
+
+func (c *conn) stat(tag uint16, d *decoder) ([]byte, error) {
+	f, ok := c.fids[d.u32()]
+	if !ok {
+		return nil, errUnknownFid
+	}
+
+	stat := encodeStat(f.path, f.fi)
+
+	m := newMessage(rstat, tag)
+	m.u16(uint16(len(stat)))
+	m.buf = append(m.buf, stat...)
+	return m.bytes(), nil
+}
+
+func qidOf(name string, fi os.FileInfo) qid {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+
+	q := qid{typ: qtFile, path: h.Sum64()}
+	if fi.IsDir() {
+		q.typ = qtDir
+	} else if fi.Mode()&os.ModeSymlink != 0 {
+		q.typ = qtSymlink
+	}
+
+	return q
+}
+
+// encodeStat encodes fi in the stat format of 9P2000.
+func encodeStat(name string, fi os.FileInfo) []byte {
+	mode := uint32(fi.Mode().Perm())
+	length := uint64(fi.Size())
+	if fi.IsDir() {
+		mode |= dmDir
+		length = 0
+	} else if fi.Mode()&os.ModeSymlink != 0 {
+		mode |= dmSymlink
+	}
+
+	baseName := fi.Name()
+	if name == "" {
+		baseName = "/"
+	}
+
+	mtime := uint32(fi.ModTime().Unix())
+	if fi.ModTime().IsZero() {
+		mtime = 0
+	}
+
+	e := &encoder{buf: make([]byte, 2, 64)} // size[2] is filled below
+	e.u16(0)                                // type
+	e.u32(0)                                // dev
+	e.qid(qidOf(name, fi))
+	e.u32(mode)
+	e.u32(mtime) // atime
+	e.u32(mtime)
+	e.u64(length)
+	e.str(baseName)
+	e.str("git") // uid
+	e.str("git") // gid
+	e.str("git") // muid
+
+	size := len(e.buf) - 2
+	e.buf[0], e.buf[1] = byte(size), byte(size>>8)
+
+	return e.buf
+}
@@ -0,0 +1,172 @@
+package ninep
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+}
+
+func (c *testClient) rpc(m *encoder, want uint8) *decoder {
+	_, err := c.conn.Write(m.bytes())
+	require.NoError(c.t, err)
+
+	resp, err := readMessage(c.conn, maxMessageSize)
+	require.NoError(c.t, err)
+
+	d := &decoder{buf: resp[4:]}
+	typ := d.u8()
+	d.u16() // tag
+	if typ == rerror && want != rerror {
+		c.t.Fatalf("unexpected error: %s", d.str())
+	}
+	require.Equal(c.t, want, typ)
+
+	return d
+}
+
+func TestServer(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("LICENSE", "MIT License\n").
+		AddFile("git/attr.go", "package git\n").
+		AddFile("git/git.go", "package git\n").
+		Commit("initial")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir, git.WithModTimeMode(git.ModTimeCommitterDate))
+	require.NoError(t, err)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go NewServer(repo).ServeConn(server)
+
+	c := &testClient{t: t, conn: client}
+
+	m := newMessage(tversion, noTag)
+	m.u32(8192)
+	m.str("9P2000")
+	d := c.rpc(m, rversion)
+	assert.EqualValues(t, 8192, d.u32())
+	assert.Equal(t, "9P2000", d.str())
+
+	m = newMessage(tattach, 1)
+	m.u32(0)     // fid
+	m.u32(noFid) // afid
+	m.str("user")
+	m.str("")
+	d = c.rpc(m, rattach)
+	assert.EqualValues(t, qtDir, d.u8())
+
+	walk := func(fid, newFid uint32, names ...string) *encoder {
+		m := newMessage(twalk, 1)
+		m.u32(fid)
+		m.u32(newFid)
+		m.u16(uint16(len(names)))
+		for _, name := range names {
+			m.str(name)
+		}
+		return m
+	}
+
+	t.Run("file", func(t *testing.T) {
+		d := c.rpc(walk(0, 1, "git", "git.go"), rwalk)
+		assert.EqualValues(t, 2, d.u16())
+
+		m := newMessage(topen, 1)
+		m.u32(1)
+		m.u8(0)
+		c.rpc(m, ropen)
+
+		m = newMessage(tread, 1)
+		m.u32(1)
+		m.u64(0)
+		m.u32(11)
+		d = c.rpc(m, rread)
+		n := d.u32()
+		assert.Equal(t, "package git", string(d.take(int(n))))
+
+		m = newMessage(tstat, 1)
+		m.u32(1)
+		d = c.rpc(m, rstat)
+		d.u16() // n
+		d.u16() // size
+		d.u16() // type
+		d.u32() // dev
+		d.take(13)
+		assert.Zero(t, d.u32()&dmDir)
+		d.u32() // atime
+		d.u32() // mtime
+		assert.NotZero(t, d.u64())
+		assert.Equal(t, "git.go", d.str())
+
+		m = newMessage(tclunk, 1)
+		m.u32(1)
+		c.rpc(m, rclunk)
+	})
+
+	t.Run("write", func(t *testing.T) {
+		c.rpc(walk(0, 2, "LICENSE"), rwalk)
+
+		m := newMessage(topen, 1)
+		m.u32(2)
+		m.u8(1) // OWRITE
+		d := c.rpc(m, rerror)
+		assert.Equal(t, "read-only file system", d.str())
+
+		m = newMessage(tremove, 1)
+		m.u32(2)
+		c.rpc(m, rerror)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		d := c.rpc(walk(0, 3, "no-such-file"), rerror)
+		assert.Equal(t, "file does not exist", d.str())
+
+		// partial walks do not create the new fid
+		d = c.rpc(walk(0, 3, "git", "no-such-file"), rwalk)
+		assert.EqualValues(t, 1, d.u16())
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		c.rpc(walk(0, 4, "git"), rwalk)
+
+		m := newMessage(topen, 1)
+		m.u32(4)
+		m.u8(0)
+		c.rpc(m, ropen)
+
+		names := []string{}
+		var offset uint64
+		for {
+			m := newMessage(tread, 1)
+			m.u32(4)
+			m.u64(offset)
+			m.u32(512)
+			d := c.rpc(m, rread)
+			n := d.u32()
+			if n == 0 {
+				break
+			}
+			offset += uint64(n)
+
+			data := &decoder{buf: d.take(int(n))}
+			for len(data.buf) > 0 {
+				size := data.u16()
+				stat := &decoder{buf: data.take(int(size))}
+				stat.take(2 + 4 + 13 + 4 + 4 + 4 + 8)
+				names = append(names, stat.str())
+			}
+		}
+
+		assert.Contains(t, names, "git.go")
+		assert.IsIncreasing(t, names)
+	})
+}
@@ -0,0 +1,273 @@
+// Package rawfs provides a file system of a repository on a public forge
+// which reads the first few files through the raw content endpoint of the
+// forge, such as raw.githubusercontent.com, and clones the repository with
+// git.NewFromURL only when more is needed, so that a tool wanting a go.mod
+// or two does not pay for a clone.
+//
+//	fs, _ := rawfs.New("https://github.com/motemen/go-vcs-fs", "v1.2.3", cacheDir)
+//	f, _ := fs.Open("go.mod")
+//
+// Only Open takes the fast path; Stat, Lstat, ReadDir and Readlink, and
+// Open once the raw fetches are used up, go to the clone. The revision
+// should be a tag or a commit ID: a branch may move between a raw fetch
+// and the clone.
+package rawfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// DefaultMaxRawFiles is the number of files read through the raw content
+// endpoint before cloning, unless WithMaxRawFiles is given.
+const DefaultMaxRawFiles = 8
+
+// Option configures an FS created by New.
+type Option func(*FS)
+
+// WithHTTPClient sets the client for raw content requests. It defaults to
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(fs *FS) {
+		fs.client = client
+	}
+}
+
+// WithMaxRawFiles sets the number of files read through the raw content
+// endpoint before cloning. Zero disables the fast path.
+func WithMaxRawFiles(n int) Option {
+	return func(fs *FS) {
+		fs.maxRawFiles = n
+	}
+}
+
+// WithGitOptions passes opts to git.NewFromURL.
+func WithGitOptions(opts ...git.Option) Option {
+	return func(fs *FS) {
+		fs.gitOpts = append(fs.gitOpts, opts...)
+	}
+}
+
+// FS is a vfs.FileSystem of a revision of a remote repository.
+type FS struct {
+	url      string
+	revision string
+	cacheDir string
+
+	client      *http.Client
+	maxRawFiles int
+	gitOpts     []git.Option
+	rawURL      func(name string) string // nil if the forge is not known
+
+	mu       sync.Mutex
+	rawFiles int
+	repo     *git.Repository
+}
+
+var _ vfs.FileSystem = (*FS)(nil)
+
+// New returns an FS of revision of the repository at repoURL, cloned under
+// cacheDir if needed. Repositories on github.com and gitlab.com have the
+// fast path; others are cloned on first access.
+func New(repoURL, revision, cacheDir string, opts ...Option) (*FS, error) {
+	if revision == "" {
+		revision = "HEAD"
+	}
+
+	fs := &FS{
+		url:         repoURL,
+		revision:    revision,
+		cacheDir:    cacheDir,
+		client:      http.DefaultClient,
+		maxRawFiles: DefaultMaxRawFiles,
+	}
+	for _, opt := range opts {
+		opt(fs)
+	}
+
+	rawURL, err := rawURLFunc(repoURL, revision)
+	if err != nil {
+		return nil, err
+	}
+	fs.rawURL = rawURL
+
+	return fs, nil
+}
+
+// rawURLFunc returns the function making the raw content URL of a file for
+// the forge hosting repoURL, or nil if the forge is not known.
+func rawURLFunc(repoURL, revision string) (func(string) string, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, nil
+	}
+
+	repoPath := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	rev := escapePath(revision)
+
+	switch u.Host {
+	case "github.com":
+		if strings.Count(repoPath, "/") != 1 {
+			return nil, fmt.Errorf("not a GitHub repository: %s", repoURL)
+		}
+		return func(name string) string {
+			return "https://raw.githubusercontent.com/" + repoPath + "/" + rev + "/" + escapePath(name)
+		}, nil
+
+	case "gitlab.com":
+		return func(name string) string {
+			return "https://gitlab.com/" + repoPath + "/-/raw/" + rev + "/" + escapePath(name)
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func escapePath(name string) string {
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// repository returns the clone, making it on first use.
+func (fs *FS) repository() (*git.Repository, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.repo == nil {
+		repo, err := git.NewFromURL(fs.url, fs.revision, fs.cacheDir, fs.gitOpts...)
+		if err != nil {
+			return nil, err
+		}
+		fs.repo = repo
+	}
+
+	return fs.repo, nil
+}
+
+// takeRawFile reports whether a file may be read through the raw content
+// endpoint, counting it if so.
+func (fs *FS) takeRawFile() bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.rawURL == nil || fs.repo != nil || fs.rawFiles >= fs.maxRawFiles {
+		return false
+	}
+
+	fs.rawFiles++
+	return true
+}
+
+// openRaw reads name through the raw content endpoint. It reports false if
+// the file could not be read so, in which case the clone knows better: the
+// name may be a directory, for instance.
+func (fs *FS) openRaw(name string) (vfs.ReadSeekCloser, bool) {
+	resp, err := fs.client.Get(fs.rawURL(name))
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false
+	}
+
+	return nopCloser{bytes.NewReader(b)}, true
+}
+
+// rawName cleans name into a path from the root for the raw content
+// endpoint. It reports false for the root itself and for names escaping
+// it, which would reach other repositories on the forge.
+func rawName(name string) (string, bool) {
+	name = path.Clean(strings.TrimLeft(name, "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+
+	return name, true
+}
+
+func (fs *FS) Open(name string) (vfs.ReadSeekCloser, error) {
+	if raw, ok := rawName(name); ok && fs.takeRawFile() {
+		if f, ok := fs.openRaw(raw); ok {
+			return f, nil
+		}
+	}
+
+	repo, err := fs.repository()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.Open(name)
+}
+
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	repo, err := fs.repository()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.Lstat(name)
+}
+
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	repo, err := fs.repository()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.Stat(name)
+}
+
+func (fs *FS) ReadDir(name string) ([]os.FileInfo, error) {
+	repo, err := fs.repository()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.ReadDir(name)
+}
+
+// Readlink returns the target of the symbolic link at name.
+func (fs *FS) Readlink(name string) (string, error) {
+	repo, err := fs.repository()
+	if err != nil {
+		return "", err
+	}
+
+	return repo.Readlink(name)
+}
+
+func (fs *FS) RootType(path string) vfs.RootType { return "" }
+
+func (fs *FS) String() string { return "rawfs(" + fs.url + "@" + fs.revision + ")" }
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
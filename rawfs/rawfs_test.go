@@ -0,0 +1,90 @@
+package rawfs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func readFile(t *testing.T, fs vfs.FileSystem, name string) string {
+	t.Helper()
+
+	f, err := fs.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestFS(t *testing.T) {
+	r := gittest.New(t).
+		AddFile("go.mod", "module example.com/m\n").
+		AddFile("a.go", "package m\n").
+		AddFile("dir/b.go", "package dir\n").
+		Commit("initial").Tag("v1")
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.URL.Path)
+		if req.URL.Path == "/v1/go.mod" {
+			io.WriteString(w, "module example.com/m\n")
+			return
+		}
+		http.NotFound(w, req)
+	}))
+	defer srv.Close()
+
+	fs, err := New(r.Dir, "v1", t.TempDir(), WithMaxRawFiles(2))
+	require.NoError(t, err)
+	fs.rawURL = func(name string) string { return srv.URL + "/v1/" + escapePath(name) }
+
+	// names escaping the root never reach the forge
+	_, err = fs.Open("../../evil/repo/v1/go.mod")
+	assert.Error(t, err)
+	assert.Empty(t, requests)
+
+	fs, err = New(r.Dir, "v1", t.TempDir(), WithMaxRawFiles(2))
+	require.NoError(t, err)
+	fs.rawURL = func(name string) string { return srv.URL + "/v1/" + escapePath(name) }
+
+	assert.Equal(t, "module example.com/m\n", readFile(t, fs, "dir/../go.mod"))
+	assert.Nil(t, fs.repo, "not cloned")
+
+	// not found on the forge, read from the clone
+	assert.Equal(t, "package m\n", readFile(t, fs, "a.go"))
+	assert.NotNil(t, fs.repo)
+
+	assert.Equal(t, "package dir\n", readFile(t, fs, "dir/b.go"))
+	assert.Equal(t, []string{"/v1/go.mod", "/v1/a.go"}, requests)
+
+	entries, err := fs.ReadDir("/")
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
+
+func TestRawURLFunc(t *testing.T) {
+	f, err := rawURLFunc("https://github.com/motemen/go-vcs-fs.git", "v1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "https://raw.githubusercontent.com/motemen/go-vcs-fs/v1.2.3/dir/a%20b.go", f("/dir/a b.go"))
+
+	f, err = rawURLFunc("https://gitlab.com/group/sub/proj", "main")
+	require.NoError(t, err)
+	assert.Equal(t, "https://gitlab.com/group/sub/proj/-/raw/main/README.md", f("README.md"))
+
+	f, err = rawURLFunc("https://example.com/x/y", "main")
+	require.NoError(t, err)
+	assert.Nil(t, f)
+
+	_, err = rawURLFunc("https://github.com/motemen", "main")
+	assert.True(t, err != nil && strings.Contains(err.Error(), "GitHub"))
+}
@@ -0,0 +1,268 @@
+package rawfs
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultCacheBytes is the size of responses a Transport keeps unless
+// MaxBytes is set.
+const DefaultCacheBytes = 64 << 20
+
+// DefaultMaxWait is the longest a Transport waits for a rate limit to be
+// lifted unless MaxWait is set.
+const DefaultMaxWait = time.Minute
+
+// Transport is an http.RoundTripper for the endpoints of forges which
+// caches responses to GET requests by URL, revalidating them with
+// If-None-Match or If-Modified-Since so that a not modified response does
+// not count against the quota of the API, and waits out rate limits told by
+// Retry-After or X-RateLimit-Reset (RateLimit-Reset on GitLab). Share one
+// Transport among the FSs of a process:
+//
+//	client := &http.Client{Transport: &rawfs.Transport{}}
+//	fs, _ := rawfs.New(url, rev, cacheDir, rawfs.WithHTTPClient(client))
+type Transport struct {
+	// Base makes the requests. It defaults to http.DefaultTransport.
+	Base http.RoundTripper
+	// MaxBytes bounds the size of responses cached, evicting the oldest.
+	// Zero means DefaultCacheBytes.
+	MaxBytes int64
+	// MaxWait is the longest to wait for a rate limit to be lifted before
+	// returning the response telling it. Zero means DefaultMaxWait.
+	MaxWait time.Duration
+
+	mu           sync.Mutex
+	entries      map[string]*cachedResponse
+	order        []string // keys of entries, oldest first
+	bytes        int64
+	blockedUntil time.Time // when the rate limit is lifted
+}
+
+type cachedResponse struct {
+	header http.Header
+	body   []byte
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.waitRateLimit(req); err != nil {
+		return nil, err
+	}
+
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.roundTrip(req)
+	}
+
+	key := req.URL.String()
+
+	t.mu.Lock()
+	cached := t.entries[key]
+	t.mu.Unlock()
+
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lm := cached.header.Get("Last-Modified"); lm != "" {
+			req.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := t.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		resp.Body.Close()
+		return cached.response(req), nil
+
+	case resp.StatusCode == http.StatusOK && (resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""):
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		t.store(key, &cachedResponse{header: resp.Header.Clone(), body: body})
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return resp, nil
+}
+
+// roundTrip makes req, retrying once if it is rejected by a rate limit
+// which is lifted soon enough.
+func (t *Transport) roundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.observeRateLimit(resp)
+
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return resp, nil
+	}
+
+	t.mu.Lock()
+	wait := time.Until(t.blockedUntil)
+	t.mu.Unlock()
+	if wait > t.maxWait() || req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	if err := sleep(req, wait); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if req.GetBody != nil {
+		req = req.Clone(req.Context())
+		if req.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.base().RoundTrip(req)
+}
+
+func (t *Transport) maxWait() time.Duration {
+	if t.MaxWait > 0 {
+		return t.MaxWait
+	}
+	return DefaultMaxWait
+}
+
+// observeRateLimit records when requests may be made again, if resp tells
+// the rate limit is reached.
+func (t *Transport) observeRateLimit(resp *http.Response) {
+	var until time.Time
+
+	if s := resp.Header.Get("Retry-After"); s != "" && resp.StatusCode >= 400 {
+		if secs, err := strconv.Atoi(s); err == nil {
+			until = time.Now().Add(time.Duration(secs) * time.Second)
+		} else if tm, err := http.ParseTime(s); err == nil {
+			until = tm
+		}
+	} else if remaining(resp.Header) == "0" {
+		if secs, err := strconv.ParseInt(reset(resp.Header), 10, 64); err == nil {
+			until = time.Unix(secs, 0)
+		}
+	}
+
+	if until.IsZero() {
+		return
+	}
+
+	t.mu.Lock()
+	if until.After(t.blockedUntil) {
+		t.blockedUntil = until
+	}
+	t.mu.Unlock()
+}
+
+func remaining(h http.Header) string {
+	if s := h.Get("X-RateLimit-Remaining"); s != "" {
+		return s
+	}
+	return h.Get("RateLimit-Remaining")
+}
+
+func reset(h http.Header) string {
+	if s := h.Get("X-RateLimit-Reset"); s != "" {
+		return s
+	}
+	return h.Get("RateLimit-Reset")
+}
+
+// waitRateLimit waits until the rate limit is lifted, unless it takes
+// longer than MaxWait, in which case the request is made to be rejected.
+func (t *Transport) waitRateLimit(req *http.Request) error {
+	t.mu.Lock()
+	wait := time.Until(t.blockedUntil)
+	t.mu.Unlock()
+
+	if wait <= 0 || wait > t.maxWait() {
+		return nil
+	}
+
+	return sleep(req, wait)
+}
+
+func sleep(req *http.Request, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (t *Transport) store(key string, c *cachedResponse) {
+	maxBytes := t.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultCacheBytes
+	}
+	if int64(len(c.body)) > maxBytes {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.entries == nil {
+		t.entries = map[string]*cachedResponse{}
+	}
+
+	if old, ok := t.entries[key]; ok {
+		t.bytes -= int64(len(old.body))
+	} else {
+		t.order = append(t.order, key)
+	}
+	t.entries[key] = c
+	t.bytes += int64(len(c.body))
+
+	for t.bytes > maxBytes {
+		oldest := t.order[0]
+		t.order = t.order[1:]
+		t.bytes -= int64(len(t.entries[oldest].body))
+		delete(t.entries, oldest)
+	}
+}
+
+func (c *cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(c.body)),
+		ContentLength: int64(len(c.body)),
+		Request:       req,
+	}
+}
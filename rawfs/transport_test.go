@@ -0,0 +1,100 @@
+package rawfs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(b)
+}
+
+func TestTransport_revalidate(t *testing.T) {
+	var full, notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		io.WriteString(w, "content")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{}}
+
+	for range 3 {
+		status, body := get(t, client, srv.URL+"/a")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "content", body)
+	}
+
+	assert.Equal(t, int32(1), full.Load())
+	assert.Equal(t, int32(2), notModified.Load())
+}
+
+func TestTransport_evict(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		w.Header().Set("ETag", `"x"`)
+		if req.Header.Get("If-None-Match") != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "12345")
+	}))
+	defer srv.Close()
+
+	tr := &Transport{MaxBytes: 8}
+	client := &http.Client{Transport: tr}
+
+	get(t, client, srv.URL+"/a")
+	get(t, client, srv.URL+"/b")
+
+	assert.Len(t, tr.entries, 1)
+	assert.Contains(t, tr.entries, srv.URL+"/b")
+	assert.Equal(t, int64(5), tr.bytes)
+}
+
+func TestTransport_rateLimit(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{}}
+
+	status, body := get(t, client, srv.URL)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", body)
+	assert.Equal(t, int32(2), requests.Load())
+
+	// a limit lifted later than MaxWait is not waited for
+	requests.Store(0)
+	client = &http.Client{Transport: &Transport{MaxWait: 1}}
+	status, _ = get(t, client, srv.URL)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+}
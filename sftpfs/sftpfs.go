@@ -0,0 +1,136 @@
+// Package sftpfs implements pkg/sftp request server handlers over
+// a git.Repository, offering read-only SFTP access to a revision.
+//
+//	server := sftp.NewRequestServer(channel, sftpfs.Handlers(repo))
+//	err := server.Serve()
+package sftpfs
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// Handlers returns sftp.Handlers serving repo. Requests to modify
+// anything are denied.
+func Handlers(repo *git.Repository) sftp.Handlers {
+	h := &handler{repo: repo}
+	return sftp.Handlers{
+		FileGet:  h,
+		FilePut:  h,
+		FileCmd:  h,
+		FileList: h,
+	}
+}
+
+type handler struct {
+	repo *git.Repository
+}
+
+func (h *handler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	if _, err := h.repo.Stat(r.Filepath); err != nil {
+		return nil, err
+	}
+
+	f, err := h.repo.Open(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	if ra, ok := f.(io.ReaderAt); ok {
+		return readerAt{ReaderAt: ra, Closer: f}, nil
+	}
+
+	return &seekReaderAt{f: f}, nil
+}
+
+func (h *handler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	return nil, sftp.ErrSSHFxPermissionDenied
+}
+
+func (h *handler) Filecmd(r *sftp.Request) error {
+	return sftp.ErrSSHFxPermissionDenied
+}
+
+func (h *handler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	switch r.Method {
+	case "List":
+		entries, err := h.repo.ReadDir(r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt(entries), nil
+
+	case "Stat":
+		fi, err := h.repo.Stat(r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{fi}, nil
+
+	case "Readlink":
+		target, err := h.repo.Readlink(r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{linkTarget(target)}, nil
+	}
+
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// linkTarget is returned for Readlink requests; only its Name is used.
+type linkTarget string
+
+func (t linkTarget) Name() string       { return string(t) }
+func (t linkTarget) Size() int64        { return 0 }
+func (t linkTarget) Mode() os.FileMode  { return os.ModeSymlink | 0777 }
+func (t linkTarget) ModTime() time.Time { return time.Time{} }
+func (t linkTarget) IsDir() bool        { return false }
+func (t linkTarget) Sys() interface{}   { return nil }
+
+type readerAt struct {
+	io.ReaderAt
+	io.Closer
+}
+
+// seekReaderAt implements io.ReaderAt over an io.ReadSeeker.
+type seekReaderAt struct {
+	mu sync.Mutex
+	f  io.ReadSeekCloser
+}
+
+func (r *seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.f.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return io.ReadFull(r.f, p)
+}
+
+func (r *seekReaderAt) Close() error {
+	return r.f.Close()
+}
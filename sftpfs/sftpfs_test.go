@@ -0,0 +1,67 @@
+package sftpfs
+
+import (
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func newClient(t *testing.T) *sftp.Client {
+	r := gittest.New(t).
+		AddFile("README.md", "# project\n").
+		AddFile("git/git.go", "package git\n").
+		Commit("initial")
+
+	repo, err := git.NewRepository("HEAD", r.GitDir)
+	require.NoError(t, err)
+
+	c1, c2 := net.Pipe()
+	server := sftp.NewRequestServer(c1, Handlers(repo))
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+
+	client, err := sftp.NewClientPipe(c2, c2)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestHandlers(t *testing.T) {
+	client := newClient(t)
+
+	entries, err := client.ReadDir("/git")
+	require.NoError(t, err)
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Contains(t, names, "git.go")
+
+	fi, err := client.Stat("/git")
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	f, err := client.Open("/git/git.go")
+	require.NoError(t, err)
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	f.Close()
+	assert.Contains(t, string(content), "package git")
+
+	_, err = client.Stat("/nonexistent")
+	assert.True(t, os.IsNotExist(err), "%v", err)
+
+	_, err = client.Create("/new.txt")
+	assert.Error(t, err)
+
+	assert.Error(t, client.Remove("/README.md"))
+}
@@ -0,0 +1,263 @@
+// Package unionfs mounts several file systems, typically git.Repository
+// values at different revisions and with their own options, under prefixes
+// of a single read-only tree, giving build tools a virtual monorepo.
+//
+//	a, _ := git.NewRepository("v1.4.0", "/src/svc-a.git")
+//	b, _ := git.NewRepository("main", "/src/svc-b.git", git.WithExclude("testdata/"))
+//	fs, _ := unionfs.New(
+//		unionfs.Mount{Prefix: "svc-a", FS: a},
+//		unionfs.Mount{Prefix: "services/svc-b", FS: b},
+//	)
+//
+// Directories leading to a prefix, such as "services" above, are
+// synthesized.
+package unionfs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// Mount is a file system served under Prefix. The empty Prefix mounts it
+// at the root, where it serves everything not under another prefix.
+type Mount struct {
+	Prefix string
+	FS     vfs.FileSystem
+}
+
+// FS is a vfs.FileSystem of Mounts. A path is served by the mount of the
+// longest prefix containing it, so mounts may be nested.
+type FS struct {
+	mounts []Mount // cleaned, sorted by prefix length descending
+}
+
+var _ vfs.FileSystem = (*FS)(nil)
+
+// New returns an FS of mounts. It fails if two of them share a prefix.
+func New(mounts ...Mount) (*FS, error) {
+	fs := &FS{mounts: make([]Mount, len(mounts))}
+
+	seen := map[string]bool{}
+	for i, m := range mounts {
+		if m.FS == nil {
+			return nil, fmt.Errorf("nil file system mounted at %q", m.Prefix)
+		}
+
+		prefix := clean(m.Prefix)
+		if seen[prefix] {
+			return nil, fmt.Errorf("duplicate mount prefix: %q", m.Prefix)
+		}
+		seen[prefix] = true
+
+		fs.mounts[i] = Mount{Prefix: prefix, FS: m.FS}
+	}
+
+	sort.SliceStable(fs.mounts, func(i, j int) bool {
+		return len(fs.mounts[i].Prefix) > len(fs.mounts[j].Prefix)
+	})
+
+	return fs, nil
+}
+
+func clean(name string) string {
+	return strings.Trim(path.Clean("/"+name), "/")
+}
+
+// under reports whether name is prefix or inside it, and the path of name
+// relative to prefix.
+func under(name, prefix string) (string, bool) {
+	switch {
+	case prefix == "":
+		return name, true
+	case name == prefix:
+		return "", true
+	case strings.HasPrefix(name, prefix+"/"):
+		return name[len(prefix)+1:], true
+	}
+
+	return "", false
+}
+
+// lookup returns the mount serving name and the path of name in it.
+func (fs *FS) lookup(name string) (*Mount, string, bool) {
+	for i := range fs.mounts {
+		if rel, ok := under(name, fs.mounts[i].Prefix); ok {
+			return &fs.mounts[i], rel, true
+		}
+	}
+
+	return nil, "", false
+}
+
+// isParent reports whether name is a directory leading to a mount prefix.
+func (fs *FS) isParent(name string) bool {
+	for _, m := range fs.mounts {
+		if rel, ok := under(m.Prefix, name); ok && rel != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (fs *FS) stat(op, name string, stat func(vfs.FileSystem, string) (os.FileInfo, error)) (os.FileInfo, error) {
+	name = clean(name)
+
+	if m, rel, ok := fs.lookup(name); ok {
+		fi, err := stat(m.FS, rel)
+		if err == nil {
+			if rel == "" && m.Prefix != "" {
+				return renamed{fi, path.Base("/" + name)}, nil
+			}
+			return fi, nil
+		}
+		if !fs.isParent(name) {
+			return nil, err
+		}
+	}
+
+	if name == "" || fs.isParent(name) {
+		return dirInfo(path.Base("/" + name)), nil
+	}
+
+	return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
+
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	return fs.stat("lstat", name, vfs.FileSystem.Lstat)
+}
+
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	return fs.stat("stat", name, vfs.FileSystem.Stat)
+}
+
+// ReadDir lists the entries of name served by its mount, along with the
+// mounts and synthesized directories directly under it, which shadow
+// entries of the same names.
+func (fs *FS) ReadDir(name string) ([]os.FileInfo, error) {
+	name = clean(name)
+
+	var children []string
+	for _, m := range fs.mounts {
+		if rel, ok := under(m.Prefix, name); ok && rel != "" {
+			child, _, _ := strings.Cut(rel, "/")
+			children = append(children, child)
+		}
+	}
+
+	var entries []os.FileInfo
+	if m, rel, ok := fs.lookup(name); ok {
+		var err error
+		entries, err = m.FS.ReadDir(rel)
+		if err != nil && len(children) == 0 {
+			return nil, err
+		}
+	} else if len(children) == 0 && name != "" {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: os.ErrNotExist}
+	}
+
+	if len(children) == 0 {
+		return entries, nil
+	}
+
+	byName := map[string]os.FileInfo{}
+	for _, e := range entries {
+		byName[e.Name()] = e
+	}
+	for _, child := range children {
+		fi, err := fs.Lstat(path.Join(name, child))
+		if err != nil {
+			return nil, err
+		}
+		byName[child] = fi
+	}
+
+	entries = make([]os.FileInfo, 0, len(byName))
+	for _, e := range byName {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	return entries, nil
+}
+
+func (fs *FS) Open(name string) (vfs.ReadSeekCloser, error) {
+	name = clean(name)
+
+	if m, rel, ok := fs.lookup(name); ok {
+		f, err := m.FS.Open(rel)
+		if err == nil || !fs.isParent(name) {
+			return f, err
+		}
+	}
+
+	if name == "" || fs.isParent(name) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	}
+
+	return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+// Readlink returns the target of the symbolic link at name, if the file
+// system mounted there supports reading links.
+func (fs *FS) Readlink(name string) (string, error) {
+	name = clean(name)
+
+	m, rel, ok := fs.lookup(name)
+	if !ok {
+		if fs.isParent(name) {
+			return "", fmt.Errorf("not a symlink: %s", name)
+		}
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+	}
+
+	rl, ok := m.FS.(interface{ Readlink(string) (string, error) })
+	if !ok {
+		return "", fmt.Errorf("readlink not supported by %s", m.FS)
+	}
+
+	return rl.Readlink(rel)
+}
+
+func (fs *FS) RootType(name string) vfs.RootType {
+	if m, rel, ok := fs.lookup(clean(name)); ok {
+		return m.FS.RootType(rel)
+	}
+
+	return ""
+}
+
+func (fs *FS) String() string {
+	names := make([]string, len(fs.mounts))
+	for i, m := range fs.mounts {
+		names[i] = "/" + m.Prefix + "=" + m.FS.String()
+	}
+	sort.Strings(names)
+
+	return "unionfs(" + strings.Join(names, ", ") + ")"
+}
+
+// renamed is the root of a mounted file system, named after its prefix.
+type renamed struct {
+	os.FileInfo
+	name string
+}
+
+func (fi renamed) Name() string { return fi.name }
+
+// dirInfo is a synthesized directory.
+type dirInfo string
+
+func (fi dirInfo) Name() string       { return string(fi) }
+func (fi dirInfo) Size() int64        { return 0 }
+func (fi dirInfo) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (fi dirInfo) ModTime() time.Time { return time.Time{} }
+func (fi dirInfo) IsDir() bool        { return true }
+func (fi dirInfo) Sys() interface{}   { return nil }
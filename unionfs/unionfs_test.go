@@ -0,0 +1,121 @@
+package unionfs
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+	"github.com/motemen/go-vcs-fs/memfs"
+	"github.com/motemen/go-vcs-fs/vcsfstest"
+)
+
+func TestConformance(t *testing.T) {
+	vcsfstest.Run(t, func(t *testing.T, files []vcsfstest.File) vfs.FileSystem {
+		m := map[string]memfs.File{}
+		for _, f := range files {
+			m[f.Path] = memfs.File{Mode: f.Mode, Content: f.Content}
+		}
+
+		fs, err := New(Mount{FS: memfs.New(m)})
+		require.NoError(t, err)
+		return fs
+	})
+}
+
+func readFile(t *testing.T, fs vfs.FileSystem, name string) string {
+	t.Helper()
+
+	f, err := fs.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func names(entries []os.FileInfo) []string {
+	ns := make([]string, len(entries))
+	for i, e := range entries {
+		ns[i] = e.Name()
+	}
+	return ns
+}
+
+func TestFS_repositories(t *testing.T) {
+	a := gittest.New(t).AddFile("main.go", "package a // v1\n").Commit("v1").Tag("v1.0.0").
+		AddFile("main.go", "package a // v2\n").Commit("v2")
+	b := gittest.New(t).AddFile("lib.go", "package b\n").AddFile("testdata/big", "x").Commit("initial")
+
+	repoA, err := git.NewRepository("v1.0.0", a.GitDir)
+	require.NoError(t, err)
+	repoB, err := git.NewRepository("main", b.GitDir, git.WithExclude("testdata/"))
+	require.NoError(t, err)
+
+	fs, err := New(
+		Mount{Prefix: "svc-a", FS: repoA},
+		Mount{Prefix: "/services/svc-b/", FS: repoB},
+		Mount{FS: memfs.New(map[string]memfs.File{"WORKSPACE": {Content: "ws"}, "services/README": {}})},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "package a // v1\n", readFile(t, fs, "svc-a/main.go"))
+	assert.Equal(t, "package b\n", readFile(t, fs, "services/svc-b/lib.go"))
+	assert.Equal(t, "ws", readFile(t, fs, "/WORKSPACE"))
+
+	entries, err := fs.ReadDir("/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"WORKSPACE", "services", "svc-a"}, names(entries))
+
+	entries, err = fs.ReadDir("services")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README", "svc-b"}, names(entries))
+
+	entries, err = fs.ReadDir("services/svc-b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lib.go"}, names(entries))
+
+	fi, err := fs.Stat("services/svc-b")
+	require.NoError(t, err)
+	assert.Equal(t, "svc-b", fi.Name())
+	assert.True(t, fi.IsDir())
+
+	_, err = fs.Stat("services/svc-b/testdata")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = fs.Stat("svc-c")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFS_synthesized(t *testing.T) {
+	fs, err := New(Mount{Prefix: "a/b/c", FS: memfs.New(map[string]memfs.File{"f": {Content: "f"}})})
+	require.NoError(t, err)
+
+	fi, err := fs.Stat("a/b")
+	require.NoError(t, err)
+	assert.Equal(t, "b", fi.Name())
+	assert.True(t, fi.IsDir())
+
+	entries, err := fs.ReadDir("a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, names(entries))
+
+	_, err = fs.Open("a")
+	assert.Error(t, err)
+
+	_, err = fs.ReadDir("b")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.Equal(t, "f", readFile(t, fs, "a/b/c/f"))
+}
+
+func TestNew_duplicate(t *testing.T) {
+	m := memfs.New(nil)
+	_, err := New(Mount{Prefix: "a", FS: m}, Mount{Prefix: "/a/", FS: m})
+	assert.Error(t, err)
+}
@@ -0,0 +1,170 @@
+// Package vcsfs opens a revision of a repository given by URL as
+// a vfs.FileSystem, picking the backend from the URL as go get does.
+//
+//	fs, _ := vcsfs.OpenURL("https://github.com/motemen/go-vcs-fs@v1.2.3")
+//
+// Only git is supported for now; URLs of other VCSes are recognized and
+// fail with ErrUnsupportedVCS.
+package vcsfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// ErrUnsupportedVCS is returned by OpenURL for a URL of a VCS without
+// a backend.
+var ErrUnsupportedVCS = errors.New("unsupported VCS")
+
+// Option configures OpenURL.
+type Option func(*config)
+
+type config struct {
+	cacheDir string
+	gitOpts  []git.Option
+}
+
+// WithCacheDir sets the directory clones of remote repositories are kept
+// in. It defaults to "go-vcs-fs" under os.UserCacheDir.
+func WithCacheDir(dir string) Option {
+	return func(c *config) {
+		c.cacheDir = dir
+	}
+}
+
+// WithGitOptions passes opts to the constructor of a git repository.
+func WithGitOptions(opts ...git.Option) Option {
+	return func(c *config) {
+		c.gitOpts = append(c.gitOpts, opts...)
+	}
+}
+
+// Location is a repository URL taken apart by ParseURL.
+type Location struct {
+	VCS      string // "git", "hg" or "svn"
+	URL      string // without the VCS prefix and the revision
+	Revision string // "HEAD" if not given
+	Local    bool   // URL is a path on this machine
+}
+
+// ParseURL parses rawURL of the forms:
+//
+//	https://host/path[@rev]    also http://, ssh://, git:// and git+ssh://
+//	user@host:path[@rev]       scp-like syntax of ssh
+//	file:///path[@rev]         or a path of the file system
+//	vcs::url[@rev]             forces the VCS, one of git, hg and svn
+//
+// The revision follows the first "@" of the path part, so it may contain
+// slashes, as in "https://host/repo@feature/x".
+func ParseURL(rawURL string) (Location, error) {
+	loc := Location{Revision: "HEAD"}
+
+	if vcs, rest, ok := strings.Cut(rawURL, "::"); ok && !strings.ContainsAny(vcs, "/:@") {
+		switch vcs {
+		case "git", "hg", "svn":
+			loc.VCS = vcs
+		default:
+			return Location{}, fmt.Errorf("%w: %s", ErrUnsupportedVCS, vcs)
+		}
+		rawURL = rest
+	}
+
+	if rawURL == "" {
+		return Location{}, fmt.Errorf("empty repository URL")
+	}
+
+	// the index where the path part starts
+	pathStart := 0
+	if scheme, rest, ok := strings.Cut(rawURL, "://"); ok {
+		switch scheme {
+		case "https", "http", "ssh", "git", "git+ssh":
+		case "svn", "svn+ssh":
+			if loc.VCS == "" {
+				loc.VCS = "svn"
+			}
+		case "file":
+			loc.Local = true
+		default:
+			return Location{}, fmt.Errorf("unknown URL scheme: %s", scheme)
+		}
+		pathStart = len(scheme) + len("://")
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			pathStart += i
+		} else {
+			pathStart = len(rawURL)
+		}
+	} else if i := strings.IndexByte(rawURL, ':'); i > 0 && !strings.ContainsRune(rawURL[:i], '/') {
+		// user@host:path
+		pathStart = i + 1
+	} else {
+		loc.Local = true
+	}
+
+	if i := strings.IndexByte(rawURL[pathStart:], '@'); i >= 0 {
+		loc.Revision = rawURL[pathStart+i+1:]
+		rawURL = rawURL[:pathStart+i]
+		if loc.Revision == "" {
+			return Location{}, fmt.Errorf("empty revision in URL: %s", rawURL)
+		}
+	}
+
+	loc.URL = rawURL
+	if loc.VCS == "" {
+		loc.VCS = "git"
+	}
+
+	return loc, nil
+}
+
+// OpenURL returns the file system of the revision of the repository at
+// rawURL, in a syntax ParseURL accepts. Remote git repositories are
+// opened with git.NewFromURL and local ones in place.
+func OpenURL(rawURL string, opts ...Option) (vfs.FileSystem, error) {
+	loc, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if loc.VCS != "git" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedVCS, loc.VCS)
+	}
+
+	if loc.Local {
+		dir := strings.TrimPrefix(loc.URL, "file://")
+		if fi, err := os.Stat(filepath.Join(dir, ".git")); err == nil && fi.IsDir() {
+			dir = filepath.Join(dir, ".git")
+		}
+		repo, err := git.NewRepository(loc.Revision, dir, c.gitOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	}
+
+	if c.cacheDir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		c.cacheDir = filepath.Join(dir, "go-vcs-fs")
+	}
+
+	repo, err := git.NewFromURL(loc.URL, loc.Revision, c.cacheDir, c.gitOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
@@ -0,0 +1,64 @@
+package vcsfs
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Location
+	}{
+		{"https://github.com/x/y@v1.2.3", Location{VCS: "git", URL: "https://github.com/x/y", Revision: "v1.2.3"}},
+		{"https://github.com/x/y", Location{VCS: "git", URL: "https://github.com/x/y", Revision: "HEAD"}},
+		{"https://user@example.com/x/y@feature/a", Location{VCS: "git", URL: "https://user@example.com/x/y", Revision: "feature/a"}},
+		{"ssh://git@github.com/x/y@main", Location{VCS: "git", URL: "ssh://git@github.com/x/y", Revision: "main"}},
+		{"git@github.com:x/y@main", Location{VCS: "git", URL: "git@github.com:x/y", Revision: "main"}},
+		{"file:///srv/repo@v1", Location{VCS: "git", URL: "file:///srv/repo", Revision: "v1", Local: true}},
+		{"/srv/repo", Location{VCS: "git", URL: "/srv/repo", Revision: "HEAD", Local: true}},
+		{"hg::https://example.com/repo@default", Location{VCS: "hg", URL: "https://example.com/repo", Revision: "default"}},
+		{"svn::svn://example.com/trunk", Location{VCS: "svn", URL: "svn://example.com/trunk", Revision: "HEAD"}},
+		{"svn://example.com/trunk", Location{VCS: "svn", URL: "svn://example.com/trunk", Revision: "HEAD"}},
+		{"svn+ssh://example.com/repo@1234", Location{VCS: "svn", URL: "svn+ssh://example.com/repo", Revision: "1234"}},
+	}
+
+	for _, test := range tests {
+		loc, err := ParseURL(test.in)
+		if assert.NoError(t, err, test.in) {
+			assert.Equal(t, test.want, loc, test.in)
+		}
+	}
+
+	for _, in := range []string{"", "ftp://example.com/x", "cvs::x", "https://example.com/x@"} {
+		_, err := ParseURL(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestOpenURL(t *testing.T) {
+	r := gittest.New(t).AddFile("a", "1").Commit("first").Tag("v1").
+		AddFile("a", "2").Commit("second")
+
+	for _, url := range []string{r.Dir + "@v1", "file://" + r.Dir + "@v1"} {
+		fs, err := OpenURL(url)
+		require.NoError(t, err, url)
+
+		f, err := fs.Open("a")
+		require.NoError(t, err)
+		b, err := io.ReadAll(f)
+		f.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "1", string(b), url)
+	}
+
+	for _, url := range []string{"hg::https://example.com/repo", "svn://example.com/trunk", "svn+ssh://example.com/repo"} {
+		_, err := OpenURL(url)
+		assert.ErrorIs(t, err, ErrUnsupportedVCS, url)
+	}
+}
@@ -0,0 +1,344 @@
+// Package vcsfstest provides a conformance test suite for file systems
+// serving a revision of a version-controlled tree, such as git.Repository.
+//
+// A backend proves itself by building a tree holding Fixture and passing
+// it to Run:
+//
+//	func TestConformance(t *testing.T) {
+//		vcsfstest.Run(t, func(t *testing.T, files []vcsfstest.File) vfs.FileSystem {
+//			return newBackendFor(t, files)
+//		})
+//	}
+package vcsfstest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// File is an entry of a tree. Directories are implied by the paths of
+// files in them.
+type File struct {
+	Path    string      // slash-separated, relative to the root
+	Mode    os.FileMode // 0644, 0755 or os.ModeSymlink
+	Content string      // the target for symbolic links
+}
+
+// LargeFileSize is the size of "large.bin" in Fixture.
+const LargeFileSize = 1 << 20
+
+// Fixture is the tree the suite expects.
+var Fixture = []File{
+	{Path: "README.md", Mode: 0644, Content: "# fixture\n"},
+	{Path: "empty", Mode: 0644, Content: ""},
+	{Path: "script.sh", Mode: 0755, Content: "#!/bin/sh\necho hello\n"},
+	{Path: "large.bin", Mode: 0644, Content: largeContent()},
+	{Path: "dir/a.txt", Mode: 0644, Content: "a\n"},
+	{Path: "dir/b.txt", Mode: 0644, Content: "b\n"},
+	{Path: "dir/sub/deep/leaf.txt", Mode: 0644, Content: "leaf\n"},
+	{Path: "dir/Z.txt", Mode: 0644, Content: "Z\n"},
+	{Path: "link", Mode: os.ModeSymlink, Content: "dir/a.txt"},
+	{Path: "dangling", Mode: os.ModeSymlink, Content: "nowhere"},
+	{Path: "日本語/ファイル.txt", Mode: 0644, Content: "こんにちは\n"},
+	{Path: "with space.txt", Mode: 0644, Content: "space\n"},
+}
+
+func largeContent() string {
+	b := make([]byte, LargeFileSize)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	return string(b)
+}
+
+// Run runs the suite against the file system returned by newFS, which is
+// called once with Fixture.
+func Run(t *testing.T, newFS func(t *testing.T, files []File) vfs.FileSystem) {
+	fs := newFS(t, Fixture)
+
+	t.Run("Root", func(t *testing.T) { testRoot(t, fs) })
+	t.Run("Stat", func(t *testing.T) { testStat(t, fs) })
+	t.Run("Paths", func(t *testing.T) { testPaths(t, fs) })
+	t.Run("ReadDir", func(t *testing.T) { testReadDir(t, fs) })
+	t.Run("Open", func(t *testing.T) { testOpen(t, fs) })
+	t.Run("LargeFile", func(t *testing.T) { testLargeFile(t, fs) })
+	t.Run("Symlink", func(t *testing.T) { testSymlink(t, fs) })
+	t.Run("Unicode", func(t *testing.T) { testUnicode(t, fs) })
+	t.Run("NotExist", func(t *testing.T) { testNotExist(t, fs) })
+	t.Run("Errors", func(t *testing.T) { testErrors(t, fs) })
+}
+
+func testRoot(t *testing.T, fs vfs.FileSystem) {
+	for _, name := range []string{"", "/", "."} {
+		fi, err := fs.Stat(name)
+		require.NoError(t, err, "%q", name)
+		assert.True(t, fi.IsDir(), "%q", name)
+		assert.True(t, fi.Mode().IsDir(), "%q", name)
+	}
+}
+
+func testStat(t *testing.T, fs vfs.FileSystem) {
+	for _, f := range Fixture {
+		if f.Mode&os.ModeSymlink != 0 {
+			continue
+		}
+
+		fi, err := fs.Stat(f.Path)
+		if !assert.NoError(t, err, f.Path) {
+			continue
+		}
+
+		assert.Equal(t, path.Base(f.Path), fi.Name(), f.Path)
+		assert.Equal(t, int64(len(f.Content)), fi.Size(), f.Path)
+		assert.True(t, fi.Mode().IsRegular(), f.Path)
+		assert.False(t, fi.IsDir(), f.Path)
+		assert.Equal(t, f.Mode&0100 != 0, fi.Mode()&0100 != 0, "%s: executable bit", f.Path)
+	}
+
+	for _, name := range []string{"dir", "dir/sub", "dir/sub/deep", "日本語"} {
+		fi, err := fs.Stat(name)
+		if assert.NoError(t, err, name) {
+			assert.True(t, fi.IsDir(), name)
+			assert.Equal(t, path.Base(name), fi.Name())
+		}
+	}
+}
+
+func testPaths(t *testing.T, fs vfs.FileSystem) {
+	for _, name := range []string{"dir/a.txt", "/dir/a.txt"} {
+		fi, err := fs.Stat(name)
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, "a.txt", fi.Name(), name)
+		}
+	}
+
+	for _, name := range []string{"dir", "/dir", "dir/", "/dir/"} {
+		fi, err := fs.Stat(name)
+		if assert.NoError(t, err, name) {
+			assert.True(t, fi.IsDir(), name)
+		}
+
+		entries, err := fs.ReadDir(name)
+		if assert.NoError(t, err, name) {
+			assert.Len(t, entries, 4, name)
+		}
+	}
+}
+
+func testReadDir(t *testing.T, fs vfs.FileSystem) {
+	for _, dir := range []string{"", "dir", "dir/sub", "日本語"} {
+		entries, err := fs.ReadDir(dir)
+		if !assert.NoError(t, err, dir) {
+			continue
+		}
+
+		names := make([]string, len(entries))
+		for i, fi := range entries {
+			names[i] = fi.Name()
+		}
+
+		assert.Equal(t, childNames(dir), names, "%q: entries sorted by name", dir)
+	}
+
+	entries, err := fs.ReadDir("dir")
+	require.NoError(t, err)
+	for _, fi := range entries {
+		assert.Equal(t, fi.Name() == "sub", fi.IsDir(), fi.Name())
+	}
+}
+
+// childNames returns the sorted names of entries directly under dir in
+// Fixture.
+func childNames(dir string) []string {
+	seen := map[string]bool{}
+	names := []string{}
+
+	for _, f := range Fixture {
+		rest := f.Path
+		if dir != "" {
+			if !strings.HasPrefix(rest, dir+"/") {
+				continue
+			}
+			rest = strings.TrimPrefix(rest, dir+"/")
+		}
+
+		name := strings.SplitN(rest, "/", 2)[0]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func testOpen(t *testing.T, fs vfs.FileSystem) {
+	for _, f := range Fixture {
+		if f.Mode&os.ModeSymlink != 0 || f.Path == "large.bin" {
+			continue
+		}
+
+		content, err := vfs.ReadFile(fs, f.Path)
+		if assert.NoError(t, err, f.Path) {
+			assert.Equal(t, f.Content, string(content), f.Path)
+		}
+	}
+
+	r, err := fs.Open("script.sh")
+	require.NoError(t, err)
+	defer r.Close()
+
+	pos, err := r.Seek(10, io.SeekStart)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), pos)
+
+	rest, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "echo hello\n", string(rest))
+
+	pos, err = r.Seek(-6, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), pos)
+
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(r, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+}
+
+func testLargeFile(t *testing.T, fs vfs.FileSystem) {
+	fi, err := fs.Stat("large.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(LargeFileSize), fi.Size())
+
+	content, err := vfs.ReadFile(fs, "large.bin")
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal([]byte(largeContent()), content), "content of large.bin")
+}
+
+func testSymlink(t *testing.T, fs vfs.FileSystem) {
+	for _, name := range []string{"link", "dangling"} {
+		fi, err := fs.Lstat(name)
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, os.ModeSymlink, fi.Mode().Type(), name)
+			assert.Equal(t, name, fi.Name())
+		}
+	}
+
+	rl, ok := fs.(interface{ Readlink(string) (string, error) })
+	if !ok {
+		t.Skip("Readlink not implemented")
+	}
+
+	target, err := rl.Readlink("link")
+	require.NoError(t, err)
+	assert.Equal(t, "dir/a.txt", target)
+
+	target, err = rl.Readlink("dangling")
+	require.NoError(t, err)
+	assert.Equal(t, "nowhere", target)
+
+	_, err = rl.Readlink("README.md")
+	assert.Error(t, err, "not a symlink")
+}
+
+func testUnicode(t *testing.T, fs vfs.FileSystem) {
+	entries, err := fs.ReadDir("")
+	require.NoError(t, err)
+
+	found := false
+	for _, fi := range entries {
+		if fi.Name() == "日本語" {
+			found = fi.IsDir()
+		}
+	}
+	assert.True(t, found, "日本語/ listed as a directory")
+
+	content, err := vfs.ReadFile(fs, "日本語/ファイル.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "こんにちは\n", string(content))
+
+	content, err = vfs.ReadFile(fs, "with space.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "space\n", string(content))
+}
+
+func testNotExist(t *testing.T, fs vfs.FileSystem) {
+	for _, name := range []string{"nonexistent", "dir/nonexistent", "nonexistent/file", "dir/sub/nonexistent/file"} {
+		_, err := fs.Stat(name)
+		assert.True(t, errors.Is(err, os.ErrNotExist), "Stat(%q): %v", name, err)
+
+		_, err = fs.Lstat(name)
+		assert.True(t, errors.Is(err, os.ErrNotExist), "Lstat(%q): %v", name, err)
+
+		_, err = fs.Open(name)
+		assert.True(t, errors.Is(err, os.ErrNotExist), "Open(%q): %v", name, err)
+	}
+
+	for _, name := range []string{"nonexistent", "dir/nonexistent"} {
+		_, err := fs.ReadDir(name)
+		assert.True(t, errors.Is(err, os.ErrNotExist), "ReadDir(%q): %v", name, err)
+	}
+}
+
+func testErrors(t *testing.T, fsys vfs.FileSystem) {
+	testOpenDir(t, fsys)
+
+	_, err := fsys.ReadDir("README.md")
+	assert.Error(t, err, "ReadDir of a file")
+
+	_, err = fsys.Stat("README.md/child")
+	assert.Error(t, err, "Stat under a file")
+}
+
+// testOpenDir checks Open of a directory either fails, or gives a handle
+// which fails to be read like an *os.File does, and lists the directory if
+// it implements fs.ReadDirFile.
+func testOpenDir(t *testing.T, fsys vfs.FileSystem) {
+	f, err := fsys.Open("dir")
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	_, err = f.Read(make([]byte, 1))
+	assert.Error(t, err, "Read of a directory")
+
+	d, ok := f.(fs.ReadDirFile)
+	if !ok {
+		return
+	}
+
+	var names []string
+	for {
+		entries, err := d.ReadDir(1)
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			break
+		}
+		for _, e := range entries {
+			names = append(names, e.Name())
+			assert.Equal(t, e.Name() == "sub", e.IsDir(), e.Name())
+		}
+	}
+	assert.Equal(t, childNames("dir"), names)
+
+	fi, err := d.Stat()
+	if assert.NoError(t, err) {
+		assert.True(t, fi.IsDir())
+	}
+}
@@ -0,0 +1,13 @@
+package vcsfstest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChildNames(t *testing.T) {
+	assert.Equal(t, []string{"README.md", "dangling", "dir", "empty", "large.bin", "link", "script.sh", "with space.txt", "日本語"}, childNames(""))
+	assert.Equal(t, []string{"Z.txt", "a.txt", "b.txt", "sub"}, childNames("dir"))
+	assert.Equal(t, []string{"deep"}, childNames("dir/sub"))
+}
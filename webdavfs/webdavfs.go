@@ -0,0 +1,176 @@
+// Package webdavfs exposes a git.Repository read-only through
+// golang.org/x/net/webdav, so that a revision can be mounted as
+// a network drive.
+//
+//	repo, _ := git.NewRepository("v1.0.0", "", git.WithModTimeMode(git.ModTimeCommitterDate))
+//	http.Handle("/", &webdav.Handler{
+//		FileSystem: webdavfs.New(repo),
+//		LockSystem: webdav.NewMemLS(),
+//	})
+//
+// ETags are git object IDs. getlastmodified derives from ModTime of files,
+// so the Repository should report committer dates of the pinned commit.
+package webdavfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/net/webdav"
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/motemen/go-vcs-fs/git"
+)
+
+// FileSystem implements webdav.FileSystem. All modifications fail with
+// os.ErrPermission.
+type FileSystem struct {
+	repo *git.Repository
+}
+
+var _ webdav.FileSystem = (*FileSystem)(nil)
+
+// New returns a read-only webdav.FileSystem serving repo.
+func New(repo *git.Repository) *FileSystem {
+	return &FileSystem{repo: repo}
+}
+
+func (fs *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrPermission}
+}
+
+func (fs *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: os.ErrPermission}
+}
+
+func (fs *FileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: os.ErrPermission}
+}
+
+func (fs *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	fi, err := fs.repo.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileInfo{fi}, nil
+}
+
+// fileInfo uses git object IDs as ETags.
+type fileInfo struct {
+	os.FileInfo
+}
+
+var _ webdav.ETager = fileInfo{}
+
+func (fi fileInfo) ETag(ctx context.Context) (string, error) {
+	if oid, ok := git.ObjectID(fi.FileInfo); ok {
+		return `"` + oid + `"`, nil
+	}
+
+	return "", webdav.ErrNotImplemented
+}
+
+const writeFlags = os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_APPEND
+
+func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&writeFlags != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+	}
+
+	stat, err := fs.repo.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	fi := fileInfo{stat}
+
+	if fi.IsDir() {
+		return &dir{fs: fs, name: name, fi: fi}, nil
+	}
+
+	f, err := fs.repo.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &file{ReadSeekCloser: f, fi: fi}, nil
+}
+
+type file struct {
+	vfs.ReadSeekCloser
+	fi os.FileInfo
+}
+
+func (f *file) Stat() (os.FileInfo, error) { return f.fi, nil }
+
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, fmt.Errorf("not a directory: %s", f.fi.Name())
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+type dir struct {
+	fs      *FileSystem
+	name    string
+	fi      os.FileInfo
+	entries []os.FileInfo
+	read    bool
+}
+
+func (d *dir) Stat() (os.FileInfo, error) { return d.fi, nil }
+
+func (d *dir) Close() error { return nil }
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, fmt.Errorf("is a directory: %s", d.name)
+}
+
+func (d *dir) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		d.entries, d.read = nil, false
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("is a directory: %s", d.name)
+}
+
+func (d *dir) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+func (d *dir) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.read {
+		entries, err := d.fs.repo.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		for i, fi := range entries {
+			entries[i] = fileInfo{fi}
+		}
+
+		d.entries, d.read = entries, true
+	}
+
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+
+	return entries, nil
+}
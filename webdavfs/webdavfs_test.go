@@ -0,0 +1,102 @@
+package webdavfs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+
+	"github.com/motemen/go-vcs-fs/git"
+	"github.com/motemen/go-vcs-fs/gittest"
+)
+
+func newFixture(t *testing.T) *gittest.Repo {
+	return gittest.New(t).
+		AddFile("LICENSE", "MIT License\n").
+		AddFile("git/git.go", "package git\n").
+		Commit("initial")
+}
+
+func TestFileSystem(t *testing.T) {
+	repo, err := git.NewRepository("HEAD", newFixture(t).GitDir, git.WithModTimeMode(git.ModTimeCommitterDate))
+	require.NoError(t, err)
+
+	fs := New(repo)
+	ctx := context.Background()
+
+	f, err := fs.OpenFile(ctx, "/git/git.go", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "package git")
+	f.Close()
+
+	d, err := fs.OpenFile(ctx, "/git", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	entries, err := d.Readdir(0)
+	require.NoError(t, err)
+	assert.NotEmpty(t, entries)
+
+	_, err = fs.OpenFile(ctx, "/git/git.go", os.O_RDWR, 0)
+	assert.True(t, os.IsPermission(err))
+
+	_, err = fs.Stat(ctx, "/nonexistent/file")
+	assert.True(t, os.IsNotExist(err))
+
+	assert.True(t, os.IsPermission(fs.Mkdir(ctx, "/new", 0755)))
+}
+
+func TestFileSystem_handler(t *testing.T) {
+	repo, err := git.NewRepository("HEAD", newFixture(t).GitDir, git.WithModTimeMode(git.ModTimeCommitterDate))
+	require.NoError(t, err)
+
+	s := httptest.NewServer(&webdav.Handler{
+		FileSystem: New(repo),
+		LockSystem: webdav.NewMemLS(),
+	})
+	defer s.Close()
+
+	req, err := http.NewRequest("PROPFIND", s.URL+"/git/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Depth", "1")
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusMultiStatus, res.StatusCode)
+	assert.Contains(t, string(body), "/git/git.go")
+	assert.Contains(t, string(body), "getetag")
+	assert.Contains(t, string(body), "getlastmodified")
+
+	req, err = http.NewRequest("PUT", s.URL+"/new.txt", strings.NewReader("x"))
+	require.NoError(t, err)
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.NotEqual(t, http.StatusCreated, res.StatusCode)
+
+	fi, err := repo.Stat("LICENSE")
+	require.NoError(t, err)
+	oid, ok := git.ObjectID(fi)
+	require.True(t, ok)
+
+	res, err = http.Get(s.URL + "/LICENSE")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, `"`+oid+`"`, res.Header.Get("ETag"))
+
+	res, err = http.Get(s.URL + "/nonexistent")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}